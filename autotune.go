@@ -0,0 +1,89 @@
+package gopeat
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// autoTuneBudget bounds the wall time AutoTune reads the source
+	autoTuneBudget = 100 * time.Millisecond
+
+	// autoTuneFill is the source read time a tuned buffer holds, and
+	// autoTuneAhead the read time the tuned data chan holds
+	autoTuneFill  = 10 * time.Millisecond
+	autoTuneAhead = 50 * time.Millisecond
+
+	// Tuned size limits
+	autoTuneMaxBufSize = 5000
+	autoTuneMinChanLen = 2
+	autoTuneMaxChanLen = 64
+)
+
+// AutoTune sets the buffer sizes, see SetBufferSizes, from the
+// source's measured throughput instead of the fixed defaults. The
+// source is read as fast as it goes for up to 100ms, a slow source
+// gets small buffers so values aren't held waiting on a buffer to
+// fill and a fast source gets large buffers that cut the chan
+// handoffs. The values read aren't lost, they're played first. Call
+// before Play, a single blocking Next can hold AutoTune past the
+// 100ms.
+func (pb *PlayBack) AutoTune() error {
+	if !pb.IsIdle() {
+		return errors.New("playBack: can't auto tune while running")
+	}
+
+	start := time.Now()
+	for time.Since(start) < autoTuneBudget && !pb.primedDone {
+		tsData, ok := pb.sourceNext(context.Background())
+		if !ok {
+			pb.primedDone = true
+			break
+		}
+		pb.primed = append(pb.primed, tsData)
+	}
+	if len(pb.primed) == 0 {
+		return nil
+	}
+	perRec := time.Since(start) / time.Duration(len(pb.primed))
+	perRec = max(perRec, time.Nanosecond)
+
+	bufSize := int(min(autoTuneFill/perRec, autoTuneMaxBufSize))
+	bufSize = max(bufSize, 1)
+	chanLen := int(min(autoTuneAhead/(perRec*time.Duration(bufSize)),
+		autoTuneMaxChanLen))
+	chanLen = max(chanLen, autoTuneMinChanLen)
+	return pb.SetBufferSizes(bufSize, chanLen)
+}
+
+// nextTsData returns the next value for the loader, values read by
+// AutoTune come first
+func (pb *PlayBack) nextTsData() (TimeStamper, bool) {
+	if len(pb.primed) > 0 {
+		tsData := pb.primed[0]
+		pb.primed = pb.primed[1:]
+		return tsData, true
+	}
+	if pb.primedDone {
+		pb.primedDone = false
+		return nil, false
+	}
+	return pb.sourceNext(pb.loadContext())
+}
+
+// loadContext returns the context the loader reads a
+// CancellableSource with
+func (pb *PlayBack) loadContext() context.Context {
+	if pb.loadCtx == nil {
+		return context.Background()
+	}
+	return pb.loadCtx
+}
+
+// sourceNext returns the source's next value, skipping the values
+// outside the time bracket for a source that doesn't implement
+// TimeBracket. A CancellableSource is read with ctx
+func (pb *PlayBack) sourceNext(ctx context.Context) (TimeStamper, bool) {
+	return bracketedNext(ctx, pb.TsDataSource, pb.bracketing, pb.StartTime, pb.EndTime)
+}
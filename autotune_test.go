@@ -0,0 +1,50 @@
+package gopeat
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAutoTune confirms a fast source gets larger buffers than a slow
+// one, and the values read tuning are still played
+func TestAutoTune(t *testing.T) {
+	simStartTime := time.Now()
+	newSource := func(cnt int) mockSliceBackedDs {
+		var mts mockSliceBackedDs
+		for i := 1; i <= cnt; i++ {
+			mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+				Tim: simStartTime.Add(time.Duration(i) * time.Millisecond),
+				Val: int64(i)})
+		}
+		return mts
+	}
+
+	fast := newSource(200000)
+	fastPb, _ := New("fast", simStartTime, simStartTime.Add(time.Hour), &fast, 1, nil)
+	if err := fastPb.AutoTune(); err != nil {
+		t.Fatal(err)
+	}
+
+	slow := mockSlowDs{mockSliceBackedDs: newSource(1000), Delay: 2 * time.Millisecond}
+	slowPb, _ := New("slow", simStartTime, simStartTime.Add(time.Hour), &slow, 1, nil)
+	if err := slowPb.AutoTune(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fastPb.tsDataBufSize <= slowPb.tsDataBufSize {
+		t.Errorf("fast buf size %d, slow %d; expected fast larger",
+			fastPb.tsDataBufSize, slowPb.tsDataBufSize)
+	}
+
+	// Every value is still there, in order
+	slow.Delay = 0
+	for i := 1; i <= 1000; i++ {
+		ts, ok := slowPb.nextTsData()
+		if !ok || ts.(mockTsData).Val != int64(i) {
+			t.Fatalf("value %d: got %v, %t", i, ts, ok)
+		}
+	}
+	if _, ok := slowPb.nextTsData(); ok {
+		t.Error("value after the last; expected none")
+	}
+}
@@ -0,0 +1,60 @@
+package gopeat
+
+import "time"
+
+// bucketStart returns the start of tim's ReverseWithinBucket bucket,
+// buckets are measured from StartTime
+func (pb *PlayBack) bucketStart(tim time.Time) time.Time {
+	g := pb.ReverseWithinBucket
+	return pb.StartTime.Add(tim.Sub(pb.StartTime) / g * g)
+}
+
+// nextLoaded returns the next value for the loader, reversed within
+// each bucket when ReverseWithinBucket is set
+func (pb *PlayBack) nextLoaded() (TimeStamper, bool) {
+	if pb.ReverseWithinBucket <= 0 {
+		return pb.nextTsData()
+	}
+	if len(pb.bucket) == 0 {
+		pb.fillBucket()
+	}
+	n := len(pb.bucket)
+	if n == 0 {
+		return nil, false
+	}
+	tsData := pb.bucket[n-1]
+	pb.bucket = pb.bucket[:n-1]
+	return tsData, true
+}
+
+// fillBucket reads the values of the next bucket, the first value of
+// the bucket after is held for the next fill. A nil value is a bucket
+// of its own so the loader can report it.
+func (pb *PlayBack) fillBucket() {
+	tsData := pb.bucketNext
+	if !pb.bucketHeld {
+		var more bool
+		if tsData, more = pb.nextTsData(); !more {
+			return
+		}
+	}
+	pb.bucketNext, pb.bucketHeld = nil, false
+	pb.bucket = append(pb.bucket, tsData)
+	if ts, _ := unwrapRaw(tsData); ts == nil {
+		return
+	}
+
+	start := pb.bucketStart(tsData.GetTimeStamp())
+	for {
+		next, more := pb.nextTsData()
+		if !more {
+			return
+		}
+		if ts, _ := unwrapRaw(next); ts == nil ||
+			!pb.bucketStart(next.GetTimeStamp()).Equal(start) {
+			pb.bucketNext, pb.bucketHeld = next, true
+			return
+		}
+		pb.bucket = append(pb.bucket, next)
+	}
+}
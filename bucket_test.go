@@ -0,0 +1,47 @@
+package gopeat
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestReverseWithinBucket confirms values are sent latest first within
+// each bucket, buckets in order and paced at their start
+func TestReverseWithinBucket(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i, ms := range []int{10, 20, 30, 120, 150, 260} {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(ms) * time.Millisecond),
+			Val: int64(i + 1)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+	pb.ReverseWithinBucket = 100 * time.Millisecond
+
+	var vals []int64
+	var sendDurs []time.Duration
+	pb.SendTs = func(ts TimeStamper) error {
+		vals = append(vals, ts.(mockTsData).Val)
+		sendDurs = append(sendDurs, time.Since(pb.WallStartTime))
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	expVals := []int64{3, 2, 1, 5, 4, 6}
+	expDurs := []time.Duration{0, 0, 0, 100, 100, 200}
+	if len(vals) != len(expVals) {
+		t.Fatalf("Got %d sends; expected %d", len(vals), len(expVals))
+	}
+	for i := range expVals {
+		if vals[i] != expVals[i] {
+			t.Errorf("send %d value %d; expected %d", i, vals[i], expVals[i])
+		}
+		d := (sendDurs[i] - expDurs[i]*time.Millisecond).Seconds() * 1000
+		if math.Abs(d) > 3 {
+			t.Errorf("send %d Time = %f(ms); want less than 3(ms)", i, d)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package gopeat
+
+import "time"
+
+// Calendar is implemented by any value that knows when the source
+// of the time stamped data, an exchange for example, is open.
+// Playback skips the closed time between data values instead of
+// waiting it out in real time.
+type Calendar interface {
+	IsOpen(t time.Time) bool
+}
+
+// WeekdayCalendar is a Calendar open Monday through Friday and
+// closed all weekend, days are in Location, UTC when nil
+type WeekdayCalendar struct {
+	Location *time.Location
+}
+
+// IsOpen reports if t falls on a weekday
+func (wc WeekdayCalendar) IsOpen(t time.Time) bool {
+	if wc.Location != nil {
+		t = t.In(wc.Location)
+	}
+	wd := t.Weekday()
+	return wd != time.Saturday && wd != time.Sunday
+}
+
+// calendarStep is how often closedDur samples the calendar. Open and
+// closed periods shorter than the step may be missed.
+const calendarStep = time.Minute
+
+// closedDur returns how much of from to to the calendar is closed.
+// The calendar is sampled every calendarStep, each open-closed
+// change found is pinned down by bisection.
+func closedDur(cal Calendar, from, to time.Time) time.Duration {
+	var closed time.Duration
+	t, open := from, cal.IsOpen(from)
+	for t.Before(to) {
+		next := t.Add(calendarStep)
+		if next.After(to) {
+			next = to
+		}
+		nextOpen := cal.IsOpen(next)
+		switch {
+		case !open && !nextOpen:
+			closed += next.Sub(t)
+		case open != nextOpen:
+			edge := calendarEdge(cal, t, next, open)
+			if open {
+				closed += next.Sub(edge)
+			} else {
+				closed += edge.Sub(t)
+			}
+		}
+		t, open = next, nextOpen
+	}
+	return closed
+}
+
+// calendarEdge returns, to the microsecond, the instant between lo
+// and hi where the calendar changes from loOpen
+func calendarEdge(cal Calendar, lo, hi time.Time, loOpen bool) time.Time {
+	for hi.Sub(lo) > time.Microsecond {
+		mid := lo.Add(hi.Sub(lo) / 2)
+		if cal.IsOpen(mid) == loOpen {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi
+}
@@ -0,0 +1,57 @@
+package gopeat
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestCalendarWeekend confirms a weekend between two values is
+// skipped instead of waited out
+func TestCalendarWeekend(t *testing.T) {
+	// Friday night to Monday morning
+	simStartTime := time.Date(2013, 9, 6, 23, 59, 59, 900000000, time.UTC)
+	fri := time.Date(2013, 9, 6, 23, 59, 59, 950000000, time.UTC)
+	mon := time.Date(2013, 9, 9, 0, 0, 0, 50000000, time.UTC)
+	mts := mockSliceBackedDs{TimeStampers: []TimeStamper{
+		mockTsData{Tim: fri, Val: 1},
+		mockTsData{Tim: mon, Val: 2},
+	}}
+	pb, _ := New("test", simStartTime, mon, &mts, 1, nil)
+	pb.Calendar = WeekdayCalendar{}
+
+	var sendDurs []time.Duration
+	pb.SendTs = func(ts TimeStamper) error {
+		sendDurs = append(sendDurs, time.Since(pb.WallStartTime))
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	// 50ms in, then 50ms of Friday and 50ms of Monday later
+	expected := []time.Duration{50 * time.Millisecond, 150 * time.Millisecond}
+	if len(sendDurs) != len(expected) {
+		t.Fatalf("Got %d sends; expected %d", len(sendDurs), len(expected))
+	}
+	for i, exp := range expected {
+		d := (sendDurs[i] - exp).Seconds() * 1000
+		if math.Abs(d) > 3 {
+			t.Errorf("send %d Time = %f(ms); want less than 3(ms)", i, d)
+		}
+	}
+}
+
+func TestClosedDur(t *testing.T) {
+	fri := time.Date(2013, 9, 6, 12, 0, 0, 0, time.UTC)
+	mon := time.Date(2013, 9, 9, 12, 0, 0, 0, time.UTC)
+	closed := closedDur(WeekdayCalendar{}, fri, mon)
+	if d := closed - 48*time.Hour; d < -time.Microsecond || d > time.Microsecond {
+		t.Errorf("closed %v; expected 48h", closed)
+	}
+
+	// All open
+	if closed := closedDur(WeekdayCalendar{}, fri, fri.Add(time.Hour)); closed != 0 {
+		t.Errorf("closed %v; expected 0", closed)
+	}
+}
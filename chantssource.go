@@ -0,0 +1,61 @@
+package gopeat
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ChanTsSource implements a time stamped data source for values
+// arriving on a chan, for example a live feed, paced like any other
+// source. Next waits on C and returns false once C is closed or the
+// source is stopped. Values must arrive timestamp ordered, playback
+// skips the ones outside the time bracket. The loader hands the values
+// on a buffer at a time, a buffer size of 1 passes each value on as it
+// arrives, see PlayBack.SetBufferSizes.
+//
+// ChanTsSource is a CancellableSource, Quit doesn't wait on C.
+type ChanTsSource struct {
+	C <-chan TimeStamper
+
+	initOnce sync.Once
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// Next implements an iterator for the values received on C
+func (cs *ChanTsSource) Next() (TimeStamper, bool) {
+	return cs.NextContext(context.Background())
+}
+
+// NextContext is Next, returning false once ctx is done, see
+// CancellableSource
+func (cs *ChanTsSource) NextContext(ctx context.Context) (TimeStamper, bool) {
+	select {
+	case tsData, ok := <-cs.C:
+		return tsData, ok
+	case <-cs.stopChan():
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// Count returns ErrNotCountable, the values are yet to arrive, see
+// Counter
+func (cs *ChanTsSource) Count() (int64, time.Duration, error) {
+	return 0, 0, ErrNotCountable
+}
+
+// stopChan returns the chan closed by Stop
+func (cs *ChanTsSource) stopChan() chan struct{} {
+	cs.initOnce.Do(func() { cs.stop = make(chan struct{}) })
+	return cs.stop
+}
+
+// Stop ends the source, a Next waiting on C returns false. Safe to
+// call from any goroutine and more than once
+func (cs *ChanTsSource) Stop() {
+	stop := cs.stopChan()
+	cs.stopOnce.Do(func() { close(stop) })
+}
@@ -0,0 +1,68 @@
+package gopeat
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// chanRun plays a ChanTsSource fed by feed, failing if Wait doesn't
+// return, and returns the values sent
+func chanRun(t *testing.T, feed func(start time.Time, c chan<- TimeStamper, pb *PlayBack)) ([]int64, *PlayBack) {
+	start := time.Now()
+	c := make(chan TimeStamper)
+	pb, _ := New("test", start, start.Add(time.Minute), &ChanTsSource{C: c}, 1, nil)
+	if err := pb.SetBufferSizes(1, 4); err != nil {
+		t.Fatal(err)
+	}
+	pb.PreloadTimeout = time.Millisecond
+
+	var vals []int64
+	pb.SendTs = func(ts TimeStamper) error {
+		vals = append(vals, ts.(mockTsData).Val)
+		return nil
+	}
+	pb.Play()
+	go feed(start, c, pb)
+
+	waited := make(chan struct{})
+	go func() {
+		pb.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait blocked")
+	}
+	return vals, pb
+}
+
+// TestChanTsSource plays values as they arrive, the run ends when
+// the chan is closed
+func TestChanTsSource(t *testing.T) {
+	vals, pb := chanRun(t, func(start time.Time, c chan<- TimeStamper, pb *PlayBack) {
+		for i := 1; i <= 3; i++ {
+			tim := start.Add(time.Duration(i) * 10 * time.Millisecond)
+			time.Sleep(time.Until(tim))
+			c <- mockTsData{Tim: tim, Val: int64(i)}
+		}
+		close(c)
+	})
+	if fmt.Sprint(vals) != "[1 2 3]" || pb.Completion() != CompletedOK {
+		t.Errorf("Got %v, %v; expected [1 2 3], CompletedOK", vals, pb.Completion())
+	}
+}
+
+// TestChanTsSourceQuit quits while the loader waits on a chan that
+// never closes, the run ends anyway
+func TestChanTsSourceQuit(t *testing.T) {
+	vals, pb := chanRun(t, func(start time.Time, c chan<- TimeStamper, pb *PlayBack) {
+		c <- mockTsData{Tim: start, Val: 1}
+		time.Sleep(20 * time.Millisecond)
+		pb.Quit()
+	})
+	if fmt.Sprint(vals) != "[1]" || pb.Completion() != CompletedQuit {
+		t.Errorf("Got %v, %v; expected [1], CompletedQuit", vals, pb.Completion())
+	}
+}
@@ -0,0 +1,39 @@
+package gopeat
+
+import "time"
+
+// Clock is the wall clock playback paces sends by. The timer takes
+// its wall times from Now and sleeps on After, so a fake Clock makes
+// pacing deterministic and can play hours of data in milliseconds.
+// SendDeadline and ControlDebounce are timed by the Clock too. The
+// timeouts, PreloadTimeout, LoaderStallTimeout and LingerAfterLast,
+// and the OnTick ticks stay on the system clock.
+type Clock interface {
+	// Now returns the current wall time
+	Now() time.Time
+
+	// After returns a chan that gets the wall time once d has passed
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock of the time package
+type systemClock struct{}
+
+// Now returns time.Now
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After returns time.After
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// clock returns the Clock playback paces by, the system clock unless
+// set
+func (pb *PlayBack) clock() Clock {
+	if pb.Clock != nil {
+		return pb.Clock
+	}
+	return systemClock{}
+}
@@ -0,0 +1,176 @@
+package gopeat
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockClock is a Clock that moves on only when slept on or advanced.
+// Each After advances it by the sleep and fires at once, unless
+// manual, then the sleep waits for the test to advance the clock.
+type mockClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	manual  bool
+	waiters []mockWaiter
+}
+
+// mockWaiter is a sleep on a manual mockClock
+type mockWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func (mc *mockClock) Now() time.Time {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.now
+}
+
+func (mc *mockClock) After(d time.Duration) <-chan time.Time {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	c := make(chan time.Time, 1)
+	if !mc.manual {
+		mc.now = mc.now.Add(d)
+	}
+	if d <= 0 || !mc.manual {
+		c <- mc.now
+		return c
+	}
+	mc.waiters = append(mc.waiters, mockWaiter{at: mc.now.Add(d), c: c})
+	return c
+}
+
+// advance moves a manual clock on d, firing the sleeps over by then
+func (mc *mockClock) advance(d time.Duration) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.advanceLocked(mc.now.Add(d))
+}
+
+func (mc *mockClock) advanceLocked(to time.Time) {
+	mc.now = to
+	waiting := mc.waiters[:0]
+	for _, w := range mc.waiters {
+		if w.at.After(to) {
+			waiting = append(waiting, w)
+			continue
+		}
+		w.c <- to
+	}
+	mc.waiters = waiting
+}
+
+// advanceNext waits for a sleep on a manual clock and moves the clock
+// to the end of the earliest, returns false if done closes first
+func (mc *mockClock) advanceNext(done <-chan struct{}) bool {
+	for {
+		mc.mu.Lock()
+		if len(mc.waiters) > 0 {
+			next := mc.waiters[0].at
+			for _, w := range mc.waiters {
+				if w.at.Before(next) {
+					next = w.at
+				}
+			}
+			mc.advanceLocked(next)
+			mc.mu.Unlock()
+			return true
+		}
+		mc.mu.Unlock()
+		select {
+		case <-done:
+			return false
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+// TestExternalClock confirms values are sent up to each frontier the
+// external clock advances to, and no further
+func TestExternalClock(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	for _, sec := range []int{10, 20, 30, 40} {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(sec) * time.Second),
+			Val: int64(sec)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), &mts, 1, nil)
+	clock := make(chan time.Time)
+	pb.ExternalClock = clock
+
+	var mu sync.Mutex
+	var sent []int64
+	pb.SendTs = func(ts TimeStamper) error {
+		mu.Lock()
+		sent = append(sent, ts.(mockTsData).Val)
+		mu.Unlock()
+		return nil
+	}
+	// sentCnt gives sends beyond want a chance to show up
+	sentCnt := func(want int) int {
+		deadline := time.Now().Add(100 * time.Millisecond)
+		for {
+			mu.Lock()
+			cnt := len(sent)
+			mu.Unlock()
+			if cnt > want || time.Now().After(deadline) {
+				return cnt
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	pb.Play()
+
+	// The sim clock runs well ahead of the wall clock, nothing paces
+	clock <- simStartTime.Add(25 * time.Second)
+	if cnt := sentCnt(2); cnt != 2 {
+		t.Errorf("sent %d up to 25s; expected 2", cnt)
+	}
+	clock <- simStartTime.Add(40 * time.Second)
+	if cnt := sentCnt(4); cnt != 4 {
+		t.Errorf("sent %d up to 40s; expected 4", cnt)
+	}
+	pb.Wait()
+
+	if wallDur := time.Since(pb.WallStartTime); wallDur > time.Second {
+		t.Errorf("run took %v; expected no pacing", wallDur)
+	}
+}
+
+// TestClock confirms an hour of data is paced by the Clock, not the
+// wall clock, with each value sent right on schedule
+func TestClock(t *testing.T) {
+	simStartTime := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	src := pipelineSource(simStartTime, 60, time.Minute)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Hour), src, 1, nil)
+	pb.Clock = &mockClock{now: simStartTime}
+	var sent int
+	pb.SendTs = func(ts TimeStamper) error {
+		sent++
+		return nil
+	}
+
+	start := time.Now()
+	pb.Play()
+	pb.Wait()
+
+	if wallDur := time.Since(start); wallDur > 5*time.Second {
+		t.Errorf("run took %v; expected no real sleeps", wallDur)
+	}
+	if sent != 60 {
+		t.Errorf("sent %d; expected 60", sent)
+	}
+	// The timer may sleep for the first value before the
+	// controller reads the start time
+	if pb.WallRunDur < 59*time.Minute || pb.WallRunDur > time.Hour {
+		t.Errorf("WallRunDur %v; expected about 1h", pb.WallRunDur)
+	}
+	if ds := pb.DriftStats(); ds.MaxDriftMs != 0 {
+		t.Errorf("MaxDriftMs %f; expected 0", ds.MaxDriftMs)
+	}
+}
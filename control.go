@@ -0,0 +1,172 @@
+package gopeat
+
+// cmdKind is a PlayBack API command
+type cmdKind int
+
+const (
+	cmdPause cmdKind = iota
+	cmdResume
+	cmdQuit
+	cmdSetRate
+	cmdSync
+	cmdStepBack
+	cmdStepMode
+)
+
+// cmdChanLen is the number of API commands that can be queued for
+// the controller before the API methods block
+const cmdChanLen = 16
+
+// command is an API request queued for the controller. Commands are
+// applied one at a time in the order queued.
+type command struct {
+	kind cmdKind
+
+	// rate for cmdSetRate
+	rate float64
+
+	// canceled for a cmdQuit by PlayContext's context
+	canceled bool
+
+	// step for cmdStepBack, set to the value replayed
+	step *stepReq
+
+	// done, when set, is closed once the command is applied
+	done chan struct{}
+}
+
+// enqueue queues a command for the running controller, returns false
+// if there is no controller to take it. Commands queued as the
+// controller exits are dropped, the run is over.
+func (pb *PlayBack) enqueue(c command) bool {
+	pb.ctlMu.Lock()
+	if !pb.running {
+		pb.ctlMu.Unlock()
+		return false
+	}
+	cmdChan, exited := pb.cmdChan, pb.exited
+	pb.ctlMu.Unlock()
+
+	select {
+	case cmdChan <- c:
+	case <-exited:
+	}
+	return true
+}
+
+// sync blocks until the controller has applied the commands queued
+// ahead of it, or the run is over
+func (pb *PlayBack) sync() {
+	c := command{kind: cmdSync, done: make(chan struct{})}
+	pb.ctlMu.Lock()
+	exited := pb.exited
+	pb.ctlMu.Unlock()
+	if pb.enqueue(c) {
+		select {
+		case <-c.done:
+		case <-exited:
+		}
+	}
+}
+
+// apply carries out a command, only called by the controller
+func (pb *PlayBack) apply(c command) {
+	switch c.kind {
+	case cmdPause:
+		pb.pause()
+	case cmdResume:
+		pb.resume()
+	case cmdQuit:
+		if pb.quit() && c.canceled {
+			pb.doneMu.Lock()
+			pb.canceled = true
+			pb.doneMu.Unlock()
+		}
+	case cmdSetRate:
+		pb.setRate(c.rate)
+	case cmdStepBack:
+		pb.stepBack(c.step)
+	case cmdStepMode:
+		pb.pause()
+		pb.ctlMu.Lock()
+		pb.stepping = pb.paused
+		pb.ctlMu.Unlock()
+	}
+	if c.done != nil {
+		close(c.done)
+	}
+}
+
+// applyPending applies the queued commands, returns false once quit.
+// Only called by the controller
+func (pb *PlayBack) applyPending() bool {
+	for {
+		select {
+		case c := <-pb.cmdChan:
+			pb.apply(c)
+		case <-pb.quitChan:
+			return false
+		default:
+			return true
+		}
+	}
+}
+
+// pause signals a pause, a no-op when paused or not active
+func (pb *PlayBack) pause() {
+	pb.ctlMu.Lock()
+	defer pb.ctlMu.Unlock()
+	if !pb.paused && pb.replayActive {
+		// Open up the resume chan to allow ending the
+		// pause which is being initiated here
+		pb.resumeChan = make(chan struct{})
+
+		// Send pause signal
+		close(pb.pauseChan)
+		pb.paused = true
+		pb.pauseStart = pb.clock().Now()
+		pb.pauseCnt.Add(1)
+		pb.markPaused(pb.pauseStart)
+	}
+}
+
+// resume signals the end of a pause, a no-op when not paused
+func (pb *PlayBack) resume() {
+	pb.ctlMu.Lock()
+	defer pb.ctlMu.Unlock()
+	if pb.paused {
+		// Open up the pause chan to allow pausing the
+		// playback which is being restarted here
+		pb.pauseChan = make(chan struct{})
+
+		// Credit the pause before the timer wakes up to it
+		now := pb.clock().Now()
+		pb.creditPause(pb.pauseStart, now)
+		pb.markResumed(now)
+		pb.pauseMetrics(now.Sub(pb.pauseStart))
+
+		// Send resume signal
+		close(pb.resumeChan)
+		pb.paused = false
+		pb.stepping = false
+	}
+}
+
+// quit signals the run to stop, a no-op when already quit. Reports
+// if this call stopped the run
+func (pb *PlayBack) quit() bool {
+	pb.ctlMu.Lock()
+	defer pb.ctlMu.Unlock()
+	if !pb.replayActive {
+		return false
+	}
+	close(pb.quitChan)
+	pb.replayActive = false
+	pb.stepping = false
+
+	// Release a loader waiting on the source
+	if pb.loadCancel != nil {
+		pb.loadCancel()
+	}
+	return true
+}
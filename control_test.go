@@ -0,0 +1,237 @@
+package gopeat
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestCommandOrder confirms API commands are applied in the order
+// queued, and repeated commands keep their idempotent behavior
+func TestCommandOrder(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(2 * time.Second), Val: 1},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Hour), &mts, 1, nil)
+	pb.Play()
+
+	// Applied out of order, the rate or pause would be off
+	pb.Pause()
+	pb.SetRate(4)
+	pb.Resume()
+	pb.Pause()
+	pb.Pause()
+	pb.SetRate(2)
+	pb.Resume()
+	pb.Resume()
+	pb.sync()
+
+	if pb.paused {
+		t.Error("paused true, expected false")
+	}
+	pb.rateMu.RLock()
+	rate := pb.rate
+	pb.rateMu.RUnlock()
+	if rate != 2 {
+		t.Errorf("rate %f; expected 2", rate)
+	}
+
+	pb.Pause()
+	pb.sync()
+	if !pb.paused {
+		t.Error("paused false, expected true")
+	}
+
+	pb.Quit()
+	pb.Quit()
+	pb.Wait()
+	if c := pb.Completion(); c != CompletedQuit {
+		t.Errorf("Completion = %d; expected CompletedQuit", c)
+	}
+}
+
+// TestCommandsNotRunning confirms commands without a running playback
+// don't block, Quit unblocks Wait and SetRate takes effect at once
+func TestCommandsNotRunning(t *testing.T) {
+	var mts mockSliceBackedDs
+	pb, _ := New("test", time.Now(), time.Now(), &mts, 1, nil)
+
+	pb.Pause()
+	pb.Resume()
+	pb.sync()
+	if err := pb.SetRate(3); err != nil {
+		t.Fatal(err)
+	}
+	if pb.rate != 3 {
+		t.Errorf("rate %f; expected 3", pb.rate)
+	}
+	if pb.paused {
+		t.Error("paused true, expected false")
+	}
+
+	pb.Quit()
+	pb.Quit()
+	pb.Wait()
+}
+
+// TestControlCheckEvery confirms a Quit during a run of slow zero gap
+// sends is honored within ControlCheckEvery sends
+func TestControlCheckEvery(t *testing.T) {
+	simStartTime := time.Now()
+	for _, every := range []int{1, 4} {
+		var mts mockSliceBackedDs
+		for i := 1; i <= 20; i++ {
+			mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+				Tim: simStartTime.Add(10 * time.Millisecond),
+				Val: int64(i)})
+		}
+		pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+		pb.ControlCheckEvery = every
+
+		cbCount := 0
+		pb.SendTs = func(ts TimeStamper) error {
+			cbCount++
+			time.Sleep(5 * time.Millisecond)
+			if cbCount == 3 {
+				pb.Quit()
+			}
+			return nil
+		}
+
+		pb.Play()
+		pb.Wait()
+
+		if max := 3 + every - 1; cbCount < 3 || cbCount > max {
+			t.Errorf("every %d: %d sends; expected 3 to %d", every, cbCount, max)
+		}
+		if c := pb.Completion(); c != CompletedQuit {
+			t.Errorf("every %d: Completion = %d; expected CompletedQuit", every, c)
+		}
+	}
+}
+
+// TestPlayContext cancels a run's context mid gap, the run quits and
+// Wait unblocks with the run time recorded. Quit and the context both
+// stopping a run is fine.
+func TestPlayContext(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 3; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * time.Minute), Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Hour), &mts, 1, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pb.PlayContext(ctx)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	waited := make(chan struct{})
+	go func() {
+		pb.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("Wait blocked after the context was canceled")
+	}
+	pb.Quit()
+
+	if pb.Completion() != CompletedCanceled {
+		t.Errorf("Completion = %v; expected CompletedCanceled", pb.Completion())
+	}
+	if pb.WallRunDur < 50*time.Millisecond {
+		t.Errorf("WallRunDur = %v; expected at least 50ms", pb.WallRunDur)
+	}
+}
+
+// TestState confirms State through a play, pause, resume, quit run
+// and after a run that sends all its data
+func TestState(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(time.Hour), Val: 1},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(2*time.Hour), &mts, 1, nil)
+	if s := pb.State(); s != Stopped {
+		t.Errorf("State before Play = %d; expected Stopped", s)
+	}
+
+	pb.Play()
+	if s := pb.State(); s != Playing {
+		t.Errorf("State after Play = %d; expected Playing", s)
+	}
+
+	steps := []struct {
+		cmd  func()
+		want PlayState
+	}{
+		{pb.Pause, Paused},
+		{pb.Pause, Paused},
+		{pb.Resume, Playing},
+		{pb.Resume, Playing},
+		{pb.Pause, Paused},
+		{pb.Quit, Finished},
+	}
+	for i, st := range steps {
+		st.cmd()
+		pb.sync()
+		if s := pb.State(); s != st.want {
+			t.Errorf("step %d: State = %d; expected %d", i, s, st.want)
+		}
+	}
+	pb.Wait()
+	if s := pb.State(); s != Finished {
+		t.Errorf("State after Wait = %d; expected Finished", s)
+	}
+
+	// A run that sends all its data finishes on its own
+	mts.TimeStampers = []TimeStamper{mockTsData{Tim: simStartTime, Val: 1}}
+	pb, _ = New("test", simStartTime, simStartTime, &mts, 1, nil)
+	pb.Play()
+	pb.Wait()
+	if s := pb.State(); s != Finished {
+		t.Errorf("State after run = %d; expected Finished", s)
+	}
+}
+
+// TestConcurrentPauseResume hammers Pause, Resume and State from
+// several goroutines without a Quit, run with -race. The run must
+// still be live and unpaused after a final Resume
+func TestConcurrentPauseResume(t *testing.T) {
+	simStartTime := time.Now()
+	src := pipelineSource(simStartTime, 100, 100*time.Millisecond)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Hour), src, 1, nil)
+	pb.Play()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				pb.Pause()
+				pb.State()
+				pb.Resume()
+			}
+		}()
+	}
+	wg.Wait()
+
+	pb.Resume()
+	pb.sync()
+	if s := pb.State(); s != Playing {
+		t.Errorf("State after final Resume = %d; expected Playing", s)
+	}
+	pb.Quit()
+	pb.Wait()
+	if s := pb.State(); s != Finished {
+		t.Errorf("State after Quit = %d; expected Finished", s)
+	}
+}
@@ -1,36 +1,231 @@
 package gopeat
 
 import (
+	"compress/bzip2"
+	"compress/gzip"
 	"encoding/csv"
+	"errors"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
-// CsvToTs converts a csv line slice to a TimeStamper value
+// ErrMaxSkipRecords is the CsvTsSource error when no record at or
+// after the start time is found within MaxSkipRecords
+var ErrMaxSkipRecords = errors.New("csvTsSource: start time not found within MaxSkipRecords")
+
+// ErrConverter is the CsvTsSource error when a converter panics
+var ErrConverter = errors.New("csvTsSource: converter failed")
+
+// ErrSkipRow is returned by a CsvFillTs converter to skip the row,
+// see CsvToTs
+var ErrSkipRow = errors.New("csvTsSource: skip row")
+
+// errNoStartTime is the CsvTsSource error when Next is called before
+// the start time is set
+var errNoStartTime = errors.New("csvTsSource: start time not set")
+
+// skipProgressEvery is how many skipped records between OnSkipping calls
+const skipProgressEvery = 1000
+
+// CsvToTs converts a csv line slice to a TimeStamper value. It
+// returns the value and a nil error for a row to play, a nil value and
+// a nil error for a row to skip, like a comment or a row out of
+// session, and an error for a row that stops the source, unless
+// CsvTsSource.SkipBadRows skips it.
 type CsvToTs func([]string) (TimeStamper, error)
 
+// CsvFillTs converts a csv line slice into an existing TimeStamper
+// value, typically a pointer acquired from the source's pool. It
+// returns ErrSkipRow for a row to skip, other errors are as CsvToTs.
+type CsvFillTs func([]string, TimeStamper) error
+
 // CsvTsSource implement a time stamped data source for
-// csv data(with header). Client must provide CsvToTs to
-// convert csv data to timestamper value
+// csv data(with header, unless NoHeader). Client must provide CsvToTs to
+// convert csv data to timestamper value, or the Format name of a
+// converter registered with RegisterFormat.
+//
+// To reuse values instead, provide NewTs and CsvTsFill. Values are
+// then acquired from the source's TimeStamperPool and filled from
+// each csv line, see PlayBack.ReleaseAfterSend.
+//
+// Records before the start time are skipped, which can take a while
+// for a wide file. OnSkipping, when set, is called with the count of
+// records scanned every 1000 records skipped until the first record
+// in the bracket is found. A MaxSkipRecords above zero stops Next
+// with ErrMaxSkipRecords when that many records are skipped first.
+//
+// RawCapture provides each value as a RawTs holding the csv line it
+// was parsed from, for PlayBack.SendTsRaw.
+//
+// Rows may have more or fewer fields than the header, ragged rows are
+// passed to the converter which must check the field count before
+// indexing. StrictFields requires every row to have the header's
+// field count, a ragged row then stops Next with the csv error.
+//
+// A row the converter fails on stops Next with the converter's error.
+// SkipBadRows skips the row instead, counted by BadRows.
+//
+// IncludePriorRecord provides the last record before the start time
+// ahead of the first record in the bracket, as the last known value at
+// the start. Playback sends it right away, it isn't paced. Being
+// outside the bracket it ends a PlayBack.StrictBracket run.
 type CsvTsSource struct {
 	Symbol    string
 	CsvStream io.Reader
 	CsvTsConv CsvToTs
+	Format    string
+	CsvTsFill CsvFillTs
+	NewTs     func() TimeStamper
 	csvReader *csv.Reader
 	startTime time.Time
 	endTime   time.Time
 	recCount  int64
 	MaxRecs   int64
+	err       error
+	pool      sync.Pool
+
+	OnSkipping     func(recordsScanned int64)
+	MaxSkipRecords int64
+	skipCount      int64
+
+	RawCapture bool
+	tee        *rawTee
+
+	StrictFields bool
+
+	// Delimiter separates the fields, a comma when zero, and NoHeader
+	// is set for data without a header line
+	Delimiter rune
+	NoHeader  bool
+
+	SkipBadRows bool
+	badRows     int64
+
+	IncludePriorRecord bool
+
+	// prior is the last record skipped before the start time, and
+	// pending the first record in the bracket, provided after it
+	prior   TimeStamper
+	pending TimeStamper
+
+	// done is set once Next has run out, later calls stay done
+	done bool
+
+	// path of the file opened by NewCsvTsSourceFromFile, and the file
+	// while open
+	path string
+	file *os.File
+
+	// seekTime is the time last seeked to, records before it are
+	// skipped, and lastTime the timestamp of the last record provided
+	seekTime time.Time
+	lastTime time.Time
+}
+
+// NewCsvTsSourceFromFile returns a CsvTsSource for the csv file at
+// path, converted by conv. A file named .gz or .bz2 is decompressed as
+// it's read, a read error from the decompression stops Next like a
+// csv read error. The file is closed when Next runs out or on Close,
+// Reset opens it again.
+func NewCsvTsSourceFromFile(path string, conv CsvToTs) (*CsvTsSource, error) {
+	st := &CsvTsSource{CsvTsConv: conv, path: path}
+	if err := st.open(); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// open opens the file at path as the CsvStream
+func (st *CsvTsSource) open() error {
+	file, err := os.Open(st.path)
+	if err != nil {
+		return err
+	}
+	var stream io.Reader = file
+	switch strings.ToLower(filepath.Ext(st.path)) {
+	case ".gz":
+		if stream, err = gzip.NewReader(file); err != nil {
+			file.Close()
+			return err
+		}
+	case ".bz2":
+		stream = bzip2.NewReader(file)
+	}
+	st.CsvStream, st.file = stream, file
+	return nil
 }
 
-// Next implements an iterator for the contents of the csv data
+// Close closes the file opened by NewCsvTsSourceFromFile, a no-op for
+// a CsvStream provided directly. PlayBack closes the source when the
+// run is done reading it.
+func (st *CsvTsSource) Close() error {
+	if st.file == nil {
+		return nil
+	}
+	err := st.file.Close()
+	st.file = nil
+	return err
+}
+
+// Next implements an iterator for the contents of the csv data. Once
+// Next returns false, on the end of the data, MaxRecs or an error, it
+// keeps returning false. A panicking converter stops Next with an
+// error wrapping ErrConverter.
 func (st *CsvTsSource) Next() (TimeStamper, bool) {
+	if st.done {
+		return nil, false
+	}
 	if st.startTime.IsZero() {
-		panic("staticTradeSource: starttime not set")
+		st.err, st.done = errNoStartTime, true
+		return nil, false
+	}
+	ts := st.pending
+	st.pending = nil
+	if ts == nil {
+		var ok bool
+		if ts, ok = st.next(); !ok {
+			st.done = true
+			st.Close()
+			return nil, false
+		}
 	}
+	st.lastTime = ts.GetTimeStamp()
+	return ts, true
+}
+
+// next reads the next value in the bracket
+func (st *CsvTsSource) next() (TimeStamper, bool) {
 	if st.csvReader == nil {
-		st.csvReader = csv.NewReader(st.CsvStream)
-		_, _ = st.csvReader.Read()
+		// Converter by format name
+		if st.CsvTsConv == nil && !st.pooled() && st.Format != "" {
+			st.CsvTsConv, st.err = lookupFormat(st.Format)
+			if st.err != nil {
+				return nil, false
+			}
+		}
+		stream := st.CsvStream
+		if st.RawCapture {
+			st.tee = &rawTee{r: stream}
+			stream = st.tee
+		}
+		st.csvReader = csv.NewReader(stream)
+		if st.Delimiter != 0 {
+			st.csvReader.Comma = st.Delimiter
+		}
+		if !st.StrictFields {
+			st.csvReader.FieldsPerRecord = -1
+		}
+		// Fill converters only read the line, save its allocation
+		st.csvReader.ReuseRecord = st.pooled()
+		if !st.NoHeader {
+			_, _ = st.csvReader.Read()
+			st.takeRaw()
+		}
 	}
 	var trd TimeStamper
 	for {
@@ -38,12 +233,45 @@ func (st *CsvTsSource) Next() (TimeStamper, bool) {
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			panic(err)
+			st.err = err
+			break
 		}
 
-		trd, _ = st.CsvTsConv(line)
+		trd, err = st.convert(line)
+		raw := st.takeRaw()
+		if err != nil && st.SkipBadRows {
+			if trd != nil && st.pooled() {
+				st.ReleaseTimeStamper(trd)
+			}
+			st.badRows++
+			continue
+		}
+		if err != nil {
+			st.err = err
+			break
+		}
+		if trd == nil {
+			// Skipped by the converter
+			continue
+		}
 
 		if trd.GetTimeStamp().Before(st.startTime) {
+			if st.IncludePriorRecord && st.seekTime.IsZero() {
+				trd = st.swapPrior(trd, raw)
+			}
+			if trd != nil && st.pooled() {
+				st.ReleaseTimeStamper(trd)
+			}
+			if !st.skipped() {
+				break
+			}
+			continue
+		}
+
+		if trd.GetTimeStamp().Before(st.seekTime) {
+			if st.pooled() {
+				st.ReleaseTimeStamper(trd)
+			}
 			continue
 		}
 
@@ -56,6 +284,15 @@ func (st *CsvTsSource) Next() (TimeStamper, bool) {
 		if st.recCount == st.MaxRecs {
 			break
 		}
+		if st.RawCapture {
+			trd = RawTs{TimeStamper: trd, Raw: raw}
+		}
+
+		// The prior record goes first
+		if st.prior != nil {
+			st.pending = trd
+			trd, st.prior = st.prior, nil
+		}
 		return trd, true
 
 	}
@@ -63,6 +300,151 @@ func (st *CsvTsSource) Next() (TimeStamper, bool) {
 
 }
 
+// convert converts a csv line to a value, nil for a row to skip,
+// turning a converter panic into an error
+func (st *CsvTsSource) convert(line []string) (trd TimeStamper, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			trd, err = nil, fmt.Errorf("%w: panic: %v", ErrConverter, r)
+		}
+	}()
+	if !st.pooled() {
+		return st.CsvTsConv(line)
+	}
+	trd = st.AcquireTimeStamper()
+	err = st.CsvTsFill(line, trd)
+	if err == ErrSkipRow {
+		st.ReleaseTimeStamper(trd)
+		return nil, nil
+	}
+	return trd, err
+}
+
+// swapPrior keeps trd as the prior record, returning the record it
+// replaces for release
+func (st *CsvTsSource) swapPrior(trd TimeStamper, raw []byte) TimeStamper {
+	prev, _ := unwrapRaw(st.prior)
+	st.prior = trd
+	if st.RawCapture {
+		st.prior = RawTs{TimeStamper: trd, Raw: raw}
+	}
+	return prev
+}
+
+// takeRaw returns the raw bytes of the last line read in RawCapture mode
+func (st *CsvTsSource) takeRaw() []byte {
+	if st.tee == nil {
+		return nil
+	}
+	return st.tee.take(st.csvReader.InputOffset())
+}
+
+// skipped counts a record before the start time and reports progress,
+// returns false when MaxSkipRecords is hit
+func (st *CsvTsSource) skipped() bool {
+	st.skipCount++
+
+	// Only the initial scan to the start reports
+	if st.recCount > 0 {
+		return true
+	}
+	if st.OnSkipping != nil && st.skipCount%skipProgressEvery == 0 {
+		st.OnSkipping(st.skipCount)
+	}
+	if st.MaxSkipRecords > 0 && st.skipCount >= st.MaxSkipRecords {
+		st.err = ErrMaxSkipRecords
+		return false
+	}
+	return true
+}
+
+// BadRows returns the count of rows skipped for SkipBadRows
+func (st *CsvTsSource) BadRows() int64 {
+	return st.badRows
+}
+
+// Skipped returns the count of records skipped for being before the
+// start time, see SkipCounter
+func (st *CsvTsSource) Skipped() int64 {
+	return st.skipCount
+}
+
+// pooled reports if values are filled from the pool
+func (st *CsvTsSource) pooled() bool {
+	return st.CsvTsFill != nil && st.NewTs != nil
+}
+
+// AcquireTimeStamper returns a value from the pool, or a new value
+// from NewTs when the pool is empty
+func (st *CsvTsSource) AcquireTimeStamper() TimeStamper {
+	if ts, ok := st.pool.Get().(TimeStamper); ok {
+		return ts
+	}
+	return st.NewTs()
+}
+
+// ReleaseTimeStamper returns a value to the pool for reuse
+func (st *CsvTsSource) ReleaseTimeStamper(ts TimeStamper) {
+	st.pool.Put(ts)
+}
+
+// Err returns the csv read or conversion error that stopped Next,
+// nil if Next stopped at the end of the data
+func (st *CsvTsSource) Err() error {
+	return st.err
+}
+
+// Reset seeks the csv data back to the beginning, the CsvStream must
+// be an io.Seeker unless the file was opened by
+// NewCsvTsSourceFromFile, which is opened again. See Resettable
+func (st *CsvTsSource) Reset() error {
+	if err := st.rewind(); err != nil {
+		return err
+	}
+	st.csvReader, st.tee = nil, nil
+	st.recCount, st.skipCount, st.badRows = 0, 0, 0
+	st.err, st.done = nil, false
+	st.prior, st.pending = nil, nil
+	st.seekTime, st.lastTime = time.Time{}, time.Time{}
+	return nil
+}
+
+// SeekTo moves to the first record at or after t by reading forward,
+// see Seekable. Seeking back to or before the last record provided
+// starts over from the beginning, the CsvStream must then be an
+// io.Seeker. The records skipped aren't counted by Skipped and no
+// prior record is provided after a seek.
+func (st *CsvTsSource) SeekTo(t time.Time) error {
+	if !st.lastTime.IsZero() && !t.After(st.lastTime) {
+		if err := st.Reset(); err != nil {
+			return err
+		}
+	}
+	st.seekTime = t
+	st.prior = nil
+	if st.pending != nil && st.pending.GetTimeStamp().Before(t) {
+		if ts, _ := unwrapRaw(st.pending); st.pooled() {
+			st.ReleaseTimeStamper(ts)
+		}
+		st.pending = nil
+	}
+	return nil
+}
+
+// rewind starts the CsvStream over
+func (st *CsvTsSource) rewind() error {
+	if st.path != "" {
+		st.Close()
+		return st.open()
+	}
+	seeker, ok := st.CsvStream.(io.Seeker)
+	if !ok {
+		return errors.New("csvTsSource: CsvStream can't seek")
+	}
+	_, err := seeker.Seek(0, io.SeekStart)
+	return err
+}
+
 // SetStartTime sets min timpstamp for data provided
 func (st *CsvTsSource) SetStartTime(startTime time.Time) {
 	st.startTime = startTime
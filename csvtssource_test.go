@@ -0,0 +1,550 @@
+package gopeat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// skipCsv returns csv data with prefix records an hour before start
+// followed by one record at start
+func skipCsv(start time.Time, prefix int) string {
+	var sb strings.Builder
+	sb.WriteString("tim, amt\n")
+	for i := 0; i < prefix; i++ {
+		fmt.Fprintf(&sb, "%s, %d\n",
+			start.Add(-time.Hour).Format(time.RFC3339Nano), i)
+	}
+	fmt.Fprintf(&sb, "%s, %d\n", start.Format(time.RFC3339Nano), prefix)
+	return sb.String()
+}
+
+func skipCsvSource(start time.Time, prefix int) *CsvTsSource {
+	st := &CsvTsSource{
+		CsvStream: strings.NewReader(skipCsv(start, prefix)),
+		CsvTsConv: func(csv []string) (TimeStamper, error) {
+			tim, err := time.Parse(time.RFC3339Nano, csv[0])
+			return mockTsData{Tim: tim}, err
+		},
+	}
+	st.SetStartTime(start)
+	st.SetEndTime(start.Add(time.Hour))
+	return st
+}
+
+// TestCsvOnSkipping confirms skip progress is reported while scanning
+// a large prefix before the start time
+func TestCsvOnSkipping(t *testing.T) {
+	start := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	st := skipCsvSource(start, 5500)
+
+	var progress []int64
+	st.OnSkipping = func(recordsScanned int64) {
+		progress = append(progress, recordsScanned)
+	}
+
+	ts, ok := st.Next()
+	if !ok || !ts.GetTimeStamp().Equal(start) {
+		t.Fatalf("Next = %v, %t; expected the start record", ts, ok)
+	}
+	expected := []int64{1000, 2000, 3000, 4000, 5000}
+	if fmt.Sprint(progress) != fmt.Sprint(expected) {
+		t.Errorf("OnSkipping got %v; expected %v", progress, expected)
+	}
+}
+
+// TestCsvMaxSkipRecords confirms the skip cap stops the source with
+// ErrMaxSkipRecords, and a cap that isn't hit doesn't
+func TestCsvMaxSkipRecords(t *testing.T) {
+	start := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+
+	st := skipCsvSource(start, 100)
+	st.MaxSkipRecords = 50
+	if ts, ok := st.Next(); ok {
+		t.Errorf("Next = %v; expected no record", ts)
+	}
+	if st.Err() != ErrMaxSkipRecords {
+		t.Errorf("Err = %v; expected ErrMaxSkipRecords", st.Err())
+	}
+
+	st = skipCsvSource(start, 100)
+	st.MaxSkipRecords = 101
+	if _, ok := st.Next(); !ok {
+		t.Errorf("Next found no record, err %v", st.Err())
+	}
+}
+
+// TestCsvRawCapture confirms SendTsRaw gets each value with the csv
+// line it was parsed from
+func TestCsvRawCapture(t *testing.T) {
+	simStartTime := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	lines := []string{
+		"2013-09-01T16:59:59Z, 1, early",
+		"2013-09-01T17:00:00.010Z, 2, plain",
+		"2013-09-01T17:00:00.020Z, 3,\"quoted, with comma\"",
+		"2013-09-01T17:00:00.030Z, 4, crlf",
+	}
+	csvData := "tim, amt, note\n" + lines[0] + "\n" + lines[1] + "\n" +
+		lines[2] + "\n" + lines[3] + "\r\n"
+	tsSource := &CsvTsSource{
+		CsvStream:  strings.NewReader(csvData),
+		RawCapture: true,
+		CsvTsConv: func(csv []string) (TimeStamper, error) {
+			tim, err := time.Parse(time.RFC3339Nano, csv[0])
+			return mockTsData{Tim: tim}, err
+		},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second),
+		tsSource, 1, nil)
+
+	var raws []string
+	pb.SendTsRaw = func(ts TimeStamper, raw []byte) error {
+		if _, ok := ts.(mockTsData); !ok {
+			t.Errorf("SendTsRaw got %T; expected mockTsData", ts)
+		}
+		raws = append(raws, string(raw))
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	expected := lines[1:]
+	if fmt.Sprintf("%q", raws) != fmt.Sprintf("%q", expected) {
+		t.Errorf("raw lines %q; expected %q", raws, expected)
+	}
+}
+
+// TestCsvRaggedRows confirms rows with optional trailing fields are
+// passed to the converter, or stop the source with StrictFields
+func TestCsvRaggedRows(t *testing.T) {
+	start := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	csvData := "tim, amt, note\n" +
+		"2013-09-01T17:00:00.010Z, 1, full\n" +
+		"2013-09-01T17:00:00.020Z, 2\n" +
+		"2013-09-01T17:00:00.030Z, 3, extra, field\n"
+	newSource := func() *CsvTsSource {
+		st := &CsvTsSource{
+			CsvStream: strings.NewReader(csvData),
+			CsvTsConv: func(csv []string) (TimeStamper, error) {
+				tim, err := time.Parse(time.RFC3339Nano, csv[0])
+				return mockTsData{Tim: tim, Val: int64(len(csv))}, err
+			},
+		}
+		st.SetStartTime(start)
+		st.SetEndTime(start.Add(time.Second))
+		return st
+	}
+
+	st := newSource()
+	var fieldCnts []int64
+	for ts, ok := st.Next(); ok; ts, ok = st.Next() {
+		fieldCnts = append(fieldCnts, ts.(mockTsData).Val)
+	}
+	if st.Err() != nil {
+		t.Errorf("Err = %v; expected nil", st.Err())
+	}
+	if fmt.Sprint(fieldCnts) != "[3 2 4]" {
+		t.Errorf("field counts %v; expected [3 2 4]", fieldCnts)
+	}
+
+	st = newSource()
+	st.StrictFields = true
+	cnt := 0
+	for _, ok := st.Next(); ok; _, ok = st.Next() {
+		cnt++
+	}
+	if cnt != 1 || st.Err() == nil {
+		t.Errorf("strict got %d rows, err %v; expected 1 row and an error",
+			cnt, st.Err())
+	}
+}
+
+// FuzzCsvTsSource feeds arbitrary csv data through each converter
+// behavior, Next must not panic and must run out and stay out
+func FuzzCsvTsSource(f *testing.F) {
+	start := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	at := start.Add(time.Second).Format(time.RFC3339Nano)
+	f.Add([]byte(""), uint8(0))
+	f.Add([]byte("tim, amt\n"), uint8(0))
+	f.Add([]byte("tim, amt\n"+at+", 1\n"+at), uint8(0))
+	f.Add([]byte("tim, amt\n"+at+"\n"+at+", 1, 2, 3\n"), uint8(1))
+	f.Add([]byte("tim, amt\n"+at+", \"12\n"), uint8(0))
+	f.Add([]byte("tim, amt\n"+at+", "+strings.Repeat("9", 1<<16)+"\n"), uint8(1))
+	f.Add([]byte("tim\n"+at+"\n"+at+"\n"), uint8(2))
+	f.Add([]byte("tim\n\n\n"+at+"\n"), uint8(3))
+
+	f.Fuzz(func(t *testing.T, data []byte, mode uint8) {
+		st := &CsvTsSource{
+			CsvStream: bytes.NewReader(data),
+			CsvTsConv: func(csv []string) (TimeStamper, error) {
+				switch mode % 4 {
+				case 1:
+					// Indexes without checking the field count
+					amt, _ := strconv.Atoi(strings.TrimSpace(csv[1]))
+					return mockTsData{Tim: start, Val: int64(amt)}, nil
+				case 2:
+					return nil, nil
+				case 3:
+					return nil, errors.New("bad line")
+				}
+				tim, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(csv[0]))
+				return mockTsData{Tim: tim}, err
+			},
+		}
+		st.SetStartTime(start)
+		st.SetEndTime(start.Add(time.Hour))
+
+		// Each value takes up at least one line
+		maxCalls := bytes.Count(data, []byte("\n")) + 2
+		calls := 0
+		for _, ok := st.Next(); ok; _, ok = st.Next() {
+			calls++
+			if calls > maxCalls {
+				t.Fatalf("Next still going after %d calls", calls)
+			}
+		}
+		if ts, ok := st.Next(); ok || ts != nil {
+			t.Errorf("Next after the end = %v, %t; expected nil, false", ts, ok)
+		}
+	})
+}
+
+// TestCsvIncludePriorRecord starts between records, the record before
+// the start is sent first at once and the rest are paced from the start
+func TestCsvIncludePriorRecord(t *testing.T) {
+	simStartTime := time.Now()
+	var sb strings.Builder
+	sb.WriteString("tim, amt\n")
+	for i, off := range []time.Duration{-20, -10, 10, 20} {
+		fmt.Fprintf(&sb, "%s, %d\n", simStartTime.Add(off*time.Millisecond).
+			Format(time.RFC3339Nano), i+1)
+	}
+	st := &CsvTsSource{
+		CsvStream: strings.NewReader(sb.String()),
+		CsvTsConv: func(csv []string) (TimeStamper, error) {
+			tim, err := time.Parse(time.RFC3339Nano, csv[0])
+			if err != nil {
+				return nil, err
+			}
+			val, err := strconv.ParseInt(strings.TrimSpace(csv[1]), 10, 64)
+			return mockTsData{Tim: tim, Val: val}, err
+		},
+		IncludePriorRecord: true,
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), st, 1, nil)
+
+	var vals []int64
+	var sendDurs []time.Duration
+	pb.SendTs = func(ts TimeStamper) error {
+		vals = append(vals, ts.(mockTsData).Val)
+		sendDurs = append(sendDurs, time.Since(pb.WallStartTime))
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+
+	if fmt.Sprint(vals) != "[2 3 4]" {
+		t.Fatalf("Got %v; expected [2 3 4]", vals)
+	}
+	for i, exp := range []time.Duration{0, 10, 20} {
+		d := (sendDurs[i] - exp*time.Millisecond).Seconds() * 1000
+		if d < -3 || d > 3 {
+			t.Errorf("send %d Time = %f(ms); want less than 3(ms)", i, d)
+		}
+	}
+}
+
+// TestCsvReset plays a csv source twice with a Reset between, the
+// second run sends the same records
+func TestCsvReset(t *testing.T) {
+	start := time.Now()
+	st := skipCsvSource(start, 3)
+	pb, _ := New("test", start, start.Add(time.Hour), st, 1, nil)
+
+	for run := 1; run <= 2; run++ {
+		res := pb.Run()
+		if res.Records != 1 || res.Skipped != 3 {
+			t.Errorf("Run %d Records, Skipped = %d, %d; expected 1, 3",
+				run, res.Records, res.Skipped)
+		}
+		if err := pb.Reset(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mts mockSliceBackedDs
+	pb, _ = New("test", start, start.Add(time.Hour), &mts, 1, nil)
+	if err := pb.Reset(); err != ErrNotResettable {
+		t.Errorf("Reset err = %v; expected ErrNotResettable", err)
+	}
+}
+
+// TestCsvSeekTo seeks forward by reading ahead and back by starting
+// over, which needs a seekable stream
+func TestCsvSeekTo(t *testing.T) {
+	start := time.Now()
+	var sb strings.Builder
+	sb.WriteString("tim, amt\n")
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&sb, "%s, %d\n", start.Add(time.Duration(i)*time.Second).
+			Format(time.RFC3339Nano), i)
+	}
+	conv := func(csv []string) (TimeStamper, error) {
+		tim, err := time.Parse(time.RFC3339Nano, csv[0])
+		if err != nil {
+			return nil, err
+		}
+		val, err := strconv.ParseInt(strings.TrimSpace(csv[1]), 10, 64)
+		return mockTsData{Tim: tim, Val: val}, err
+	}
+	st := &CsvTsSource{CsvStream: strings.NewReader(sb.String()), CsvTsConv: conv}
+	st.SetStartTime(start)
+	st.SetEndTime(start.Add(time.Minute))
+
+	next := func(expected int64) {
+		t.Helper()
+		if ts, ok := st.Next(); !ok || ts.(mockTsData).Val != expected {
+			t.Fatalf("Next = %v, %t; expected %d", ts, ok, expected)
+		}
+	}
+	next(1)
+	next(2)
+	for _, seek := range []struct {
+		sec int
+		val int64
+	}{{5, 5}, {8, 8}, {3, 3}, {9, 9}} {
+		if err := st.SeekTo(start.Add(time.Duration(seek.sec) * time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		next(seek.val)
+	}
+	next(10)
+
+	// Not an io.Seeker, only forward
+	st = &CsvTsSource{CsvStream: io.MultiReader(strings.NewReader(sb.String())),
+		CsvTsConv: conv}
+	st.SetStartTime(start)
+	st.SetEndTime(start.Add(time.Minute))
+	if err := st.SeekTo(start.Add(4 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	next(4)
+	if err := st.SeekTo(start.Add(time.Second)); err == nil {
+		t.Error("Seek back without io.Seeker; expected error")
+	}
+}
+
+// TestCsvSkipBadRows plays csv data with an unparseable row in the
+// middle, which stops the run on its error unless SkipBadRows skips it
+func TestCsvSkipBadRows(t *testing.T) {
+	start := time.Now()
+	var sb strings.Builder
+	sb.WriteString("tim, amt\n")
+	for i := 1; i <= 5; i++ {
+		if i == 3 {
+			sb.WriteString("not a time, 3\n")
+			continue
+		}
+		fmt.Fprintf(&sb, "%s, %d\n", start.Add(time.Duration(i)*time.Millisecond).
+			Format(time.RFC3339Nano), i)
+	}
+
+	for _, skip := range []bool{false, true} {
+		st := &CsvTsSource{
+			CsvStream: strings.NewReader(sb.String()),
+			CsvTsConv: func(csv []string) (TimeStamper, error) {
+				tim, err := time.Parse(time.RFC3339Nano, csv[0])
+				return mockTsData{Tim: tim}, err
+			},
+			SkipBadRows: skip,
+		}
+		pb, _ := New("test", start, start.Add(time.Minute), st, 1, nil)
+		res := pb.Run()
+
+		if skip {
+			if res.Completion != CompletedOK || res.Records != 4 || st.BadRows() != 1 {
+				t.Errorf("Skipping Completion, Records, BadRows = %v, %d, %d; expected CompletedOK, 4, 1",
+					res.Completion, res.Records, st.BadRows())
+			}
+			continue
+		}
+		var perr *time.ParseError
+		if res.Completion != CompletedError || !errors.As(res.Err, &perr) || res.Records != 2 {
+			t.Errorf("Completion, Err, Records = %v, %v, %d; expected CompletedError, a parse error, 2",
+				res.Completion, res.Err, res.Records)
+		}
+	}
+
+	// Next without a start time stops on an error
+	st := &CsvTsSource{CsvStream: strings.NewReader(sb.String())}
+	if _, ok := st.Next(); ok || st.Err() == nil {
+		t.Errorf("Next without start time = %t, %v; expected an error", ok, st.Err())
+	}
+}
+
+// TestCsvConverterSkip confirms the three converter results, a value
+// plays, a nil value with no error skips the row and an error stops
+// the source, for both converter kinds
+func TestCsvConverterSkip(t *testing.T) {
+	start := time.Now()
+	var sb strings.Builder
+	sb.WriteString("tim, amt\n")
+	for _, row := range []string{"1", "skip", "3", "stop", "5"} {
+		fmt.Fprintf(&sb, "%s, %s\n", start.Add(time.Millisecond).Format(time.RFC3339Nano), row)
+	}
+	parse := func(csv []string) (int64, error) {
+		switch row := strings.TrimSpace(csv[1]); row {
+		case "skip":
+			return 0, ErrSkipRow
+		case "stop":
+			return 0, errors.New("stop")
+		default:
+			return strconv.ParseInt(row, 10, 64)
+		}
+	}
+	sources := map[string]*CsvTsSource{
+		"CsvTsConv": {CsvTsConv: func(csv []string) (TimeStamper, error) {
+			val, err := parse(csv)
+			if err == ErrSkipRow {
+				return nil, nil
+			}
+			return mockTsData{Tim: start.Add(time.Millisecond), Val: val}, err
+		}},
+		"CsvTsFill": {
+			NewTs: func() TimeStamper { return &mockTsData{Tim: start.Add(time.Millisecond)} },
+			CsvTsFill: func(csv []string, ts TimeStamper) (err error) {
+				ts.(*mockTsData).Val, err = parse(csv)
+				return err
+			}},
+	}
+
+	for name, st := range sources {
+		st.CsvStream = strings.NewReader(sb.String())
+		st.SetStartTime(start)
+		st.SetEndTime(start.Add(time.Minute))
+
+		var vals []int64
+		for ts, ok := st.Next(); ok; ts, ok = st.Next() {
+			if mts, isPtr := ts.(*mockTsData); isPtr {
+				ts = *mts
+			}
+			vals = append(vals, ts.(mockTsData).Val)
+		}
+		if fmt.Sprint(vals) != "[1 3]" || st.Err() == nil || st.Err().Error() != "stop" {
+			t.Errorf("%s got %v, %v; expected [1 3], stop", name, vals, st.Err())
+		}
+	}
+}
+
+// TestCsvFromFile plays plain, gzip and bzip2 csv files, each closed
+// once read and opened again by Reset
+func TestCsvFromFile(t *testing.T) {
+	start := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	data := "tim, amt\n2013-09-01T17:00:00.001Z, 1\n2013-09-01T17:00:00.002Z, 2\n"
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(data))
+	zw.Close()
+	bz, _ := hex.DecodeString("425a6839314159265359a2a425b40000205b800010400778b00410202204" +
+		"0020005440269a064c0aa9a40d0da269aeadc530d4aeb984a164b742130ca2" +
+		"95c2b241918e713553ab177245385090a2a425b4")
+
+	dir := t.TempDir()
+	conv := func(csv []string) (TimeStamper, error) {
+		tim, err := time.Parse(time.RFC3339Nano, csv[0])
+		return mockTsData{Tim: tim}, err
+	}
+	for name, content := range map[string][]byte{
+		"trades.csv": []byte(data), "trades.csv.gz": gz.Bytes(), "trades.csv.bz2": bz} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		st, err := NewCsvTsSourceFromFile(path, conv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pb, _ := New("test", start, start.Add(time.Second), st, 1, nil)
+		for run := 1; run <= 2; run++ {
+			if res := pb.Run(); res.Completion != CompletedOK || res.Records != 2 {
+				t.Errorf("%s run %d Completion, Records = %v, %d; expected CompletedOK, 2",
+					name, run, res.Completion, res.Records)
+			}
+			if st.file != nil {
+				t.Errorf("%s run %d file open after the run", name, run)
+			}
+			if err := pb.Reset(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// A truncated archive stops the run on the read error
+	path := filepath.Join(dir, "short.csv.gz")
+	if err := os.WriteFile(path, gz.Bytes()[:gz.Len()-6], 0o600); err != nil {
+		t.Fatal(err)
+	}
+	st, err := NewCsvTsSourceFromFile(path, conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, _ := New("test", start, start.Add(time.Second), st, 1, nil)
+	if res := pb.Run(); res.Completion != CompletedError ||
+		!errors.Is(res.Err, io.ErrUnexpectedEOF) {
+		t.Errorf("Completion, Err = %v, %v; expected CompletedError, unexpected EOF",
+			res.Completion, res.Err)
+	}
+}
+
+// TestCsvDelimiterNoHeader reads tab separated data and data without
+// a header line
+func TestCsvDelimiterNoHeader(t *testing.T) {
+	start := time.Now()
+	conv := func(csv []string) (TimeStamper, error) {
+		if len(csv) != 2 {
+			return nil, fmt.Errorf("%d fields; expected 2", len(csv))
+		}
+		tim, err := time.Parse(time.RFC3339Nano, csv[0])
+		if err != nil {
+			return nil, err
+		}
+		val, err := strconv.ParseInt(csv[1], 10, 64)
+		return mockTsData{Tim: tim, Val: val}, err
+	}
+	rows := func(header string, sep string) string {
+		var sb strings.Builder
+		sb.WriteString(header)
+		for i := 1; i <= 3; i++ {
+			fmt.Fprintf(&sb, "%s%s%d\n", start.Add(time.Duration(i)*time.Millisecond).
+				Format(time.RFC3339Nano), sep, i)
+		}
+		return sb.String()
+	}
+
+	for name, st := range map[string]*CsvTsSource{
+		"tsv":      {CsvStream: strings.NewReader(rows("tim\tamt\n", "\t")), Delimiter: '\t'},
+		"noHeader": {CsvStream: strings.NewReader(rows("", ",")), NoHeader: true},
+	} {
+		st.CsvTsConv = conv
+		st.SetStartTime(start)
+		st.SetEndTime(start.Add(time.Minute))
+
+		var vals []int64
+		for ts, ok := st.Next(); ok; ts, ok = st.Next() {
+			vals = append(vals, ts.(mockTsData).Val)
+		}
+		if fmt.Sprint(vals) != "[1 2 3]" || st.Err() != nil {
+			t.Errorf("%s got %v, %v; expected [1 2 3]", name, vals, st.Err())
+		}
+	}
+}
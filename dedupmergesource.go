@@ -0,0 +1,93 @@
+package gopeat
+
+import (
+	"context"
+	"fmt"
+)
+
+// defaultDedupWindow is the number of emitted records a
+// DedupMergedSource remembers when the window is zero
+const defaultDedupWindow = 1024
+
+// DedupMergedSource is a time stamped data source that merges sources
+// dropping repeated records, see DedupMergeSources
+type DedupMergedSource struct {
+	sourceWrap
+	identity func(TimeStamper) string
+	seen     map[dedupKey]struct{}
+	ring     []dedupKey
+	ringPos  int
+}
+
+// dedupKey identifies an emitted record
+type dedupKey struct {
+	tim int64
+	id  string
+}
+
+// DedupMergeSources returns a source that merges timestamp ordered
+// sources like MergeSources, for example spliced daily files, and
+// drops a record whose timestamp and identity match a record already
+// emitted, so the overlap between files is sent once.
+//
+// identity returns a record's identity, the record formatted with %v
+// when nil. Only the last window emitted records, 1024 when 0 or
+// less, are remembered, duplicates must be closer together than that.
+func DedupMergeSources(identity func(TimeStamper) string, window int,
+	sources ...TimeStampSource) *DedupMergedSource {
+	if window <= 0 {
+		window = defaultDedupWindow
+	}
+	return &DedupMergedSource{
+		sourceWrap: sourceWrap{src: MergeSources(sources...)},
+		identity:   identity,
+		seen:       make(map[dedupKey]struct{}, window),
+		ring:       make([]dedupKey, 0, window),
+	}
+}
+
+// Next implements an iterator for the merged records, repeats dropped
+func (ds *DedupMergedSource) Next() (TimeStamper, bool) {
+	return ds.NextContext(context.Background())
+}
+
+// NextContext is Next, returning false once ctx is done, see
+// CancellableSource
+func (ds *DedupMergedSource) NextContext(ctx context.Context) (TimeStamper, bool) {
+	for ctx.Err() == nil {
+		tsData, ok := ds.next(ctx)
+		if !ok || tsData == nil || ds.remember(ds.key(tsData)) {
+			return tsData, ok
+		}
+	}
+	return nil, false
+}
+
+// key returns the dedup key of a record
+func (ds *DedupMergedSource) key(tsData TimeStamper) dedupKey {
+	k := dedupKey{tim: tsData.GetTimeStamp().UnixNano()}
+	if ds.identity != nil {
+		k.id = ds.identity(tsData)
+	} else {
+		k.id = fmt.Sprintf("%v", tsData)
+	}
+	return k
+}
+
+// remember adds an emitted record's key to the window, dropping the
+// oldest key when full. Returns false if the key is already in the
+// window, the record is a duplicate.
+func (ds *DedupMergedSource) remember(k dedupKey) bool {
+	if _, dup := ds.seen[k]; dup {
+		return false
+	}
+	if len(ds.ring) < cap(ds.ring) {
+		ds.ring = append(ds.ring, k)
+	} else {
+		delete(ds.seen, ds.ring[ds.ringPos])
+		ds.ring[ds.ringPos] = k
+		ds.ringPos = (ds.ringPos + 1) % len(ds.ring)
+	}
+	ds.seen[k] = struct{}{}
+	return true
+}
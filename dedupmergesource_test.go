@@ -0,0 +1,121 @@
+package gopeat
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestDedupMergeSources merges two overlapping days, the overlap
+// duplicates are emitted once and a distinct record at a duplicate
+// timestamp is kept
+func TestDedupMergeSources(t *testing.T) {
+	base := time.Date(2013, 9, 1, 23, 58, 0, 0, time.UTC)
+	rec := func(mins int, val int64) TimeStamper {
+		return mockTsData{Tim: base.Add(time.Duration(mins) * time.Minute), Val: val}
+	}
+	day1 := &mockSliceBackedDs{TimeStampers: []TimeStamper{
+		rec(0, 1), rec(1, 2), rec(2, 3), rec(3, 4),
+	}}
+	day2 := &mockSliceBackedDs{TimeStampers: []TimeStamper{
+		rec(2, 3), rec(3, 4), rec(3, 40), rec(4, 5), rec(5, 6),
+	}}
+	ms := DedupMergeSources(nil, 0, day1, day2)
+
+	var vals []int64
+	for ts, ok := ms.Next(); ok; ts, ok = ms.Next() {
+		vals = append(vals, ts.(mockTsData).Val)
+	}
+	expected := []int64{1, 2, 3, 4, 40, 5, 6}
+	if len(vals) != len(expected) {
+		t.Fatalf("Got %v; expected %v", vals, expected)
+	}
+	for i := range expected {
+		if vals[i] != expected[i] {
+			t.Fatalf("Got %v; expected %v", vals, expected)
+		}
+	}
+}
+
+// TestDedupWindow confirms only the last window records are
+// remembered
+func TestDedupWindow(t *testing.T) {
+	tim := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	src := &mockSliceBackedDs{TimeStampers: []TimeStamper{
+		mockTsData{Tim: tim, Val: 1},
+		mockTsData{Tim: tim, Val: 2},
+		mockTsData{Tim: tim, Val: 3},
+		mockTsData{Tim: tim, Val: 1},
+		mockTsData{Tim: tim, Val: 3},
+	}}
+	ms := DedupMergeSources(func(ts TimeStamper) string {
+		return string(rune('0' + ts.(mockTsData).Val))
+	}, 2, src)
+
+	cnt := 0
+	for _, ok := ms.Next(); ok; _, ok = ms.Next() {
+		cnt++
+	}
+
+	// 1 fell out of the window before its repeat, 3 didn't
+	if cnt != 4 {
+		t.Errorf("Got %d records; expected 4", cnt)
+	}
+}
+
+// TestDedupMergeBracket confirms the records of sources that don't
+// bracket themselves are kept to the playback bracket
+func TestDedupMergeBracket(t *testing.T) {
+	simStartTime := time.Now()
+	rec := func(d time.Duration, val int64) TimeStamper {
+		return mockTsData{Tim: simStartTime.Add(d), Val: val}
+	}
+	a := &mockNextOnlyDs{TimeStampers: []TimeStamper{
+		rec(-time.Hour, 1), rec(time.Millisecond, 2), rec(2*time.Hour, 3),
+	}}
+	b := &mockNextOnlyDs{TimeStampers: []TimeStamper{
+		rec(time.Millisecond, 2), rec(2*time.Millisecond, 4),
+	}}
+	ms := DedupMergeSources(nil, 0, a, b)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Hour), ms, 1, nil)
+
+	var vals []int64
+	pb.SendTs = func(ts TimeStamper) error {
+		vals = append(vals, ts.(mockTsData).Val)
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+	if fmt.Sprint(vals) != "[2 4]" {
+		t.Errorf("Got %v; expected [2 4]", vals)
+	}
+}
+
+// TestDedupMergeNil confirms a nil record from a source is passed on
+// without taking its identity, and dedup carries on after it
+func TestDedupMergeNil(t *testing.T) {
+	simStartTime := time.Now()
+	rec := func(ms int, val int64) TimeStamper {
+		return mockTsData{Tim: simStartTime.Add(time.Duration(ms) * time.Millisecond), Val: val}
+	}
+	a := &mockNextOnlyDs{TimeStampers: []TimeStamper{rec(1, 1), nil, rec(2, 2)}}
+	b := &mockSliceBackedDs{TimeStampers: []TimeStamper{nil, rec(2, 2), rec(3, 3)}}
+	identity := func(ts TimeStamper) string {
+		return fmt.Sprint(ts.(mockTsData).Val)
+	}
+	ms := DedupMergeSources(identity, 0, a, b)
+
+	// 0 stands for a nil record
+	var vals []int64
+	for ts, ok := ms.Next(); ok; ts, ok = ms.Next() {
+		if ts == nil {
+			vals = append(vals, 0)
+			continue
+		}
+		vals = append(vals, ts.(mockTsData).Val)
+	}
+	expected := "[0 1 0 2 3]"
+	if fmt.Sprint(vals) != expected {
+		t.Errorf("Got %v; expected %s", vals, expected)
+	}
+}
@@ -0,0 +1,97 @@
+package gopeat
+
+import "time"
+
+// DriftController turns the drift of each send, the wall time it went
+// out past its schedule, into the correction taken off the sleep
+// before the next send. A positive correction sends earlier to make
+// up for a slow client callback. Playback resets the controller at
+// the start of each run.
+type DriftController interface {
+	Reset()
+	Correction(drift time.Duration) time.Duration
+}
+
+// DefaultPIDDrift is a proportional-integral tuning that takes a
+// client's occasional slow callback back out over the next several
+// sends instead of all at once
+var DefaultPIDDrift = PIDDrift{Kp: 0.05, Ki: 0.15}
+
+// PIDDrift is a PID DriftController, the correction is the drift
+// times Kp plus the sum of drifts times Ki plus the change in drift
+// times Kd. Without a DriftController playback sums the drifts, the
+// same as a Ki of 1 alone. That makes up a slow send in full on the
+// next one, which then goes out early by as much, a lower Ki spreads
+// the make up over more sends and a Kp reacts more to the latest
+// drift. Copy a PIDDrift per PlayBack, it holds the run's state.
+type PIDDrift struct {
+	Kp, Ki, Kd float64
+
+	// IntegralMax bounds the sum of drifts term to +/- IntegralMax,
+	// so a run of slow sends doesn't wind up a sum that keeps the
+	// correction up long after. 0 uses DefaultMaxDriftCorrection,
+	// below 0 leaves it unbounded.
+	IntegralMax time.Duration
+
+	integral  time.Duration
+	prevDrift time.Duration
+}
+
+// Reset clears the run state
+func (pd *PIDDrift) Reset() {
+	pd.integral, pd.prevDrift = 0, 0
+}
+
+// Correction returns the correction for the latest drift
+func (pd *PIDDrift) Correction(drift time.Duration) time.Duration {
+	pd.integral += drift
+	if limit := pd.IntegralMax; limit >= 0 && pd.Ki > 0 {
+		if limit == 0 {
+			limit = DefaultMaxDriftCorrection
+		}
+		pd.integral = clampDrift(pd.integral, time.Duration(float64(limit)/pd.Ki))
+	}
+	c := pd.Kp*float64(drift) + pd.Ki*float64(pd.integral) +
+		pd.Kd*float64(drift-pd.prevDrift)
+	pd.prevDrift = drift
+	return time.Duration(c)
+}
+
+// DefaultMaxDriftCorrection bounds the drift correction of a PlayBack
+// that doesn't set MaxDriftCorrection
+const DefaultMaxDriftCorrection = 250 * time.Millisecond
+
+// clampDrift bounds correction to +/- limit, a limit of 0 or less
+// leaves it unbounded
+func clampDrift(correction, limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return correction
+	}
+	if correction > limit {
+		return limit
+	}
+	if correction < -limit {
+		return -limit
+	}
+	return correction
+}
+
+// sumDrift is the DriftController used when none is set, the
+// correction is the sum of the drifts. The sum is held to +/- limit so
+// a run of slow sends doesn't pile up a debt the later sends pay back
+// by going out early for as long.
+type sumDrift struct {
+	sum   time.Duration
+	limit time.Duration
+}
+
+// Reset clears the sum
+func (sm *sumDrift) Reset() {
+	sm.sum = 0
+}
+
+// Correction adds drift to the sum
+func (sm *sumDrift) Correction(drift time.Duration) time.Duration {
+	sm.sum = clampDrift(sm.sum+drift, sm.limit)
+	return sm.sum
+}
@@ -0,0 +1,225 @@
+package gopeat
+
+import (
+	"testing"
+	"time"
+)
+
+// driftRun plays 24 values 20ms apart to a client whose every fourth
+// callback takes 30ms, and returns the earliest send drift once the
+// correction has settled
+func driftRun(t *testing.T, dc DriftController) time.Duration {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 24; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * 20 * time.Millisecond),
+			Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), &mts, 1, nil)
+	pb.DriftController = dc
+	pb.SendTs = func(ts TimeStamper) error {
+		if ts.(mockTsData).Val%4 == 0 {
+			time.Sleep(30 * time.Millisecond)
+		}
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+
+	timings := pb.Timings()
+	if len(timings) != 24 {
+		t.Fatalf("Got %d timings; expected 24", len(timings))
+	}
+	var earliest time.Duration
+	for _, rt := range timings[8:] {
+		earliest = min(earliest, rt.DriftDur)
+	}
+	return earliest
+}
+
+// TestPIDDrift confirms the PID tuning makes up a periodic slow
+// callback without the default's early send overshoot
+func TestPIDDrift(t *testing.T) {
+	sum := driftRun(t, nil)
+	pid := DefaultPIDDrift
+	pi := driftRun(t, &pid)
+
+	// The default sends the value after a slow callback the whole
+	// 10ms early
+	if sum > -8*time.Millisecond {
+		t.Errorf("Default earliest drift = %v; expected about -10ms", sum)
+	}
+	if pi < sum+4*time.Millisecond {
+		t.Errorf("PID earliest drift = %v; expected at least 4ms later than %v",
+			pi, sum)
+	}
+}
+
+// TestPIDDriftWindup confirms a long run of slow sends leaves the
+// bounded integral recovering within a few dozen early sends, while the
+// unbounded one stays wound up for about as long as the slow run
+func TestPIDDriftWindup(t *testing.T) {
+	recovery := func(max time.Duration) int {
+		pd := PIDDrift{Ki: 0.15, IntegralMax: max}
+		for range 200 {
+			pd.Correction(50 * time.Millisecond)
+		}
+		n := 0
+		for n < 10000 && pd.Correction(-20*time.Millisecond) > 0 {
+			n++
+		}
+		return n
+	}
+	if n := recovery(-1); n < 400 {
+		t.Errorf("Unbounded recovered in %d sends; expected at least 400", n)
+	}
+	if n := recovery(0); n > 100 {
+		t.Errorf("Bounded recovered in %d sends; expected at most 100", n)
+	}
+}
+
+// TestDriftStats confirms the stats of a run with a prompt client are
+// in milliseconds and the run takes the expected time
+func TestDriftStats(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 5; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * 10 * time.Millisecond),
+			Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), &mts, 1, nil)
+	pb.Play()
+	pb.Wait()
+
+	stats := pb.DriftStats()
+	if stats.TotalRecords != 5 {
+		t.Errorf("TotalRecords = %d; expected 5", stats.TotalRecords)
+	}
+	if stats.MaxDriftMs > 3 || stats.MeanDriftMs > stats.MaxDriftMs {
+		t.Errorf("Max, Mean drift = %f, %f(ms); want max under 3(ms), mean under max",
+			stats.MaxDriftMs, stats.MeanDriftMs)
+	}
+	if stats.ExpectedRunDuration != 50*time.Millisecond {
+		t.Errorf("ExpectedRunDuration = %v; expected 50ms", stats.ExpectedRunDuration)
+	}
+	d := (stats.ActualRunDuration - stats.ExpectedRunDuration).Seconds() * 1000
+	if d < 0 || d > 5 {
+		t.Errorf("ActualRunDuration off by %f(ms); want 0 to 5(ms)", d)
+	}
+}
+
+// earlySends plays 40 values 10ms apart to a client whose first 5
+// callbacks take 40ms, and returns how many of the sends after them
+// go out over 5ms early
+func earlySends(t *testing.T, maxDrift time.Duration) int {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 40; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * 10 * time.Millisecond),
+			Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), &mts, 1, nil)
+	pb.MaxDriftCorrection = maxDrift
+	pb.SendTs = func(ts TimeStamper) error {
+		if ts.(mockTsData).Val <= 5 {
+			time.Sleep(40 * time.Millisecond)
+		}
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+
+	timings := pb.Timings()
+	if len(timings) != 40 {
+		t.Fatalf("Got %d timings; expected 40", len(timings))
+	}
+	early := 0
+	for _, rt := range timings[6:] {
+		if rt.DriftDur < -5*time.Millisecond {
+			early++
+		}
+	}
+	return early
+}
+
+// TestMaxDriftCorrection confirms a client that is slow for a while
+// leaves the unbounded correction sending early long after, and the
+// bounded one pacing again within a couple of sends
+func TestMaxDriftCorrection(t *testing.T) {
+	if early := earlySends(t, -1); early < 8 {
+		t.Errorf("Unbounded sent %d early; expected at least 8", early)
+	}
+	if early := earlySends(t, 15*time.Millisecond); early > 3 {
+		t.Errorf("Bounded sent %d early; expected at most 3", early)
+	}
+}
+
+// TestSendDeadline confirms the callbacks over the SendDeadline are
+// reported to OnSlowSend and counted in the DriftStats
+func TestSendDeadline(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 10; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * 5 * time.Millisecond),
+			Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), &mts, 1, nil)
+	pb.SendDeadline = 10 * time.Millisecond
+	pb.SendTs = func(ts TimeStamper) error {
+		if val := ts.(mockTsData).Val; val == 3 || val == 7 {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return nil
+	}
+	var recs []int64
+	pb.OnSlowSend = func(rec int64, took time.Duration) {
+		recs = append(recs, rec)
+		if took < 20*time.Millisecond {
+			t.Errorf("Send %d took %v; expected at least 20ms", rec, took)
+		}
+	}
+	pb.Play()
+	pb.Wait()
+
+	if len(recs) != 2 || recs[0] != 3 || recs[1] != 7 {
+		t.Errorf("OnSlowSend got %v; expected [3 7]", recs)
+	}
+	stats := pb.DriftStats()
+	if stats.SlowSends != 2 || stats.SlowSendOverrun < 20*time.Millisecond {
+		t.Errorf("SlowSends, SlowSendOverrun = %d, %v; expected 2, at least 20ms",
+			stats.SlowSends, stats.SlowSendOverrun)
+	}
+}
+
+// TestTimingSampleRate confirms a sampled run keeps one timing per
+// TimingSampleRate sends, so the timings don't grow with the record
+// count, while the stats still count every send
+func TestTimingSampleRate(t *testing.T) {
+	simStartTime := time.Now()
+	for _, n := range []int{10000, 100000} {
+		ss := pipelineSource(simStartTime, n, time.Millisecond)
+		endTime := simStartTime.Add(time.Hour)
+		pb, _ := New("test", simStartTime, endTime, ss, 1, nil)
+		clock := make(chan time.Time, 1)
+		clock <- endTime
+		pb.ExternalClock = clock
+		pb.TimingSampleRate = n / 100
+
+		res := pb.Run()
+		if kept := len(pb.Timings()); kept != 100 {
+			t.Errorf("%d records kept %d timings; expected 100", n, kept)
+		}
+		if stats := pb.DriftStats(); stats.TotalRecords != int64(n) {
+			t.Errorf("TotalRecords = %d; expected %d", stats.TotalRecords, n)
+		}
+		span := time.Duration(n-1) * time.Millisecond
+		if res.Records != int64(n) || res.SimSpan != span {
+			t.Errorf("Records, SimSpan = %d, %v; expected %d, %v", res.Records,
+				res.SimSpan, n, span)
+		}
+	}
+}
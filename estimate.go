@@ -0,0 +1,102 @@
+package gopeat
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// EstimateRunTime returns the wall time a run is expected to take,
+// from the first sampleRecords values read ahead of Play instead of
+// the whole source. The values read aren't lost, they're played
+// first, see AutoTune.
+//
+// The estimate is only as good as what's known of the source's last
+// value. It's exact when the sample reaches the end of the source or
+// the source implements TimeRanger. Otherwise the sample is taken to
+// continue to the EndTime, so the estimate is an upper bound that's
+// close for a source filling the bracket and long for one ending
+// early. The Calendar and RateTriggers are accounted for, pauses,
+// later rate changes and a client slower than the pacing are not.
+func (pb *PlayBack) EstimateRunTime(sampleRecords int) (time.Duration, error) {
+	if sampleRecords < 1 {
+		return 0, errors.New("playBack: sample records must be at least 1")
+	}
+	if !pb.IsIdle() {
+		return 0, errors.New("playBack: can't estimate while running")
+	}
+
+	for len(pb.primed) < sampleRecords && !pb.primedDone {
+		tsData, ok := pb.sourceNext(context.Background())
+		if !ok {
+			pb.primedDone = true
+			break
+		}
+		pb.primed = append(pb.primed, tsData)
+	}
+	if pb.primedDone {
+		if es, ok := pb.TsDataSource.(ErrSource); ok && es.Err() != nil {
+			return 0, es.Err()
+		}
+	}
+
+	// Last value known, or to be assumed
+	var last time.Time
+	for _, tsData := range pb.primed {
+		if tsData != nil {
+			last = tsData.GetTimeStamp()
+		}
+	}
+	switch {
+	case pb.primedDone:
+	case pb.rangedLast(&last):
+	default:
+		last = pb.EndTime
+	}
+	if last.IsZero() || last.Before(pb.StartTime) {
+		return 0, nil
+	}
+	if last.After(pb.EndTime) {
+		last = pb.EndTime
+	}
+	return pb.estimatePaced(pb.StartTime, last), nil
+}
+
+// rangedLast sets last to the source's TimeRanger last value, returns
+// false if the source can't say
+func (pb *PlayBack) rangedLast(last *time.Time) bool {
+	tr, ok := pb.TsDataSource.(TimeRanger)
+	if !ok {
+		return false
+	}
+	_, trLast, ok := tr.TimeRange()
+	if ok {
+		*last = trLast
+	}
+	return ok
+}
+
+// estimatePaced returns the wall duration the sim time from to to
+// plays over at the base rate and RateTriggers, pacedDur without
+// switching the rate
+func (pb *PlayBack) estimatePaced(from, to time.Time) time.Duration {
+	pb.rateMu.RLock()
+	rate := pb.baseRate
+	pb.rateMu.RUnlock()
+
+	var paced time.Duration
+	for _, trig := range pb.RateTriggers {
+		if trig.At.After(to) {
+			break
+		}
+		if trig.Rate <= 0 {
+			continue
+		}
+		if trig.At.After(from) {
+			paced += time.Duration(float64(pb.simDur(from, trig.At)) / rate)
+			from = trig.At
+		}
+		rate = trig.Rate
+	}
+	return paced + time.Duration(float64(pb.simDur(from, to))/rate)
+}
@@ -0,0 +1,61 @@
+package gopeat
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestEstimateRunTime estimates a uniform source filling the bracket
+// from a 10 value sample and plays it, the estimate is close to the
+// run and the sample is still played
+func TestEstimateRunTime(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 1000; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * time.Millisecond),
+			Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 10, nil)
+
+	est, err := pb.EstimateRunTime(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est != 100*time.Millisecond {
+		t.Errorf("Estimate = %v; expected 100ms", est)
+	}
+
+	res := pb.Run()
+	if res.Records != 1000 {
+		t.Errorf("Records = %d; expected 1000", res.Records)
+	}
+	d := (res.WallRunDur - est).Seconds() * 1000
+	if math.Abs(d) > 10 {
+		t.Errorf("Run off estimate by %f(ms); want less than 10(ms)", d)
+	}
+}
+
+// TestEstimateRunTimeRanged confirms a TimeRanger source's last value
+// ends the estimate, not the EndTime
+func TestEstimateRunTimeRanged(t *testing.T) {
+	simStartTime := time.Now()
+	ss := &SliceTsSource{}
+	for i := 1; i <= 100; i++ {
+		ss.Values = append(ss.Values, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * time.Second), Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Hour), ss, 2, nil)
+
+	est, err := pb.EstimateRunTime(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est != 50*time.Second {
+		t.Errorf("Estimate = %v; expected 50s", est)
+	}
+	if _, err := pb.EstimateRunTime(0); err == nil {
+		t.Error("Got nil error for 0 sample records; expected error")
+	}
+}
@@ -123,18 +123,25 @@ func sendSimTicks(conn *websocket.Conn) {
 		return
 	}
 
+	// Give the last frame time to reach the browser
+	pb.LingerAfterLast = 1 * time.Second
+
+	// Keep a sample of the timings of the long run
+	pb.TimingSampleRate = 1000
+
+	// A failed write ends the playback
+	pb.StopOnError = true
+
 	// Create a playback callback to send playback's
 	// simulation time data output our websocket
 	pb.SendTs = func(ts gopeat.TimeStamper) error {
-		err := conn.WriteJSON(ts.(tsprovider.Trade))
-		if err != nil {
-			fmt.Println(err)
-			pb.Quit()
-		}
-		return err
+		return conn.WriteJSON(ts.(tsprovider.Trade))
 	}
 
 	go commandMonitor(conn, pb)
 	pb.Wait()
+	if err := pb.Err(); err != nil {
+		fmt.Println(err)
+	}
 	fmt.Println("Playback concluded")
 }
@@ -6,31 +6,90 @@ import (
 	"time"
 )
 
-// StaticTradesSource generates random timed trades starting at
-// start time and stopping when the total number of records
-// reaches TotalTrades
+// StaticTradesSource generates timed trades starting at start time
+// and stopping when the total number of records reaches TotalTrades
+// or the next trade would be past the end time. Trades are Interval
+// apart, or a random gap of up to 900ms when Interval is 0.
 type StaticTradesSource struct {
 	Symbol      string
 	TotalTrades int
-	count       int
-	startTime   time.Time
-	endTime     time.Time
-	lastTime    time.Time
+	Interval    time.Duration
+
+	// Rand, when set, generates the random gaps, volumes and prices,
+	// seed it for the same trades every run. A source seeded from the
+	// time is used otherwise
+	Rand *rand.Rand
+
+	// Vol and Amt, when set, give each trade's volume and price from
+	// the source's Rand, see UniformVol and RandomWalk. Trades are 15
+	// at 3011.25 otherwise
+	Vol func(r *rand.Rand) int
+	Amt func(r *rand.Rand) float64
+
+	count     int
+	done      bool
+	startTime time.Time
+	endTime   time.Time
+	lastTime  time.Time
 }
 
-// Next implements an iterator for the contents of the csv data
+// Next implements an iterator for the generated trades
 func (st *StaticTradesSource) Next() (gopeat.TimeStamper, bool) {
 	if st.startTime.IsZero() {
 		panic("staticTradeSource: starttime not set")
 	}
-	if st.count < st.TotalTrades {
-		st.count++
-		st.lastTime = st.lastTime.Add(time.Nanosecond * time.Duration(rand.Intn(899999999)))
-		return Trade{Tim: st.lastTime, Vol: 15, Amt: 3011.25}, true
+	if st.done || st.count >= st.TotalTrades {
+		return nil, false
 	}
 
-	return nil, false
+	tim := st.lastTime.Add(st.gap())
+	if !st.endTime.IsZero() && tim.After(st.endTime) {
+		st.done = true
+		return nil, false
+	}
+	st.count++
+	st.lastTime = tim
+	trd := Trade{Tim: st.lastTime, Vol: 15, Amt: 3011.25}
+	if st.Vol != nil {
+		trd.Vol = st.Vol(st.rand())
+	}
+	if st.Amt != nil {
+		trd.Amt = st.Amt(st.rand())
+	}
+	return trd, true
+}
 
+// gap returns the time from the last trade to the next
+func (st *StaticTradesSource) gap() time.Duration {
+	if st.Interval > 0 {
+		return st.Interval
+	}
+	return time.Nanosecond * time.Duration(st.rand().Intn(899999999))
+}
+
+// rand returns Rand, seeding one from the time if not set
+func (st *StaticTradesSource) rand() *rand.Rand {
+	if st.Rand == nil {
+		st.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return st.Rand
+}
+
+// UniformVol returns a Vol func for volumes from lo to hi
+func UniformVol(lo, hi int) func(r *rand.Rand) int {
+	return func(r *rand.Rand) int {
+		return lo + r.Intn(hi-lo+1)
+	}
+}
+
+// RandomWalk returns an Amt func for prices starting at start that
+// move up or down a tick, or stay, each trade
+func RandomWalk(start, tick float64) func(r *rand.Rand) float64 {
+	price := start
+	return func(r *rand.Rand) float64 {
+		price += float64(r.Intn(3)-1) * tick
+		return price
+	}
 }
 
 // SetStartTime sets min timpstamp for data provided
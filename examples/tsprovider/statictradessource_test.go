@@ -0,0 +1,90 @@
+package tsprovider
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// trades reads the trade times from st
+func trades(st *StaticTradesSource) []time.Time {
+	var tims []time.Time
+	for {
+		ts, ok := st.Next()
+		if !ok {
+			return tims
+		}
+		tims = append(tims, ts.GetTimeStamp())
+	}
+}
+
+// TestStaticTradesSource confirms trades stop at the end time, an
+// Interval spaces them evenly and a seeded Rand repeats its gaps
+func TestStaticTradesSource(t *testing.T) {
+	st := &StaticTradesSource{TotalTrades: 100, Interval: time.Second}
+	st.SetStartTime(esStart)
+	st.SetEndTime(esStart.Add(10 * time.Second))
+	tims := trades(st)
+	if len(tims) != 10 {
+		t.Fatalf("Got %d trades; expected 10 up to the end time", len(tims))
+	}
+	for i, tim := range tims {
+		if expected := esStart.Add(time.Duration(i+1) * time.Second); !tim.Equal(expected) {
+			t.Errorf("Trade %d at %v; expected %v", i, tim, expected)
+		}
+	}
+
+	var runs [2][]time.Time
+	for i := range runs {
+		st := &StaticTradesSource{TotalTrades: 50, Rand: rand.New(rand.NewSource(7))}
+		st.SetStartTime(esStart)
+		runs[i] = trades(st)
+	}
+	if len(runs[0]) != 50 {
+		t.Fatalf("Got %d trades; expected 50", len(runs[0]))
+	}
+	for i := range runs[0] {
+		if !runs[0][i].Equal(runs[1][i]) {
+			t.Fatalf("Trade %d at %v then %v; expected the same", i, runs[0][i], runs[1][i])
+		}
+	}
+}
+
+// TestStaticTradesSourceDists confirms a seeded source with volume and
+// price funcs repeats its trades, volumes in range and prices a tick
+// apart
+func TestStaticTradesSourceDists(t *testing.T) {
+	var runs [2][]Trade
+	for i := range runs {
+		st := &StaticTradesSource{
+			TotalTrades: 100,
+			Rand:        rand.New(rand.NewSource(42)),
+			Vol:         UniformVol(1, 10),
+			Amt:         RandomWalk(3011.25, 0.25),
+		}
+		st.SetStartTime(esStart)
+		for {
+			ts, ok := st.Next()
+			if !ok {
+				break
+			}
+			runs[i] = append(runs[i], ts.(Trade))
+		}
+	}
+	if len(runs[0]) != 100 {
+		t.Fatalf("Got %d trades; expected 100", len(runs[0]))
+	}
+	prev := 3011.25
+	for i, trd := range runs[0] {
+		if trd != runs[1][i] {
+			t.Fatalf("Trade %d = %+v then %+v; expected the same", i, trd, runs[1][i])
+		}
+		if trd.Vol < 1 || trd.Vol > 10 {
+			t.Errorf("Trade %d Vol = %d; expected 1 to 10", i, trd.Vol)
+		}
+		if d := trd.Amt - prev; d != 0 && d != 0.25 && d != -0.25 {
+			t.Errorf("Trade %d Amt = %f from %f; expected a tick at most", i, trd.Amt, prev)
+		}
+		prev = trd.Amt
+	}
+}
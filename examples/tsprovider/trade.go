@@ -35,17 +35,46 @@ func (trd Trade) MarshalJSON() ([]byte, error) {
 
 const tdiTimeLayout string = "01/02/2006 15:04:05.999 MST"
 
+// Register TickData's format, gopeat.CsvTsSource{Format: "tdi"}
+func init() {
+	gopeat.RegisterFormat("tdi", TdiCsvToTrd)
+}
+
 // TdiCsvToTrd converts a csv line slice in
 // TickData's (www.tickdata.com) format to a Trade Value
 // Symbol,Date,Time,Price,Volume
 // ESU13,09/01/2013,17:00:00.083,1640.25,8
 func TdiCsvToTrd(csv []string) (gopeat.TimeStamper, error) {
-	tim, _ := time.Parse(tdiTimeLayout, csv[1]+" "+csv[2]+" "+"UTC")
-	amt, _ := strconv.ParseFloat(csv[3], 64)
-	vol, _ := strconv.Atoi(csv[4])
-	return Trade{
-		Tim: tim,
-		Amt: amt,
-		Vol: vol,
-	}, nil
+	var trd Trade
+	if err := tdiParse(csv, &trd); err != nil {
+		return nil, err
+	}
+	return trd, nil
+}
+
+// tdiParse parses a TickData csv line slice into trd
+func tdiParse(csv []string, trd *Trade) error {
+	if len(csv) < 5 {
+		return fmt.Errorf("tdi: %d fields, expected at least 5", len(csv))
+	}
+	var err error
+	if trd.Tim, err = time.Parse(tdiTimeLayout, csv[1]+" "+csv[2]+" "+"UTC"); err != nil {
+		return err
+	}
+	if trd.Amt, err = strconv.ParseFloat(csv[3], 64); err != nil {
+		return err
+	}
+	trd.Vol, err = strconv.Atoi(csv[4])
+	return err
+}
+
+// NewTrade allocates a Trade for a pooled gopeat.CsvTsSource
+func NewTrade() gopeat.TimeStamper {
+	return &Trade{}
+}
+
+// TdiCsvFillTrd is TdiCsvToTrd for a pooled gopeat.CsvTsSource, it
+// fills the *Trade acquired from the pool
+func TdiCsvFillTrd(csv []string, ts gopeat.TimeStamper) error {
+	return tdiParse(csv, ts.(*Trade))
 }
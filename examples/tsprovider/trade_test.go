@@ -0,0 +1,95 @@
+package tsprovider
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/michelpmcdonald/go-peat"
+)
+
+var esStart = time.Date(2013, 9, 3, 8, 30, 0, 0, time.UTC)
+var esEnd = time.Date(2013, 9, 3, 15, 59, 59, 999, time.UTC)
+
+// benchmarkEsTrades reads the ES dataset through a CsvTsSource,
+// releasing each value once used like a ReleaseAfterSend playback
+func benchmarkEsTrades(b *testing.B, pooled bool) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		csvFile, err := os.Open("ES_Trades.csv")
+		if err != nil {
+			b.Fatal(err)
+		}
+		tsSource := &gopeat.CsvTsSource{CsvStream: csvFile}
+		if pooled {
+			tsSource.NewTs = NewTrade
+			tsSource.CsvTsFill = TdiCsvFillTrd
+		} else {
+			tsSource.CsvTsConv = TdiCsvToTrd
+		}
+		tsSource.SetStartTime(esStart)
+		tsSource.SetEndTime(esEnd)
+
+		for {
+			ts, ok := tsSource.Next()
+			if !ok {
+				break
+			}
+			if pooled {
+				tsSource.ReleaseTimeStamper(ts)
+			}
+		}
+		csvFile.Close()
+	}
+}
+
+func BenchmarkEsTrades(b *testing.B) {
+	benchmarkEsTrades(b, false)
+}
+
+func BenchmarkEsTradesPooled(b *testing.B) {
+	benchmarkEsTrades(b, true)
+}
+
+// TestTdiFormat confirms the tdi format is registered and converts
+// the ES dataset
+func TestTdiFormat(t *testing.T) {
+	csvFile, err := os.Open("ES_Trades.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer csvFile.Close()
+	tsSource := &gopeat.CsvTsSource{CsvStream: csvFile, Format: "tdi"}
+	tsSource.SetStartTime(esStart)
+	tsSource.SetEndTime(esEnd)
+
+	ts, ok := tsSource.Next()
+	if !ok {
+		t.Fatalf("no trade, err %v", tsSource.Err())
+	}
+	trd := ts.(Trade)
+	expected := Trade{Tim: time.Date(2013, 9, 3, 8, 30, 0, 40000000, time.UTC),
+		Vol: 21, Amt: 1646.50}
+	if !trd.Tim.Equal(expected.Tim) || trd.Vol != expected.Vol ||
+		trd.Amt != expected.Amt {
+		t.Errorf("first trade %+v; expected %+v", trd, expected)
+	}
+}
+
+// TestTdiBadLines confirms malformed lines are errors, not panics or
+// zero value trades
+func TestTdiBadLines(t *testing.T) {
+	for _, line := range [][]string{
+		{"ESU13", "09/03/2013"},
+		{"ESU13", "09/03/2013", "8:30", "1646.50", "21"},
+		{"ESU13", "09/03/2013", "08:30:00.040", "", "21"},
+		{"ESU13", "09/03/2013", "08:30:00.040", "1646.50", "x"},
+	} {
+		if _, err := TdiCsvToTrd(line); err == nil {
+			t.Errorf("TdiCsvToTrd(%q) no error; expected one", line)
+		}
+		if err := TdiCsvFillTrd(line, NewTrade()); err == nil {
+			t.Errorf("TdiCsvFillTrd(%q) no error; expected one", line)
+		}
+	}
+}
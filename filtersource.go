@@ -0,0 +1,35 @@
+package gopeat
+
+import "context"
+
+// FilteredSource is a time stamped data source that provides the
+// values of a source a keep func accepts, see FilterSource
+type FilteredSource struct {
+	sourceWrap
+	keep func(TimeStamper) bool
+}
+
+// FilterSource returns a source that provides src's values keep
+// returns true for, for example trades above a volume. The values
+// keep rejects are read through and dropped, a long run of them is
+// read in one Next, which stops early when the run is quit.
+func FilterSource(src TimeStampSource, keep func(TimeStamper) bool) *FilteredSource {
+	return &FilteredSource{sourceWrap: sourceWrap{src: src}, keep: keep}
+}
+
+// Next implements an iterator for the values kept
+func (fs *FilteredSource) Next() (TimeStamper, bool) {
+	return fs.NextContext(context.Background())
+}
+
+// NextContext is Next, returning false once ctx is done, see
+// CancellableSource
+func (fs *FilteredSource) NextContext(ctx context.Context) (TimeStamper, bool) {
+	for ctx.Err() == nil {
+		tsData, ok := fs.next(ctx)
+		if !ok || tsData == nil || fs.keep(tsData) {
+			return tsData, ok
+		}
+	}
+	return nil, false
+}
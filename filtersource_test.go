@@ -0,0 +1,39 @@
+package gopeat
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestFilterSource plays the even indexed records of a source, then
+// a long run of rejects is cut short by a done context
+func TestFilterSource(t *testing.T) {
+	simStartTime := time.Now()
+	ss := pipelineSource(simStartTime, 10, time.Millisecond)
+	fs := FilterSource(ss, func(ts TimeStamper) bool {
+		return ts.(mockTsData).Val%2 == 0
+	})
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), fs, 1, nil)
+
+	var vals []int64
+	pb.SendTs = func(ts TimeStamper) error {
+		vals = append(vals, ts.(mockTsData).Val)
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+	if fmt.Sprint(vals) != "[2 4 6 8 10]" {
+		t.Errorf("Got %v; expected [2 4 6 8 10]", vals)
+	}
+
+	ss = pipelineSource(simStartTime, 100000, time.Millisecond)
+	fs = FilterSource(ss, func(ts TimeStamper) bool { return false })
+	fs.SetStartTime(simStartTime)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, ok := fs.NextContext(ctx); ok || ss.idx == len(ss.Values) {
+		t.Errorf("NextContext ok %t after reading %d; expected stopped early", ok, ss.idx)
+	}
+}
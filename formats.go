@@ -0,0 +1,40 @@
+package gopeat
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Registered csv formats, see RegisterFormat
+var (
+	formatsMu sync.RWMutex
+	formats   = make(map[string]CsvToTs)
+)
+
+// RegisterFormat makes a csv converter available by name, a
+// CsvTsSource with that Format uses it when CsvTsConv isn't set.
+// Packages with converters for well known feeds register them in
+// init, for example importing examples/tsprovider registers TickData's
+// format as "tdi". Panics if conv is nil or name is already registered.
+func RegisterFormat(name string, conv CsvToTs) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	if conv == nil {
+		panic("gopeat: RegisterFormat converter is nil")
+	}
+	if _, dup := formats[name]; dup {
+		panic("gopeat: RegisterFormat called twice for format " + name)
+	}
+	formats[name] = conv
+}
+
+// lookupFormat returns the converter registered for a format
+func lookupFormat(name string) (CsvToTs, error) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	conv, ok := formats[name]
+	if !ok {
+		return nil, fmt.Errorf("csvTsSource: unknown format %q", name)
+	}
+	return conv, nil
+}
@@ -0,0 +1,73 @@
+package gopeat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	RegisterFormat("mock", func(csv []string) (TimeStamper, error) {
+		tim, err := time.Parse(time.RFC3339Nano, csv[0])
+		return mockTsData{Tim: tim}, err
+	})
+}
+
+// TestFormatReplay confirms a registered format is resolved by name
+// and replays
+func TestFormatReplay(t *testing.T) {
+	simStartTime := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	csvData := "tim, amt\n" +
+		"2013-09-01T17:00:00.010Z, 1\n" +
+		"2013-09-01T17:00:00.020Z, 2\n"
+	tsSource := &CsvTsSource{
+		CsvStream: strings.NewReader(csvData),
+		Format:    "mock",
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second),
+		tsSource, 1, nil)
+
+	var sent []time.Time
+	pb.SendTs = func(ts TimeStamper) error {
+		sent = append(sent, ts.GetTimeStamp())
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	if len(sent) != 2 || !sent[1].Equal(simStartTime.Add(20*time.Millisecond)) {
+		t.Errorf("sent %v; expected the 2 csv records", sent)
+	}
+	if pb.Completion() != CompletedOK {
+		t.Errorf("Completion = %d; expected CompletedOK", pb.Completion())
+	}
+}
+
+// TestFormatUnknown confirms an unregistered format stops the source
+// with an error
+func TestFormatUnknown(t *testing.T) {
+	st := &CsvTsSource{
+		CsvStream: strings.NewReader("tim, amt\n"),
+		Format:    "nope",
+	}
+	st.SetStartTime(time.Now())
+	if _, ok := st.Next(); ok {
+		t.Error("Next ok; expected no records")
+	}
+	if st.Err() == nil || !strings.Contains(st.Err().Error(), `"nope"`) {
+		t.Errorf("Err = %v; expected unknown format error", st.Err())
+	}
+}
+
+// TestRegisterFormatTwice confirms a duplicate name panics
+func TestRegisterFormatTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic registering mock twice")
+		}
+	}()
+	RegisterFormat("mock", func(csv []string) (TimeStamper, error) {
+		return nil, nil
+	})
+}
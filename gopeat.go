@@ -6,10 +6,12 @@ package gopeat
 
 import (
 	"container/list"
+	"context"
 	"errors"
 	"fmt"
-	"math"
+	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -24,7 +26,9 @@ type TimeStamper interface {
 // TimeBracket is implemented by any value that has SetStartTime and
 // a SetEndTime methods, which defines the time bracket the value falls
 // in. Playback uses the interface to notify its time series data
-// source to limit data to the given time bracket
+// source to limit data to the given time bracket. TimeBracket is
+// optional, playback skips the values outside the bracket itself for a
+// source that doesn't implement it.
 type TimeBracket interface {
 	SetStartTime(startTime time.Time)
 	SetEndTime(startTime time.Time)
@@ -33,15 +37,124 @@ type TimeBracket interface {
 // TimeStampSource is implemented by any value that has a Next iterator
 // method which returns TimeStamper values.  When ok is false iterator
 // is past the last value and the previous Next call returned the last
-// value.  The way playback is currently designed, a implementor of
-// TimeStampSource should have a complete stream of data available so
-// next can either return the next value or return EOF.  If Next()
-// blocks, the loader goroutine will block and it will never
-// terminate on it's own.  This design will be revisited.(TODO)
+// value.  A implementor of TimeStampSource should have a complete
+// stream of data available so next can either return the next value
+// or return EOF.  If Next() blocks, the loader goroutine blocks with
+// it, which also blocks Wait, even after Quit. A source waiting on
+// live data should implement CancellableSource, Quit then cancels the
+// wait, see ChanTsSource.
+//
+// A source that also implements io.Closer is closed by the loader
+// when it's done reading the source, at the end of the data or on
+// Quit.
 type TimeStampSource interface {
 	Next() (tsData TimeStamper, ok bool)
 }
 
+// CancellableSource is implemented by a TimeStampSource whose Next
+// can block waiting on data. The loader calls NextContext instead of
+// Next, with a context canceled when the run is quit or done, and a
+// NextContext waiting on data returns false once ctx is done. Other
+// reads of the source, AutoTune for one, call Next.
+type CancellableSource interface {
+	NextContext(ctx context.Context) (tsData TimeStamper, ok bool)
+}
+
+// ErrNilTimeStamper is the run error when a TimeStampSource's Next
+// returns a nil value with ok true
+var ErrNilTimeStamper = errors.New("playBack: source returned nil TimeStamper with ok true")
+
+// ErrOutOfBracket is the run error in StrictBracket mode when the
+// source provides a value outside the StartTime to EndTime bracket
+var ErrOutOfBracket = errors.New("playBack: source value outside the time bracket")
+
+// ErrOutOfOrder is the run error with the OutOfOrderError policy when
+// the source provides a value timestamped before the previous one
+var ErrOutOfOrder = errors.New("playBack: source value before the previous value")
+
+// ErrLoaderStall is the run error when the source provides no data
+// within PlayBack.LoaderStallTimeout
+var ErrLoaderStall = errors.New("playBack: source stalled")
+
+// ErrSource is implemented by a TimeStampSource that can report why
+// Next stopped providing values. After Next returns ok false, Err
+// returns nil for a clean end of data or the error that stopped the
+// source. Playback ends the run with CompletedError when Err is not
+// nil, after sending the values provided before the error.
+type ErrSource interface {
+	Err() error
+}
+
+// TimeStamperPool is implemented by a TimeStampSource that reuses
+// TimeStamper values to cut allocations on high volume replays.
+// When PlayBack.ReleaseAfterSend is set, Playback releases each
+// value back to the source once the client is done with it.
+type TimeStamperPool interface {
+	AcquireTimeStamper() TimeStamper
+	ReleaseTimeStamper(TimeStamper)
+}
+
+// TimeRanger is implemented by a TimeStampSource that can report the
+// timestamps of its first and last values without reading through
+// them, for example a seekable or indexed source. ok is false when
+// the range is unknown.
+type TimeRanger interface {
+	TimeRange() (first, last time.Time, ok bool)
+}
+
+// Resettable is implemented by a TimeStampSource that can go back to
+// its first value in the bracket, so a PlayBack can replay it, see
+// PlayBack.Reset
+type Resettable interface {
+	Reset() error
+}
+
+// ErrNotResettable is the PlayBack.Reset error for a source that
+// isn't Resettable
+var ErrNotResettable = errors.New("playBack: source not resettable")
+
+// Completion is how a playback run ended
+type Completion int
+
+// Playback run completions
+const (
+	// NotCompleted run has not started or is still running
+	NotCompleted Completion = iota
+
+	// CompletedOK run sent all the source data
+	CompletedOK
+
+	// CompletedQuit run was stopped by Quit
+	CompletedQuit
+
+	// CompletedError run was stopped by an error, see Err
+	CompletedError
+
+	// CompletedNoData run found the source empty and sent nothing
+	CompletedNoData
+
+	// CompletedCanceled run was stopped by the context of PlayContext
+	CompletedCanceled
+)
+
+// PlayState is where a PlayBack is in its run, see State
+type PlayState int
+
+// PlayBack states
+const (
+	// Stopped PlayBack has not been played
+	Stopped PlayState = iota
+
+	// Playing run is sending data
+	Playing
+
+	// Paused run is held by Pause or StepMode
+	Paused
+
+	// Finished run has ended or is shutting down, see Completion
+	Finished
+)
+
 // OnTsDataReady is the function the Playback client should provide to
 // the playback to receive the time stamped data at simulation time.
 // The client implementation should return as soon as the time sensitive
@@ -50,10 +163,56 @@ type TimeStampSource interface {
 // Playback's send thread, not the clients thread
 type OnTsDataReady func(TimeStamper) error
 
+// OnTsRawReady is the OnTsDataReady variant that also gets the raw
+// source bytes of the value, see PlayBack.SendTsRaw
+type OnTsRawReady func(ts TimeStamper, raw []byte) error
+
+// Class groups time stamped data values that share pacing settings
+type Class int
+
+// Classes provided for Classifier use, any Class value works
+const (
+	// ClassCritical data is paced precisely
+	ClassCritical Class = iota
+
+	// ClassBulk data is typically given a coarse Pacing
+	ClassBulk
+)
+
+// OutOfOrderPolicy is how playback handles a source value timestamped
+// before the previous value, see PlayBack.OutOfOrder
+type OutOfOrderPolicy int
+
+// Out of order value policies
+const (
+	// OutOfOrderClamp sends the value right away, paced at the
+	// previous value's time
+	OutOfOrderClamp OutOfOrderPolicy = iota
+
+	// OutOfOrderDrop skips the value
+	OutOfOrderDrop
+
+	// OutOfOrderError ends the run with CompletedError and an error
+	// wrapping ErrOutOfOrder
+	OutOfOrderError
+)
+
+// Pacing holds the pacing settings for a Class of time stamped data.
+// Granularity 0 paces each value precisely at its own timestamp.
+// A Granularity greater than 0 paces each value at the start of its
+// Granularity window of sim time (measured from the playback
+// StartTime) so the class is released in coarse batches with fewer
+// timing sleeps. A coarse value is never paced before the value
+// sent ahead of it, so it never delays precisely paced values.
+type Pacing struct {
+	Granularity time.Duration
+}
+
 // PlayBack implements a simulation run.  Playback clients need to
-// provide a data source that implement both TimeBracket and
-// TimeStampSource interfaces.  Clients can stop the playback
-// by closing StopChan.
+// provide a data source that implements TimeStampSource, and
+// optionally TimeBracket to limit the data itself.  Clients can stop
+// the playback with Quit, or by canceling the context passed to
+// PlayContext.
 type PlayBack struct {
 	Symbol       string
 	StartTime    time.Time
@@ -62,20 +221,111 @@ type PlayBack struct {
 	TsDataSource TimeStampSource
 	WallRunDur   time.Duration
 
-	// Client specifies rate Ex: 2 = 2x, store it as
-	// a duration for actual time use
-	rateDur time.Duration
-	rateMu  sync.RWMutex
+	// Client specifies rate Ex: 2 = 2x, sim durations
+	// are divided by it for actual time use
+	rate   float64
+	rateMu sync.RWMutex
+
+	// The rate set by New or SetRate, RateTriggers switch rate from
+	// it and a replay or seek back restarts at it. Guarded by rateMu.
+	baseRate float64
+
+	// Sim to wall time marks of the run, see WallTimeFor. Guarded by
+	// rateMu.
+	marks []clockMark
+
+	// Rate changes the timer has yet to pace from, guarded by rateMu.
+	// rateSig wakes a sleeping timer and rateCnt counts the changes.
+	rateChanges []rateChange
+	rateSig     chan struct{}
+	rateCnt     atomic.Int64
+
+	// Rate held for the ControlDebounce window, debouncing is set
+	// while a window is open
+	debounceMu   sync.Mutex
+	debounceRate float64
+	debouncing   bool
 
 	// Source-Sender TimeStamper Data
 	tsDataChan    chan []TimeStamper
 	tsDataChanLen int
 	tsDataBufSize int
 
+	// Values AutoTune read ahead of the run, primedDone is set if it
+	// read the source to the end
+	primed     []TimeStamper
+	primedDone bool
+
+	// bracketing is set when the source doesn't implement TimeBracket
+	// and playback skips the values outside the bracket
+	bracketing bool
+
+	// ReverseWithinBucket's bucket being sent, and the first value of
+	// the next bucket when bucketHeld
+	bucket     []TimeStamper
+	bucketNext TimeStamper
+	bucketHeld bool
+
+	// Sends over SendDeadline and the time they went over it
+	slowSends   atomic.Int64
+	slowOverrun atomic.Int64
+
+	// Source-Sender chan blocking counters
+	timerWaits  atomic.Int64
+	timerReads  atomic.Int64
+	loaderWaits atomic.Int64
+	loaderSends atomic.Int64
+
 	// Sim timed output
 	timedTs chan TimeStamper
 
-	// API Control chans
+	// Output streams added by AddSink and Subscribe, and the chan
+	// returned by Output
+	sinks  []*Sink
+	output atomic.Pointer[chan TimeStamper]
+
+	// Step and Peek requests for the timer, stepped values for the
+	// controller, and timerDone closes when the timer is done
+	stepReqs  chan *stepReq
+	stepTs    chan *stepReq
+	timerDone chan struct{}
+
+	// Context for a CancellableSource read by the loader, canceled by
+	// quit
+	loadCtx    context.Context
+	loadCancel context.CancelFunc
+
+	// SeekTo requests for the loader, seekGen counts the seeks for
+	// the timer, which seekSig wakes. seeked is set by a seek for
+	// the loader to drop its buffer, loaderDone closes when the
+	// loader is done
+	seekReqs   chan *seekReq
+	seekGen    atomic.Int64
+	seekSig    chan struct{}
+	seeked     bool
+	loaderDone chan struct{}
+
+	// Sent values cached for StepBack, and the count stepped back
+	// since the last send. Only used by the controller
+	emitted []emittedTs
+	backCnt int
+
+	// Source and rewritten timestamps of the previous value moved to
+	// RewriteOrigin's timeline
+	originSrc time.Time
+	originOut time.Time
+
+	// API Control chans, the API methods queue commands on cmdChan
+	// for the controller, which signals the other goroutines with
+	// the quit, pause and resume chans. exited closes when the
+	// controller is done taking commands.
+	cmdChan chan command
+	exited  chan struct{}
+
+	// noData closes when the loader finds the source empty, preloaded
+	// when it has loaded PreloadBuffers or is done
+	noData     chan struct{}
+	preloaded  chan struct{}
 	quitChan   chan struct{}
 	pauseChan  chan struct{}
 	resumeChan chan struct{}
@@ -85,19 +335,288 @@ type PlayBack struct {
 	paused       bool
 	replayActive bool
 
-	// Keep track of pause time, set to 0 after using
-	pauseDur time.Duration
-	pauseMu  sync.RWMutex
+	// Paused by StepMode, only Step moves the playback on
+	stepping bool
+
+	// Wall time the current pause started, and the count of pauses
+	// so the timer can tell a pause happened while it slept
+	pauseStart time.Time
+	pauseCnt   atomic.Int64
+
+	// termHeld is set while termWg holds the count Wait waits on,
+	// it is released once by the controller or Quit
+	termHeld bool
+
+	// running is set from Play until the controller exits, a quit
+	// run is no longer active but may still be running
+	running bool
+
+	// ctlMu guards the control flags and the swaps of the pause and
+	// resume chans
+	ctlMu sync.Mutex
+
+	// Consumer freeze state, freezeSig wakes the controller to
+	// pick up a change
+	frozen    atomic.Bool
+	freezeSig chan struct{}
+
+	// Keep track of pause time, the timer drops the pauses once
+	// accounted for
+	pauses  []pauseSpan
+	pauseMu sync.RWMutex
 
 	controllerStarted sync.WaitGroup
 
-	// Holds run time timing info for reporting
-	timingsInfo *list.List
+	// Holds run time timing info for reporting, sampled per
+	// TimingSampleRate, and the totals of every send
+	timingsInfo  *list.List
+	timingTotals timingTotals
+
+	// Sends and wall time of the run so far, see Progress
+	sentCnt       int64
+	sentSimTime   time.Time
+	progressStart time.Time
+	progressEnd   time.Time
+	progressMu    sync.RWMutex
+
+	// Timestamp of the first value loaded from the source
+	firstTsTime time.Time
+	rangeMu     sync.RWMutex
+
+	// How the last run ended and the error that ended it, canceled
+	// when quit by PlayContext's context
+	completion Completion
+	err        error
+	canceled   bool
+	doneMu     sync.RWMutex
+
+	// Gets the RunResult when the run is over, see Done
+	resultChan chan RunResult
 
 	// PlayBack end of life.
 	termWg sync.WaitGroup
 
+	// Goroutines spawned for the run
+	life lifecycle
+
 	WallStartTime time.Time
+
+	// TickEvery, when greater than 0 and OnTick is set, fires OnTick
+	// on each wall clock TickEvery boundary with the most recently
+	// sent time stamped data value (nil if nothing has been sent).
+	// Unlike SendTs, ticks are driven by the wall clock and not by
+	// the data's own cadence. OnTick runs on Playback's send thread.
+	TickEvery time.Duration
+	OnTick    func(wall time.Time, last TimeStamper)
+
+	// ReadyGate, when set, holds off emitting until the client closes
+	// or sends on it, for example once a websocket client is connected
+	// and listening. The wait follows the read ahead warmup and is
+	// interrupted by Quit. Play does not block on the gate.
+	ReadyGate <-chan struct{}
+
+	// Classifier, when set, assigns each time stamped data value a
+	// Class and ClassPacing provides the Pacing for each Class.
+	// Classes missing from ClassPacing are paced precisely. ClassifyBy
+	// builds a Classifier from a Metadataer tag.
+	Classifier  func(TimeStamper) Class
+	ClassPacing map[Class]Pacing
+
+	// RewriteTs, when set, maps each time stamped data value to the
+	// value sent to the client, typically to give it a new timestamp.
+	// Pacing uses the source timestamps. Rewritten timestamps must not
+	// go backwards, a rewritten timestamp before the previous one ends
+	// the run with CompletedError.
+	RewriteTs func(TimeStamper) TimeStamper
+
+	// DateShift, when set, moves each sent value to DateShift's date
+	// keeping its time of day, for example to replay last Tuesday as
+	// if it were today. Time of day is kept across DST changes. Runs
+	// before RewriteTs. Use a rate of 1 to keep the original pace.
+	DateShift time.Time
+
+	// TimestampResolution, when above 0, truncates each sent value's
+	// timestamp to a multiple of TimestampResolution, for example
+	// time.Millisecond for a store without finer resolution. Values
+	// truncated to the same instant are paced and sent together. Runs
+	// before DateShift and RewriteTs.
+	TimestampResolution time.Duration
+
+	// RewriteOrigin, when set, moves the sent timestamps to a timeline
+	// starting at RewriteOrigin. A value is sent timestamped
+	// RewriteOrigin + (timestamp - StartTime) / rate, a rate change
+	// rescales the time from the previous value on, so a RewriteOrigin
+	// of the wall time at Play gives about the wall send times. Runs
+	// after TimestampResolution and before DateShift and RewriteTs.
+	RewriteOrigin time.Time
+
+	// Calendar, when set, has playback skip the time the Calendar is
+	// closed, a weekend for example, instead of waiting through it.
+	// WeekdayCalendar is a simple weekend skipping Calendar.
+	Calendar Calendar
+
+	// ExternalClock, when set, slaves playback to another system's
+	// clock. Each time received advances the sim time frontier and
+	// the values up to it are sent right away, playback doesn't pace
+	// itself and rates don't apply. Closing ExternalClock ends the run,
+	// values past the frontier aren't sent.
+	ExternalClock <-chan time.Time
+
+	// Clock, when set, is the wall clock sends are paced by, see
+	// Clock. Nil paces by the system clock.
+	Clock Clock
+
+	// NoPacing, when set, sends every value as fast as the client
+	// takes them, in order and without sleeping, for batch
+	// reprocessing. Rates don't apply, drift is the wall time between
+	// sends. Pause, Step and Quit work as usual.
+	NoPacing bool
+
+	// MaxInterRecordWait, when above 0, caps the wall time between
+	// sends. A longer gap between values, after the rate is applied,
+	// is collapsed to MaxInterRecordWait.
+	MaxInterRecordWait time.Duration
+
+	// RateTriggers, sorted by At, switch the rate as playback
+	// reaches each At. A gap between values that spans a trigger
+	// plays at the old rate up to At and the new rate after. Triggers
+	// with a Rate of 0 or less are ignored.
+	RateTriggers []RateTrigger
+
+	// SendTsRaw, when set, is called instead of SendTs with each value
+	// and the raw source bytes it was parsed from. Sources attach the
+	// bytes in their RawCapture mode, raw is nil otherwise.
+	SendTsRaw OnTsRawReady
+
+	// ReleaseAfterSend releases sent values back to a TimeStamperPool
+	// source for reuse. Setting it is the client's promise not to
+	// retain a value passed to SendTs after the callback returns,
+	// or the value passed to OnTick after the next send. The value
+	// is reused and overwritten by the source.
+	ReleaseAfterSend bool
+
+	// LingerAfterLast holds a run open for the duration after the last
+	// data value is sent before completing and unblocking Wait, giving
+	// the client time to flush. Quit ends the linger early.
+	LingerAfterLast time.Duration
+
+	// OnGap, when set, is called after each send but the first with
+	// the sim time gap between the sent value and the previously sent
+	// value, at is the sent value's timestamp. Gaps use the source
+	// timestamps and are not adjusted for rate. OnGap runs on
+	// Playback's send thread.
+	OnGap func(gap time.Duration, at time.Time)
+
+	// SendDeadline, when above 0, is the wall time budget of a send.
+	// A SendTs callback taking longer is counted as a slow send, see
+	// DriftStats, and OnSlowSend, when set, is called with the
+	// send's record number, 1 for the first, and the time it took.
+	// The deadline only measures, the callback runs on Playback's
+	// send thread and can't be aborted, the sends behind it wait on
+	// it. OnSlowSend runs on Playback's send thread.
+	SendDeadline time.Duration
+	OnSlowSend   func(rec int64, took time.Duration)
+
+	// TimingSampleRate, when above 1, keeps the RunTiming of every
+	// TimingSampleRate-th send only, so a long run's Timings don't
+	// take memory per record. DriftStats and the RunResult record
+	// count, sim span and achieved rate still cover every send, the
+	// drift percentiles and ExportTrace use the sample.
+	TimingSampleRate int
+
+	// Metrics, when set, gets the run's drift, records sent, read
+	// ahead buffer fill and pause time as they change, see
+	// MetricsSink for the metric names.
+	Metrics MetricsSink
+
+	// StopOnError ends the run with CompletedError when SendTs, or
+	// SendTsRaw, returns an error, Err wraps the error. The value that
+	// failed isn't passed on to sinks. Otherwise callback errors are
+	// ignored.
+	StopOnError bool
+
+	// OnMilestone, when set with a MilestoneFraction between 0 and 1,
+	// is called as the sent timestamps cross each MilestoneFraction of
+	// the StartTime to EndTime span. For example, 0.1 calls it at 10%,
+	// 20% and so on, fraction is the milestone reached and simTime its
+	// sim time. OnMilestone runs on Playback's send thread, after
+	// SendTs.
+	MilestoneFraction float64
+	OnMilestone       func(fraction float64, simTime time.Time)
+
+	// ControlCheckEvery, when above 0, applies API commands queued by
+	// Quit, Pause and the like ahead of the next send every
+	// ControlCheckEvery sends. Otherwise a queued command competes
+	// with the sends and, with zero gap values and slow callbacks, a
+	// Quit can wait out several more callbacks. 1 honors commands
+	// before the next send.
+	ControlCheckEvery int
+
+	// StrictBracket ends the run with CompletedError and an error
+	// wrapping ErrOutOfBracket when the source provides a value
+	// timestamped before StartTime or after EndTime, to catch buggy
+	// sources. The values before it are sent.
+	StrictBracket bool
+
+	// OutOfOrder is the policy for a source value timestamped before
+	// the previous value, like a slightly late print in a real feed.
+	// The values are clamped by default, see OutOfOrderPolicy. Values
+	// paced together, by ClassPacing or ReverseWithinBucket, are in
+	// order as long as their pacing times are.
+	OutOfOrder OutOfOrderPolicy
+
+	// LoaderStallTimeout, when above 0, ends the run with
+	// CompletedError and ErrLoaderStall when the timer has run out of
+	// loaded data and the source provides none within
+	// LoaderStallTimeout, for a source hung in Next. Wait then returns
+	// without waiting on Next, the loader goroutine is left blocked in
+	// Next and Play starts no new run until Next returns.
+	LoaderStallTimeout time.Duration
+
+	// ReverseWithinBucket, when above 0, sends the values within each
+	// ReverseWithinBucket long bucket of sim time (measured from the
+	// StartTime) latest first, buckets stay in order. Each bucket is
+	// paced at its start and sent together. The reversed timestamps go
+	// backwards within a bucket, so rewrites that guard against that
+	// don't combine with it.
+	ReverseWithinBucket time.Duration
+
+	// PreviewSteps has Step send previews, wrapped in a PreviewTs,
+	// that don't count toward the run's records or timings
+	PreviewSteps bool
+
+	// DriftController, when set, replaces the default correction for
+	// a slow client, see PIDDrift
+	DriftController DriftController
+
+	// MaxDriftCorrection bounds the correction for a slow client to
+	// +/- MaxDriftCorrection of the wait before a send, so a client
+	// that is slow for a while makes up at most that much by sending
+	// early and pacing recovers once it's back to normal. 0 uses
+	// DefaultMaxDriftCorrection, below 0 leaves it unbounded.
+	MaxDriftCorrection time.Duration
+
+	// StepBackDepth is the number of sent values cached for StepBack,
+	// 0 disables it. Nothing is cached with ReleaseAfterSend, the
+	// released values are reused by the source.
+	StepBackDepth int
+
+	// ControlDebounce, when above 0, coalesces the rate changes made
+	// while running within ControlDebounce of the first of a burst,
+	// only the last rate is applied once the window closes. Useful
+	// behind a UI slider. The rate is queued when the window closes,
+	// not when set, so a command made within the window, a Pause
+	// after SetRate for example, is applied ahead of the rate.
+	ControlDebounce time.Duration
+
+	// PreloadBuffers is the number of read ahead buffers loaded
+	// before sending starts, 1 when 0 and at most the number of
+	// buffers the data chan holds, see SetBufferSizes. A source that
+	// ends first starts sending right away. PreloadTimeout bounds the
+	// wait for a slow source, 1 second when 0, a negative timeout
+	// waits on the buffers however long they take.
+	PreloadBuffers int
+	PreloadTimeout time.Duration
 }
 
 // New allocates a new Playback struct
@@ -106,11 +625,35 @@ func New(symbol string,
 	tsSource TimeStampSource,
 	pbRate uint16,
 	cb OnTsDataReady) (*PlayBack, error) {
+	return NewWithRate(symbol, startTime, endTime, tsSource, float64(pbRate), cb)
+}
+
+// NewWithRate allocates a new Playback struct with a fractional sim
+// rate, 0.5 plays at half speed and 1.5 one and a half times real
+// time. The rate must be greater than 0.
+func NewWithRate(symbol string,
+	startTime time.Time, endTime time.Time,
+	tsSource TimeStampSource,
+	pbRate float64,
+	cb OnTsDataReady) (*PlayBack, error) {
+
+	// Validate every input so callers get all the problems at once
+	var errs []error
 
 	// Time stamped data source is required
 	if tsSource == nil {
-		return nil, errors.New("playBack: tsSource required")
+		errs = append(errs, errors.New("playBack: tsSource required"))
 	}
+	if endTime.Before(startTime) {
+		errs = append(errs, errors.New("playBack: endTime before startTime"))
+	}
+	if !validRate(pbRate) {
+		errs = append(errs, errRateTooLow)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
 	pb := &PlayBack{
 		Symbol:       symbol,
 		StartTime:    startTime,
@@ -133,14 +676,22 @@ func New(symbol string,
 	// buffered chan
 	pb.tsDataChanLen = 5
 
-	// Notify timestamper data source of playback start-end times
-	pb.TsDataSource.(TimeBracket).SetStartTime(startTime)
-	pb.TsDataSource.(TimeBracket).SetEndTime(endTime)
+	// Notify timestamper data source of playback start-end times,
+	// or bracket the source's data here
+	if tb, ok := pb.TsDataSource.(TimeBracket); ok {
+		tb.SetStartTime(startTime)
+		tb.SetEndTime(endTime)
+	} else {
+		pb.bracketing = true
+	}
 
 	// Set the simulation rate duration
-	pb.SetRate(pbRate)
+	pb.SetSimRate(pbRate)
+
+	pb.freezeSig = make(chan struct{}, 1)
 
 	pb.termWg.Add(1)
+	pb.termHeld = true
 
 	return pb, nil
 }
@@ -153,79 +704,418 @@ func (pb *PlayBack) init() {
 	pb.pauseChan = make(chan struct{})
 	pb.resumeChan = make(chan struct{})
 	pb.quitChan = make(chan struct{})
-
-	pb.paused = false
-	pb.replayActive = false
+	pb.cmdChan = make(chan command, cmdChanLen)
+	pb.exited = make(chan struct{})
+	pb.noData = make(chan struct{})
+	pb.preloaded = make(chan struct{})
+	pb.stepReqs = make(chan *stepReq)
+	pb.stepTs = make(chan *stepReq)
+	pb.timerDone = make(chan struct{})
+	pb.loadCtx, pb.loadCancel = context.WithCancel(context.Background())
+	pb.seekReqs = make(chan *seekReq)
+	pb.seekGen.Store(0)
+	pb.seekSig = make(chan struct{}, 1)
+	pb.seeked = false
+	pb.loaderDone = make(chan struct{})
+	pb.emitted, pb.backCnt = nil, 0
+
+	pb.paused, pb.stepping = false, false
 	pb.timingsInfo = nil
+	pb.timingTotals = timingTotals{}
+	pb.originSrc, pb.originOut = time.Time{}, time.Time{}
+	pb.bucket, pb.bucketNext, pb.bucketHeld = nil, nil, false
+
+	pb.rateMu.Lock()
+	pb.marks = nil
+	pb.rateChanges = nil
+	pb.rate = pb.baseRate
+	pb.rateMu.Unlock()
+	pb.rateSig = make(chan struct{}, 1)
+
+	pb.pauseMu.Lock()
+	pb.pauses = nil
+	pb.pauseMu.Unlock()
+
+	pb.timerWaits.Store(0)
+	pb.timerReads.Store(0)
+	pb.loaderWaits.Store(0)
+	pb.loaderSends.Store(0)
+	pb.slowSends.Store(0)
+	pb.slowOverrun.Store(0)
+
+	pb.rangeMu.Lock()
+	pb.firstTsTime = time.Time{}
+	pb.rangeMu.Unlock()
+
+	pb.progressMu.Lock()
+	pb.sentCnt, pb.sentSimTime = 0, time.Time{}
+	pb.progressStart, pb.progressEnd = time.Time{}, time.Time{}
+	pb.progressMu.Unlock()
+
+	pb.doneMu.Lock()
+	pb.completion = NotCompleted
+	pb.err = nil
+	pb.canceled = false
+	pb.doneMu.Unlock()
+	pb.resultChan = make(chan RunResult, 1)
 }
 
-// SetRate controls the realtime rate of the playback.
+var errRateTooLow = errors.New("playBack: rate must be greater than 0")
+
+// SetRate controls the realtime rate of the playback. A running
+// playback changes rate from the current point, the value being waited
+// on is repaced for the rest of its gap at the new rate. A rate of 0
+// is rejected with an error, use Pause to stop the sim clock.
 func (pb *PlayBack) SetRate(rate uint16) error {
-	if rate < 1 {
-		return errors.New("playBack: rate must be equal to or greath than 1")
+	return pb.SetSimRate(float64(rate))
+}
+
+// SetSimRate is SetRate for fractional rates, a rate below 1 plays
+// slower than real time. A rate of 0 or less is rejected with an
+// error.
+func (pb *PlayBack) SetSimRate(rate float64) error {
+	if !validRate(rate) {
+		return errRateTooLow
 	}
 
-	// Set the simulation rate duration
-	pb.rateMu.Lock()
-	pb.rateDur = time.Duration(rate)
-	pb.rateMu.Unlock()
+	if pb.ControlDebounce > 0 && pb.isRunning() {
+		pb.debounce(rate)
+		return nil
+	}
+
+	// A running playback changes rate in order with the other
+	// API commands
+	if !pb.enqueue(command{kind: cmdSetRate, rate: rate}) {
+		pb.setRate(rate)
+	}
+	return nil
+}
 
+// isRunning reports if a run is underway
+func (pb *PlayBack) isRunning() bool {
+	pb.ctlMu.Lock()
+	defer pb.ctlMu.Unlock()
+	return pb.running
+}
+
+// SetBufferSizes sets the read ahead of the next run, the number of
+// values loaded into a buffer before it's handed to the timer and the
+// number of buffers the data chan holds. Both must be at least 1.
+// Sizes can't change mid run, an error is returned while a run is
+// running.
+func (pb *PlayBack) SetBufferSizes(bufSize, chanLen int) error {
+	if bufSize < 1 || chanLen < 1 {
+		return errors.New("playBack: buffer sizes must be at least 1")
+	}
+
+	pb.ctlMu.Lock()
+	defer pb.ctlMu.Unlock()
+	if pb.running {
+		return errors.New("playBack: buffer sizes can't change while running")
+	}
+	pb.tsDataBufSize = bufSize
+	pb.tsDataChanLen = chanLen
+	return nil
+}
+
+// preloadBuffers returns the number of buffers to load before sending
+// starts, see PreloadBuffers
+func (pb *PlayBack) preloadBuffers() int {
+	return min(max(pb.PreloadBuffers, 1), pb.tsDataChanLen)
+}
+
+// preloadTimeout returns the longest wait on the preload, 0 to wait
+// however long
+func (pb *PlayBack) preloadTimeout() time.Duration {
+	switch {
+	case pb.PreloadTimeout == 0:
+		return time.Second
+	case pb.PreloadTimeout < 0:
+		return 0
+	}
+	return pb.PreloadTimeout
+}
+
+// Reset readies the source to replay from the start, so the next Play
+// sends the same data again. A source that isn't Resettable can't go
+// back, ErrNotResettable is returned and the next Play carries on
+// from where the source is, which for a source read to the end sends
+// nothing. Values read ahead by AutoTune or EstimateRunTime are
+// dropped. An error is returned while a run is running.
+func (pb *PlayBack) Reset() error {
+	if !pb.IsIdle() {
+		return errors.New("playBack: can't reset while running")
+	}
+	rs, ok := pb.TsDataSource.(Resettable)
+	if !ok {
+		return ErrNotResettable
+	}
+	if err := rs.Reset(); err != nil {
+		return err
+	}
+	pb.primed, pb.primedDone = nil, false
 	return nil
 }
 
 // Play starts replay process
 func (pb *PlayBack) Play() {
-	if !pb.replayActive {
-		// Start up the controller, controller
-		// starts and controls the replay
-		pb.controllerStarted.Add(1)
-		go pb.controller()
-		pb.controllerStarted.Wait()
+	if pb.start() {
+		pb.launch()
 	}
 }
 
-// Pause suspends the running replay
-func (pb *PlayBack) Pause() {
-	if !pb.paused && pb.replayActive {
-		// Open up the resume chan to allow ending the
-		// pause which is being initiated here
-		pb.resumeChan = make(chan struct{})
+// PlayContext starts replay like Play, and quits the run as Quit does
+// when ctx is done first, the run then completes CompletedCanceled.
+// Quit still stops the run, whichever comes first ends it and Wait
+// unblocks either way. Only this run is tied to ctx, a later Play
+// isn't quit by it.
+func (pb *PlayBack) PlayContext(ctx context.Context) {
+	if !pb.start() {
+		return
+	}
+
+	cmdChan, exited := pb.cmdChan, pb.exited
+	go func() {
+		select {
+		case <-ctx.Done():
+			select {
+			case cmdChan <- command{kind: cmdQuit, canceled: true}:
+			case <-exited:
+			}
+		case <-exited:
+		}
+	}()
+	pb.launch()
+}
+
+// launch starts the controller for a run readied by start
+func (pb *PlayBack) launch() {
+	// Start up the controller, controller
+	// starts and controls the replay
+	pb.controllerStarted.Add(1)
+	go pb.controller()
+	pb.controllerStarted.Wait()
+}
+
+// start readies a new run and marks it active, returns false if a
+// run is already running
+func (pb *PlayBack) start() bool {
+	pb.ctlMu.Lock()
+	defer pb.ctlMu.Unlock()
+	if pb.running || !pb.life.idle() {
+		return false
+	}
+
+	// Start with a clean slate
+	pb.init()
+	pb.replayActive = true
+	pb.running = true
 
-		// Send pause signal
-		close(pb.pauseChan)
-		pb.paused = true
+	// Hold Wait for this run when a previous run released it
+	if !pb.termHeld {
+		pb.termWg.Add(1)
+		pb.termHeld = true
 	}
+	return true
 }
 
-// Resume continues a paused playback
+// HeadTail is a quick inspection replay used instead of Play. It
+// sends the first n and the last n values from the source to SendTs
+// as fast as possible and skips everything in between. The source is
+// read to the end keeping the last n values in a ring, so sources
+// that can't seek work too. A source with fewer than 2n values has
+// each value sent once. Blocks until done, a SendTs or source error
+// stops it and is returned.
+func (pb *PlayBack) HeadTail(n int) error {
+	if n < 1 {
+		return errors.New("playBack: head tail count must be at least 1")
+	}
+
+	ring := make([]TimeStamper, n)
+	cnt := 0
+	for {
+		tsData, ok := pb.sourceNext(context.Background())
+		if !ok {
+			break
+		}
+		if cnt < n {
+			if err := pb.send(unwrapRaw(tsData)); err != nil {
+				return err
+			}
+		} else {
+			ring[(cnt-n)%n] = tsData
+		}
+		cnt++
+	}
+	if es, ok := pb.TsDataSource.(ErrSource); ok {
+		if err := es.Err(); err != nil {
+			return err
+		}
+	}
+
+	// Send the tail, oldest first
+	ringCnt := cnt - n
+	tailLen := ringCnt
+	if tailLen > n {
+		tailLen = n
+	}
+	for i := 0; i < tailLen; i++ {
+		if err := pb.send(unwrapRaw(ring[(ringCnt-tailLen+i)%n])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pause suspends the running replay
+func (pb *PlayBack) Pause() {
+	pb.enqueue(command{kind: cmdPause})
+}
+
+// Resume continues a paused playback, ending StepMode
 func (pb *PlayBack) Resume() {
-	if pb.paused {
-		// Open up the pause chan to allow pausing the
-		// playback which is being restarted here
-		pb.pauseChan = make(chan struct{})
+	pb.enqueue(command{kind: cmdResume})
+}
+
+// FreezeConsumer stops sending data to the client without pausing
+// the playback clock. Unlike Pause, frozen time is not credited back,
+// the held send goes out late on UnfreezeConsumer and the drift
+// correction then catches the playback up to its schedule. Useful
+// for studying catch up behavior, see Timings.
+func (pb *PlayBack) FreezeConsumer() {
+	pb.frozen.Store(true)
+	pb.signalFreeze()
+}
 
-		// Send resume signal
-		close(pb.resumeChan)
-		pb.paused = false
+// UnfreezeConsumer resumes sending data to the client
+func (pb *PlayBack) UnfreezeConsumer() {
+	pb.frozen.Store(false)
+	pb.signalFreeze()
+}
+
+// signalFreeze wakes the controller, a pending signal covers it
+func (pb *PlayBack) signalFreeze() {
+	select {
+	case pb.freezeSig <- struct{}{}:
+	default:
 	}
 }
 
 // Quit stops the running PlayBack and eventually unblocks callers
 // blocked on Wait()
 func (pb *PlayBack) Quit() {
-	if pb.replayActive {
-		close(pb.quitChan)
-		pb.replayActive = false
-	} else {
+	if !pb.enqueue(command{kind: cmdQuit}) {
+		// Not running, nothing to wait on
+		pb.ctlMu.Lock()
+		pb.releaseTerm()
+		pb.ctlMu.Unlock()
+	}
+}
+
+// releaseTerm unblocks Wait, only the first call after New counts.
+// Caller holds ctlMu
+func (pb *PlayBack) releaseTerm() {
+	if pb.termHeld {
+		pb.termHeld = false
 		pb.termWg.Done()
 	}
 }
 
+// pauseSignals returns the current pause and resume chans. Read the
+// resume chan after the pause chan closes, Pause swaps it in first
+func (pb *PlayBack) pauseSignals() (pause, resume <-chan struct{}) {
+	pb.ctlMu.Lock()
+	defer pb.ctlMu.Unlock()
+	return pb.pauseChan, pb.resumeChan
+}
+
+// IsIdle reports if the PlayBack is safe to reconfigure and Play
+// again. A PlayBack is idle before its first Play and once Wait has
+// returned for the last run, no goroutine from the run is left and
+// the next Play starts with fresh chans.
+func (pb *PlayBack) IsIdle() bool {
+	pb.ctlMu.Lock()
+	defer pb.ctlMu.Unlock()
+	return !pb.running && !pb.replayActive && pb.life.idle()
+}
+
+// Completion returns how the last playback run ended
+func (pb *PlayBack) Completion() Completion {
+	pb.doneMu.RLock()
+	defer pb.doneMu.RUnlock()
+	return pb.completion
+}
+
+// State returns the PlayBack's state. Pause, Resume and Quit are
+// applied by the controller, State reflects a command once it has
+// been applied, not when it's queued.
+func (pb *PlayBack) State() PlayState {
+	pb.ctlMu.Lock()
+	defer pb.ctlMu.Unlock()
+	switch {
+	case pb.running && pb.replayActive && pb.paused:
+		return Paused
+	case pb.running && pb.replayActive:
+		return Playing
+	case pb.resultChan == nil:
+		return Stopped
+	}
+	return Finished
+}
+
+// Err returns the error that ended the last playback run, nil when
+// the run ended cleanly or by Quit
+func (pb *PlayBack) Err() error {
+	pb.doneMu.RLock()
+	defer pb.doneMu.RUnlock()
+	return pb.err
+}
+
+// setErr records the error ending the run, first error wins
+func (pb *PlayBack) setErr(err error) {
+	pb.doneMu.Lock()
+	if pb.err == nil {
+		pb.err = err
+	}
+	pb.doneMu.Unlock()
+}
+
+// setCompletion records how the run ended, a run ended by an
+// error is always CompletedError
+func (pb *PlayBack) setCompletion(c Completion) {
+	pb.doneMu.Lock()
+	if c == CompletedQuit && pb.canceled {
+		c = CompletedCanceled
+	}
+	if pb.err != nil {
+		c = CompletedError
+	}
+	pb.completion = c
+	pb.doneMu.Unlock()
+}
+
+// Done returns a chan that gets the RunResult of the current or last
+// run once it's over, then closes, so only one receive gets the
+// result. The chan is nil before the first Play, each Play has its
+// own. Unlike Wait, Done tells a run that sent all its data from one
+// that was quit, canceled or stopped by an error.
+func (pb *PlayBack) Done() <-chan RunResult {
+	pb.ctlMu.Lock()
+	defer pb.ctlMu.Unlock()
+	return pb.resultChan
+}
+
 // Wait blocks until the controller shuts down
-// or  client calls Quit
+// or  client calls Quit. Once Wait returns no goroutine from the
+// run is left running, so a source blocked in Next holds up Wait
+// until Next returns. Client callbacks run on the controller, a
+// SendTs or OnTick in progress, including one for the final value or
+// one interrupted by Quit, has returned before Wait does.
 func (pb *PlayBack) Wait() {
 	pb.termWg.Wait()
+	pb.ctlMu.Lock()
 	pb.replayActive = false
+	pb.ctlMu.Unlock()
 }
 
 // loadTimeStampedData reads data from the source into a slice and
@@ -237,13 +1127,68 @@ func (pb *PlayBack) Wait() {
 // into a slice and writes the slice to the chan.
 func (pb *PlayBack) loadTimeStampedData() {
 
+	defer close(pb.loaderDone)
 	defer close(pb.tsDataChan)
+	if closer, ok := pb.TsDataSource.(io.Closer); ok {
+		defer closer.Close()
+	}
+	defer pb.loadCancel()
+
+	// Done loading counts as preloaded
+	preload, sent := pb.preloadBuffers(), 0
+	defer func() {
+		if sent < preload {
+			close(pb.preloaded)
+		}
+	}()
 
 	tsDataBuf := make([]TimeStamper, 0, pb.tsDataBufSize)
+	loadedAny := false
 
 	for {
-		tsData, more := pb.TsDataSource.Next()
+		// A seek drops the data loaded ahead of it
+		select {
+		case req := <-pb.seekReqs:
+			if !pb.seek(req) {
+				return
+			}
+		default:
+		}
+		if pb.seeked {
+			pb.seeked = false
+			tsDataBuf = make([]TimeStamper, 0, pb.tsDataBufSize)
+		}
+
+		tsData, more := pb.nextLoaded()
 		if !more {
+			// Find out if the source stopped on an error
+			if es, ok := pb.TsDataSource.(ErrSource); ok {
+				if err := es.Err(); err != nil {
+					pb.setErr(err)
+					break
+				}
+			}
+			if !loadedAny {
+				close(pb.noData)
+				break
+			}
+
+			// Send the rest, a seek while waiting on the timer
+			// loads on from there
+			if len(tsDataBuf) > 0 && !pb.sendTsDataBuf(tsDataBuf) {
+				return
+			}
+			tsDataBuf = nil
+			if pb.seeked {
+				continue
+			}
+			break
+		}
+
+		// A buggy source, end the run on an error instead of
+		// crashing on the value later
+		if ts, _ := unwrapRaw(tsData); ts == nil {
+			pb.setErr(ErrNilTimeStamper)
 			break
 		}
 
@@ -254,6 +1199,14 @@ func (pb *PlayBack) loadTimeStampedData() {
 		default:
 		}
 
+		// Remember where the data starts for DetectedRange
+		if !loadedAny {
+			loadedAny = true
+			pb.rangeMu.Lock()
+			pb.firstTsTime = tsData.GetTimeStamp()
+			pb.rangeMu.Unlock()
+		}
+
 		tsDataBuf = append(tsDataBuf, tsData)
 
 		// If the buffer slice is full, write it to the chan
@@ -261,7 +1214,12 @@ func (pb *PlayBack) loadTimeStampedData() {
 
 			// sendBuf now refers to the buffers slice data
 			sendBuf := tsDataBuf
-			pb.tsDataChan <- sendBuf
+			if !pb.sendTsDataBuf(sendBuf) {
+				return
+			}
+			if sent++; sent == preload {
+				close(pb.preloaded)
+			}
 
 			// buffer is reallocated to a new slice
 			tsDataBuf = make([]TimeStamper, 0, pb.tsDataBufSize)
@@ -269,7 +1227,109 @@ func (pb *PlayBack) loadTimeStampedData() {
 	}
 	// source is empty, send any remaining data in the buffer
 	if len(tsDataBuf) > 0 {
-		pb.tsDataChan <- tsDataBuf
+		pb.sendTsDataBuf(tsDataBuf)
+	}
+}
+
+// DetectedRange returns the timestamp of the first value loaded from
+// the source, known once the read ahead warmup has run, so a UI can lay
+// out a timeline right after Play. last is known when the source
+// implements TimeRanger, otherwise it's the zero time. ok is false
+// until the first value is known.
+func (pb *PlayBack) DetectedRange() (first, last time.Time, ok bool) {
+	pb.rangeMu.RLock()
+	first = pb.firstTsTime
+	pb.rangeMu.RUnlock()
+
+	if tr, isTr := pb.TsDataSource.(TimeRanger); isTr {
+		trFirst, trLast, trOk := tr.TimeRange()
+		if trOk {
+			last = trLast
+			if first.IsZero() {
+				first = trFirst
+			}
+		}
+	}
+	return first, last, !first.IsZero()
+}
+
+// sendTsDataBuf writes a loaded buffer to the data chan, counting
+// the sends that had to wait on a full chan. A seek while waiting
+// drops the buffer instead. Returns false if quit while waiting, the
+// timer may be gone and never drain the chan
+func (pb *PlayBack) sendTsDataBuf(tsDataBuf []TimeStamper) bool {
+	pb.loaderSends.Add(1)
+	select {
+	case pb.tsDataChan <- tsDataBuf:
+		return true
+	default:
+	}
+
+	pb.loaderWaits.Add(1)
+	select {
+	case pb.tsDataChan <- tsDataBuf:
+		return true
+	case req := <-pb.seekReqs:
+		return pb.seek(req)
+	case <-pb.quitChan:
+		return false
+	}
+}
+
+// recvTsDataBuf reads the next loaded buffer from the data chan,
+// counting the reads that had to wait on an empty chan. ok is false
+// when the loader is done
+func (pb *PlayBack) recvTsDataBuf() (tsDataBuf []TimeStamper, ok bool) {
+	select {
+	case tsDataBuf, ok = <-pb.tsDataChan:
+	default:
+		pb.timerWaits.Add(1)
+
+		// A nil stall chan waits on the loader for good
+		var stall <-chan time.Time
+		if pb.LoaderStallTimeout > 0 {
+			stallTimer := time.NewTimer(pb.LoaderStallTimeout)
+			defer stallTimer.Stop()
+			stall = stallTimer.C
+		}
+		select {
+		case tsDataBuf, ok = <-pb.tsDataChan:
+		case <-pb.quitChan:
+		case <-stall:
+			// Leave the loader stuck in the source's Next behind
+			pb.life.abandon(stageLoader)
+			pb.setErr(ErrLoaderStall)
+			pb.quit()
+		}
+	}
+	if ok {
+		pb.timerReads.Add(1)
+	}
+	return tsDataBuf, ok
+}
+
+// ContentionStats counts blocking on the chan that hands loaded data
+// buffers from the loader to the timer. Frequent timer waits mean the
+// source can't keep up, frequent loader waits mean the read ahead is
+// full and the source is well ahead of the timer.
+type ContentionStats struct {
+	// Timer reads of a buffer and reads that found the chan empty
+	TimerReads int64
+	TimerWaits int64
+
+	// Loader sends of a buffer and sends that found the chan full
+	LoaderSends int64
+	LoaderWaits int64
+}
+
+// ContentionStats returns the data chan blocking counts for the
+// current or last run, safe to call while running
+func (pb *PlayBack) ContentionStats() ContentionStats {
+	return ContentionStats{
+		TimerReads:  pb.timerReads.Load(),
+		TimerWaits:  pb.timerWaits.Load(),
+		LoaderSends: pb.loaderSends.Load(),
+		LoaderWaits: pb.loaderWaits.Load(),
 	}
 }
 
@@ -277,47 +1337,274 @@ func (pb *PlayBack) loadTimeStampedData() {
 // commands. Blocks, but never sleeps. Terminates when there is no
 // more data or an API command stops it
 func (pb *PlayBack) controller() {
-	defer pb.termWg.Done()
-	defer func() { pb.WallRunDur = time.Since(pb.WallStartTime) }()
-
-	// Start with a clean slate
-	pb.init()
-	pb.replayActive = true
+	defer func() {
+		res := pb.result()
+		pb.ctlMu.Lock()
+		pb.running = false
+		close(pb.exited)
+		pb.resultChan <- res
+		close(pb.resultChan)
+		pb.releaseTerm()
+		pb.ctlMu.Unlock()
+	}()
+
+	// No goroutine from the run outlives Wait
+	defer pb.life.teardown()
+	defer func() {
+		now := pb.clock().Now()
+		pb.WallRunDur = now.Sub(pb.WallStartTime)
+		pb.progressWall(now, true)
+	}()
+
+	// Play normally readies the run, the controller is started on
+	// its own in tests
+	pb.start()
+
+	// Run is quit unless the data runs out
+	completion := CompletedQuit
+	defer func() { pb.setCompletion(completion) }()
+
+	// Sinks finish up ahead of the teardown
+	defer pb.startSinks()()
 
 	// Start loading timestamped data from time stamp source,
-	// wait a few seconds to fill up read ahead buffers
-	go pb.loadTimeStampedData()
-	time.Sleep(1 * time.Second)
+	// wait for the read ahead buffers to fill up. A nil warmup
+	// chan waits on the loader however long
+	pb.life.spawn(stageLoader, pb.loadTimeStampedData)
+	var warmup <-chan time.Time
+	if timeout := pb.preloadTimeout(); timeout > 0 {
+		warmupTimer := time.NewTimer(timeout)
+		defer warmupTimer.Stop()
+		warmup = warmupTimer.C
+	}
+	for warming := true; warming; {
+		select {
+		case <-warmup:
+			warming = false
+		case <-pb.preloaded:
+			warming = false
+		case c := <-pb.cmdChan:
+			pb.apply(c)
+		}
+	}
+
+	// An empty source has nothing to play
+	select {
+	case <-pb.noData:
+		pb.WallStartTime = pb.clock().Now()
+		pb.controllerStarted.Done()
+		completion = CompletedNoData
+		return
+	default:
+	}
+
+	// Let Play return, then hold off emitting until the
+	// client signals it's ready to consume
+	if pb.ReadyGate != nil {
+		pb.controllerStarted.Done()
+		for gated := true; gated; {
+			select {
+			case <-pb.ReadyGate:
+				gated = false
+			case c := <-pb.cmdChan:
+				pb.apply(c)
+			case <-pb.quitChan:
+				pb.WallStartTime = pb.clock().Now()
+				return
+			}
+		}
+	}
 
 	// Start the timed data producer
-	go pb.dataTimer()
+	pb.life.spawn(stageTimer, pb.dataTimer)
 
 	// Wall simulation start time
-	pb.WallStartTime = time.Now()
+	pb.WallStartTime = pb.clock().Now()
+	pb.startMarks(pb.WallStartTime, pb.paused)
+	pb.progressWall(pb.WallStartTime, false)
+
+	if pb.ReadyGate == nil {
+		pb.controllerStarted.Done()
+	}
+
+	// Wall clock sampling ticks, a nil tickC disables the tick cases
+	var tickTimer *time.Timer
+	var tickC <-chan time.Time
+	if pb.TickEvery > 0 && pb.OnTick != nil {
+		tickTimer = time.NewTimer(nextTickDur(time.Now(), pb.TickEvery))
+		defer tickTimer.Stop()
+		tickC = tickTimer.C
+	}
+
+	// Most recently sent data, provided to OnTick
+	var lastTs TimeStamper
+
+	// Source pool to release sent values to, lastSrc is the source
+	// value behind lastTs and is held until the next send
+	pool, _ := pb.TsDataSource.(TimeStamperPool)
+	if !pb.ReleaseAfterSend {
+		pool = nil
+	}
+	var lastSrc TimeStamper
+
+	// Number of the next sim time milestone to reach
+	nextMilestone := 1
+
+	// Sends since queued commands were last put ahead of sends
+	sinceCheck := 0
+
+	// Values sent, not counting previews
+	var sentCnt int64
+
+	// emit sends a timed or stepped value to the client, a preview
+	// goes to SendTs only. Returns false if the run is stopped
+	emit := func(tsData TimeStamper, preview bool) bool {
+		// Raw source bytes go to SendTsRaw, not in the value
+		var raw []byte
+		tsData, raw = unwrapRaw(tsData)
+
+		srcData := tsData
+		if pb.rewriting() {
+			rwData := pb.rewrite(tsData)
+
+			// Guard against rewrites sending data back in time
+			if lastTs != nil &&
+				rwData.GetTimeStamp().Before(lastTs.GetTimeStamp()) {
+				pb.setErr(fmt.Errorf(
+					"playBack: rewritten timestamp %v before previous %v",
+					rwData.GetTimeStamp(), lastTs.GetTimeStamp()))
+				pb.quit()
+				return false
+			}
+			tsData = rwData
+		}
+
+		// Client supplied callback
+		if preview {
+			pb.send(PreviewTs{TimeStamper: tsData}, raw)
+		} else {
+			sendStart := pb.clock().Now()
+			err := pb.send(tsData, raw)
+			took := pb.clock().Now().Sub(sendStart)
+			if err != nil && pb.StopOnError {
+				pb.setErr(fmt.Errorf("playBack: send: %w", err))
+				pb.quit()
+				return false
+			}
+			pb.cacheEmitted(tsData, srcData.GetTimeStamp(), raw)
+			pb.progressSent(srcData.GetTimeStamp())
+			sentCnt++
+			pb.checkSendDeadline(sentCnt, took)
+			if !pb.fanOut(tsData) {
+				return false
+			}
+		}
+		lastTs = tsData
+		sinceCheck++
+
+		// Sim time gap from the previous send
+		if pb.OnGap != nil && lastSrc != nil {
+			pb.OnGap(srcData.GetTimeStamp().Sub(lastSrc.GetTimeStamp()),
+				srcData.GetTimeStamp())
+		}
+
+		pb.milestones(srcData.GetTimeStamp(), &nextMilestone)
 
-	pb.controllerStarted.Done()
+		// Client is done with the previous value
+		if pool != nil && lastSrc != nil {
+			pool.ReleaseTimeStamper(lastSrc)
+		}
+		lastSrc = srcData
+		return true
+	}
+
+	// stepped emits a value sent by Step, which waits on done
+	stepped := func(req *stepReq) bool {
+		defer close(req.done)
+		return emit(req.ts, req.preview)
+	}
 
 	for {
+		// A frozen consumer stops receiving, dataTimer blocks on
+		// the send while its wall clock schedule moves on
+		timedTs := pb.timedTs
+		if pb.frozen.Load() {
+			timedTs = nil
+		}
+
+		// Queued commands go first, a pause holds off the next send
+		if pb.ControlCheckEvery > 0 && sinceCheck >= pb.ControlCheckEvery {
+			sinceCheck = 0
+			if !pb.applyPending() {
+				return
+			}
+			if pb.paused {
+				timedTs = nil
+			}
+		}
+		pauseChan, _ := pb.pauseSignals()
+
 		select {
 		// data comes in at sim time on
 		// timedTs chan.
-		case tsData, ok := <-pb.timedTs:
+		case tsData, ok := <-timedTs:
 			if !ok {
+				// dataTimer also closes timedTs when quit
+				select {
+				case <-pb.quitChan:
+					return
+				default:
+				}
+
 				// All data has been sent
 				fmt.Println("controller done")
+				completion = CompletedOK
+
+				// Hold the run open so the client can finish up
+				if pb.LingerAfterLast > 0 {
+					select {
+					case <-time.After(pb.LingerAfterLast):
+					case <-pb.quitChan:
+					}
+				}
 				return
 			}
-			// Client supplied callback
-			pb.SendTs(tsData)
+			if !emit(tsData, false) {
+				return
+			}
+		case req := <-pb.stepTs:
+			if !stepped(req) {
+				return
+			}
+		case wall := <-tickC:
+			pb.OnTick(wall, lastTs)
+			tickTimer.Reset(nextTickDur(time.Now(), pb.TickEvery))
+		case <-pb.freezeSig:
+			// Freeze state changed, pick it up at the top
+		case c := <-pb.cmdChan:
+			pb.apply(c)
 		case <-pb.quitChan:
 			return
-		case <-pb.pauseChan:
-			pWallStart := time.Now()
+		case <-pauseChan:
+			// Resume credits the pause time
+		Paused:
 			select {
-			case <-pb.resumeChan:
-				pb.pauseMu.Lock()
-				pb.pauseDur = time.Since(pWallStart) + pb.pauseDur
-				pb.pauseMu.Unlock()
+			case c := <-pb.cmdChan:
+				pb.apply(c)
+				if pb.paused {
+					goto Paused
+				}
+			case req := <-pb.stepTs:
+				if !stepped(req) {
+					return
+				}
+				goto Paused
+			case wall := <-tickC:
+				// Ticks follow the wall clock, keep sampling
+				// while paused
+				pb.OnTick(wall, lastTs)
+				tickTimer.Reset(nextTickDur(time.Now(), pb.TickEvery))
+				goto Paused
 			case <-pb.quitChan:
 				return
 			}
@@ -325,14 +1612,42 @@ func (pb *PlayBack) controller() {
 	}
 }
 
+// awaitFrontier blocks until the ExternalClock frontier reaches
+// tsTime. Returns false if quit or the clock closes first
+func (pb *PlayBack) awaitFrontier(tsTime time.Time, frontier *time.Time) bool {
+	for frontier.Before(tsTime) {
+		select {
+		case tick, ok := <-pb.ExternalClock:
+			if !ok {
+				return false
+			}
+			if tick.After(*frontier) {
+				*frontier = tick
+			}
+		case <-pb.quitChan:
+			return false
+		}
+	}
+	return true
+}
+
+// nextTickDur returns the wall duration from now until the next
+// every boundary. For example, with every set to a minute the tick
+// lands on the top of the next wall clock minute.
+func nextTickDur(now time.Time, every time.Duration) time.Duration {
+	return now.Truncate(every).Add(every).Sub(now)
+}
+
 // dataTimer outputs the timestamp data at simulation time on the
 // timedTs chan
 func (pb *PlayBack) dataTimer() {
+	defer close(pb.timerDone)
 	defer close(pb.timedTs)
 
 	// A list has the constant insert time
 	// that is needed in the timing loop
 	pb.timingsInfo = list.New()
+	pb.timingTotals = timingTotals{}
 
 	// Total number of records sent
 	var tsRecCnt int64
@@ -343,49 +1658,158 @@ func (pb *PlayBack) dataTimer() {
 	// network could slow down and speed up as the network load
 	// changes.
 	var driftFactor time.Duration
+	maxDrift := pb.MaxDriftCorrection
+	if maxDrift == 0 {
+		maxDrift = DefaultMaxDriftCorrection
+	}
+	drift := pb.DriftController
+	if drift == nil {
+		drift = &sumDrift{limit: maxDrift}
+	}
+	drift.Reset()
 
 	// Sim timestamp of the previous tsData sent
 	prevTsDataTime := pb.StartTime
 
-	// Wall time of the prev tsData send
-	prevWallSendTime := time.Now()
+	// Sim time pacing measures from, the previous send's or the sim
+	// time reached at a later rate change
+	baseSim := pb.StartTime
+
+	// Index of the first RateTrigger not yet crossed
+	nextTrigger := 0
+
+	// Sim time the ExternalClock allows sending up to
+	var frontier time.Time
+
+	// Wall time of the prev tsData send, moved up with baseSim to a
+	// later rate change
+	prevWallSendTime := pb.clock().Now()
+
+	// Pacing state a Step moves on
+	st := stepTimer{recCnt: &tsRecCnt, prevWall: &prevWallSendTime,
+		prevSim: &prevTsDataTime, baseSim: &baseSim}
+
+	// Seeks the timer has caught up with
+	var seekGen int64
 
 	// read next slice of time stamped data from chan
-	for tsDataBuf := range pb.tsDataChan {
+	for tsDataBuf, ok := pb.recvTsDataBuf(); ok; tsDataBuf, ok = pb.recvTsDataBuf() {
+		// A seek drops the data loaded before its mark, pacing
+		// starts over from the sim time seeked to
+		if mark, isMark := tsDataBuf[0].(seekMark); isMark {
+			seekGen = mark.gen
+			if mark.at.Before(prevTsDataTime) && nextTrigger > 0 {
+				nextTrigger = 0
+				pb.restoreBaseRate()
+			}
+			prevTsDataTime, baseSim = mark.at, mark.at
+			prevWallSendTime = pb.clock().Now()
+			drift.Reset()
+			driftFactor = 0
+			select {
+			case <-pb.seekSig:
+			default:
+			}
+			continue
+		}
+		if seekGen != pb.seekGen.Load() {
+			continue
+		}
+	Values:
 		for _, tsData := range tsDataBuf {
 			tsRecCnt++
+
+			// Catch a source ignoring the bracket
+			if pb.StrictBracket && pb.outOfBracket(tsData.GetTimeStamp()) {
+				pb.setErr(fmt.Errorf("%w: %v not in %v to %v", ErrOutOfBracket,
+					tsData.GetTimeStamp(), pb.StartTime, pb.EndTime))
+				pb.quit()
+				return
+			}
 		SleepCheck:
+			if seekGen != pb.seekGen.Load() {
+				// Not sent, seeked past
+				tsRecCnt--
+				break Values
+			}
+			pauseChan, _ := pb.pauseSignals()
 			select {
 			case <-pb.quitChan:
 				// stop the playback
 				return
 
-			case <-pb.pauseChan:
+			case <-pauseChan:
+				_, resumeChan := pb.pauseSignals()
+			Paused:
 				select {
-				case <-pb.resumeChan:
+				case <-resumeChan:
+					goto SleepCheck
+				case <-pb.seekSig:
+					goto SleepCheck
+				case req := <-pb.stepReqs:
+					if req.peek {
+						answerPeek(req, tsData)
+						goto Paused
+					}
+					if !pb.stepSend(req, tsData, st) {
+						return
+					}
+					continue
 				case <-pb.quitChan:
 					return
 				}
 			default:
 			}
 
-			// No need to run timing calcs for repeated timestamps
+			// Sim time the ts data is paced at
+			tsTime := pb.pacingTime(tsData, prevTsDataTime)
+
+			// Pacing can't go back in time
+			if tsTime.Before(prevTsDataTime) {
+				switch pb.OutOfOrder {
+				case OutOfOrderDrop:
+					tsRecCnt--
+					continue
+				case OutOfOrderError:
+					pb.setErr(fmt.Errorf("%w: %v before %v", ErrOutOfOrder,
+						tsData.GetTimeStamp(), prevTsDataTime))
+					pb.quit()
+					return
+				default:
+					tsTime = prevTsDataTime
+				}
+			}
+
+			// No need to run timing calcs for repeated timestamps,
+			// or with NoPacing
 			var sd time.Duration
 			var tsDur time.Duration
-			if !tsData.GetTimeStamp().Equal(prevTsDataTime) {
+			if pb.ExternalClock != nil {
+				// Slaved to the external clock, no pacing
+				if !pb.awaitFrontier(tsTime, &frontier) {
+					return
+				}
+			} else if !pb.NoPacing && !tsTime.Equal(prevTsDataTime) {
+
+				// A rate change paces the rest of the gap from the
+				// sim time reached at the change
+				rateCnt := pb.rateCnt.Load()
+				pb.rebase(&baseSim, &prevWallSendTime, tsTime)
 
 				// time between this ts data and the prev ts data
 				// adjusted for sim rate TODO rename tsDur
-				tsDur = tsData.GetTimeStamp().Sub(prevTsDataTime)
-				pb.rateMu.RLock()
-				tsDur = tsDur / pb.rateDur
-				pb.rateMu.RUnlock()
+				tsDur = pb.pacedDur(baseSim, tsTime, &nextTrigger)
+
+				// Squash over-long gaps
+				if pb.MaxInterRecordWait > 0 && tsDur > pb.MaxInterRecordWait {
+					tsDur = pb.MaxInterRecordWait
+				}
 
 				// actual wall time between now and the time the prev
 				// ts data value was sent out
-				pb.pauseMu.RLock()
-				wallDur := time.Since(prevWallSendTime) - pb.pauseDur
-				pb.pauseMu.RUnlock()
+				now := pb.clock().Now()
+				wallDur := now.Sub(prevWallSendTime) -
+					pb.pausedBetween(prevWallSendTime, now)
 
 				// sleep duration is the diff between the time between
 				// ts data values and the wall time since the prev
@@ -400,42 +1824,81 @@ func (pb *PlayBack) dataTimer() {
 				// can continue to respond to API signals, otherwise the
 				// longest sleep duration is data driven and unbounded
 				if sd > (250 * time.Millisecond) {
-					time.Sleep(250 * time.Millisecond)
+					if !pb.sleep(250 * time.Millisecond) {
+						return
+					}
+					goto SleepCheck
+				}
+				pauseCnt := pb.pauseCnt.Load()
+				if !pb.sleep(sd) {
+					return
+				}
+
+				// A pause during the sleep pushes the send back, a
+				// rate change moves it and a seek drops it
+				if pb.pauseCnt.Load() != pauseCnt || pb.rateCnt.Load() != rateCnt ||
+					pb.seekGen.Load() != seekGen {
 					goto SleepCheck
 				}
-				time.Sleep(sd)
 			}
 
 			// Client call back ie the send.
 			// This is the time sensitive point of consumption.
 			// The whole point. Pièce de résistance
 			//pb.SendTs(tsData)
-			pb.timedTs <- tsData
-			wallSendTime := time.Now()
+		Send:
+			select {
+			case pb.timedTs <- tsData:
+			case req := <-pb.stepReqs:
+				// Paused with tsData due
+				if req.peek {
+					answerPeek(req, tsData)
+					goto Send
+				}
+				if !pb.stepSend(req, tsData, st) {
+					return
+				}
+				continue
+			case <-pb.seekSig:
+				// Held back by the controller and seeked past
+				goto SleepCheck
+			case <-pb.quitChan:
+				// controller is gone, nobody will receive
+				return
+			}
+			wallSendTime := pb.clock().Now()
 
 			// driftDur is actual wall time between sends minus the
 			// time stamp calculated desired time between sends.
 			// Drift can go negative due to the drift factor
 			// causing the client send to happen to early.
-			pb.pauseMu.Lock()
-			driftDur := (wallSendTime.Sub(prevWallSendTime) - pb.pauseDur) -
+			pauseDur := pb.pausedBetween(prevWallSendTime, wallSendTime)
+			driftDur := (wallSendTime.Sub(prevWallSendTime) - pauseDur) -
 				(tsDur)
 
-			// reset pause duration, done with pause adjustments
-			pb.pauseDur = time.Duration(0 * time.Second)
-			pb.pauseMu.Unlock()
+			// targetWall is the scheduled send time, driftDur is the
+			// send time's distance from it
+			targetWall := prevWallSendTime.Add(pauseDur + tsDur)
+
+			// done with pause adjustments up to the send, a pause
+			// since counts toward the next send
+			pb.trimPauses(wallSendTime)
 
 			// Collect timing data
-			rt := runTimings{}
-			rt.trdTime = tsData.GetTimeStamp()
-			rt.sd = sd
-			rt.recNum = tsRecCnt
-			rt.driftDur = driftDur
-			pb.timingsInfo.PushBack(rt)
+			rt := RunTiming{}
+			rt.TsTime = tsData.GetTimeStamp()
+			rt.SleepDur = sd
+			rt.RecNum = tsRecCnt
+			rt.DriftDur = driftDur
+			rt.TargetWall = targetWall
+			rt.ActualWall = wallSendTime
+			pb.recordTiming(rt)
+			pb.sendMetrics(rt)
 
 			// Set up loop for next iteration
 			prevWallSendTime = wallSendTime
-			prevTsDataTime = tsData.GetTimeStamp()
+			prevTsDataTime = tsTime
+			baseSim = tsTime
 
 			// Re-calc drift factor.
 			// If the last send's drift was positive the client
@@ -448,33 +1911,197 @@ func (pb *PlayBack) dataTimer() {
 			// than expected.  In this case the drift factor is
 			// decreased, the pre send sleep duration is increased,
 			// and the client callback gets called later.
-			driftFactor = driftFactor + rt.driftDur
+			// The DriftController sets how much of the drift is
+			// corrected, by default the drifts are summed. The
+			// correction is bounded by MaxDriftCorrection.
+			driftFactor = clampDrift(drift.Correction(rt.DriftDur), maxDrift)
 		}
 	}
 }
 
-// runTimings holds timing info for each timestamper
+// outOfBracket reports if tim is before StartTime or after EndTime
+func (pb *PlayBack) outOfBracket(tim time.Time) bool {
+	return tim.Before(pb.StartTime) || tim.After(pb.EndTime)
+}
+
+// sleep pauses the calling goroutine for at least d, returns false
+// if cut short by quit
+func (pb *PlayBack) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	select {
+	case <-pb.clock().After(d):
+		return true
+	case <-pb.rateSig:
+		// Cut short for the caller to repace at the new rate
+		return true
+	case <-pb.seekSig:
+		// Cut short for the caller to drop the value
+		return true
+	case <-pb.quitChan:
+		return false
+	}
+}
+
+// pacingTime returns the sim time tsData is paced at. Data in a
+// Class with a coarse Pacing is paced at the start of its Granularity
+// window, but never earlier than prev, the sim time of the previous
+// send.
+func (pb *PlayBack) pacingTime(tsData TimeStamper, prev time.Time) time.Time {
+	tsTime := tsData.GetTimeStamp()
+	if pb.TimestampResolution > 0 {
+		tsTime = tsTime.Truncate(pb.TimestampResolution)
+	}
+
+	// Values before the bracket, a prior record for one, go out at
+	// the start
+	if tsTime.Before(pb.StartTime) {
+		tsTime = pb.StartTime
+	}
+	if pb.ReverseWithinBucket > 0 {
+		tsTime = pb.bucketStart(tsTime)
+	}
+	if pb.Classifier == nil {
+		return tsTime
+	}
+	g := pb.ClassPacing[pb.Classifier(tsData)].Granularity
+	if g <= 0 {
+		return tsTime
+	}
+	tsTime = pb.StartTime.Add(tsTime.Sub(pb.StartTime) / g * g)
+	if tsTime.Before(prev) {
+		return prev
+	}
+	return tsTime
+}
+
+// RunTiming holds timing info for each timestamper
 // that was emitted during the last playback run
-type runTimings struct {
-	trdTime             time.Time
-	sd                  time.Duration
-	realTimeBeforeSleep time.Time
-	recNum              int64
-	driftDur            time.Duration
+type RunTiming struct {
+	TsTime   time.Time
+	SleepDur time.Duration
+	RecNum   int64
+	DriftDur time.Duration
+
+	// TargetWall is the wall time the send was scheduled for and
+	// ActualWall is the wall time the send happened.
+	// ActualWall - TargetWall is DriftDur
+	TargetWall time.Time
+	ActualWall time.Time
 }
 
-// TimeDrift calculates some run time timing info
-func (pb *PlayBack) TimeDrift() {
-	maxDrift := 0.0
-	var actSec runTimings
+// Timings returns the timing info collected for each timestamper sent
+// during the last playback run, or the sample of them kept per
+// TimingSampleRate. Call after Wait returns.
+func (pb *PlayBack) Timings() []RunTiming {
+	if pb.timingsInfo == nil {
+		return nil
+	}
+	timings := make([]RunTiming, 0, pb.timingsInfo.Len())
 	for el := pb.timingsInfo.Front(); el != nil; el = el.Next() {
-		actSec = el.Value.(runTimings)
+		timings = append(timings, el.Value.(RunTiming))
+	}
+	return timings
+}
+
+// DriftStats summarizes the send drift of the last run, see
+// PlayBack.DriftStats. Drifts are absolute, in milliseconds.
+type DriftStats struct {
+	MaxDriftMs   float64
+	MeanDriftMs  float64
+	TotalRecords int64
+
+	// ExpectedRunDuration is the wall time the sim time from the
+	// StartTime to the last send plays over at the rate set by New or
+	// SetRate, ActualRunDuration the run's WallRunDur
+	ExpectedRunDuration time.Duration
+	ActualRunDuration   time.Duration
+
+	// SlowSends is the count of sends over the SendDeadline,
+	// SlowSendOverrun the total time they went over it
+	SlowSends       int64
+	SlowSendOverrun time.Duration
+}
+
+// DriftStats returns the drift stats of the last run. Call after Wait
+// returns.
+func (pb *PlayBack) DriftStats() DriftStats {
+	stats := DriftStats{
+		ActualRunDuration: pb.WallRunDur,
+		SlowSends:         pb.slowSends.Load(),
+		SlowSendOverrun:   time.Duration(pb.slowOverrun.Load()),
+	}
+	tt := pb.timingTotals
+	if tt.count == 0 {
+		return stats
+	}
+	stats.MaxDriftMs = tt.maxDrift.Seconds() * 1000
+	stats.TotalRecords = tt.count
+	stats.MeanDriftMs = tt.sumDrift.Seconds() * 1000 / float64(tt.count)
+	pb.rateMu.RLock()
+	baseRate := pb.baseRate
+	pb.rateMu.RUnlock()
+	stats.ExpectedRunDuration = time.Duration(
+		float64(tt.last.TsTime.Sub(pb.StartTime)) / baseRate)
+	return stats
+}
+
+// timingTotals sums up the RunTimings of every send of a run
+type timingTotals struct {
+	count       int64
+	first, last RunTiming
+
+	// Absolute drifts
+	sumDrift time.Duration
+	maxDrift time.Duration
+}
 
-		maxDrift = math.Max(maxDrift, math.Abs(actSec.driftDur.Seconds()/1000))
+// recordTiming adds the timing of a send to the totals and keeps it
+// if it's in the TimingSampleRate sample. Only called by the timer
+func (pb *PlayBack) recordTiming(rt RunTiming) {
+	tt := &pb.timingTotals
+	if tt.count == 0 {
+		tt.first = rt
+	}
+	tt.last = rt
+	drift := rt.DriftDur
+	if drift < 0 {
+		drift = -drift
+	}
+	tt.sumDrift += drift
+	tt.maxDrift = max(tt.maxDrift, drift)
+	if pb.TimingSampleRate <= 1 || tt.count%int64(pb.TimingSampleRate) == 0 {
+		pb.timingsInfo.PushBack(rt)
 	}
-	fmt.Printf("Max Drift between: %f(ms)\n", maxDrift)
+	tt.count++
+}
+
+// TimeDrift prints some run time timing info, see DriftStats
+func (pb *PlayBack) TimeDrift() {
+	stats := pb.DriftStats()
+	fmt.Printf("Max Drift between: %f(ms)\n", stats.MaxDriftMs)
+	fmt.Printf("Mean Drift between: %f(ms)\n", stats.MeanDriftMs)
+	fmt.Printf("Records: %d\n", stats.TotalRecords)
 	fmt.Printf("Expected Real run time %f(s)\n",
-		(actSec.trdTime.Sub(pb.StartTime) / pb.rateDur).Seconds())
-	fmt.Println(pb.StartTime)
-	fmt.Println(actSec.trdTime)
+		stats.ExpectedRunDuration.Seconds())
+	fmt.Printf("Actual Real run time %f(s)\n",
+		stats.ActualRunDuration.Seconds())
+	if pb.SendDeadline > 0 {
+		fmt.Printf("Slow sends: %d over by %f(ms)\n", stats.SlowSends,
+			stats.SlowSendOverrun.Seconds()*1000)
+	}
+}
+
+// checkSendDeadline counts send rec as slow if it took longer than
+// the SendDeadline
+func (pb *PlayBack) checkSendDeadline(rec int64, took time.Duration) {
+	if pb.SendDeadline <= 0 || took <= pb.SendDeadline {
+		return
+	}
+	pb.slowSends.Add(1)
+	pb.slowOverrun.Add(int64(took - pb.SendDeadline))
+	if pb.OnSlowSend != nil {
+		pb.OnSlowSend(rec, took)
+	}
 }
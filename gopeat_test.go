@@ -1,8 +1,14 @@
 package gopeat
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"math"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -33,7 +39,7 @@ func (st *mockTsDataSource) Next() (TimeStamper, bool) {
 			Tim: st.StartTime,
 			Val: st.RecCnt,
 		}
-		st.StartTime.Add(st.DataInterval)
+		st.StartTime = st.StartTime.Add(st.DataInterval)
 		return trd, true
 	}
 }
@@ -48,12 +54,12 @@ func (st *mockTsDataSource) SetEndTime(endTime time.Time) {
 // does not block the load data goroutine from shutting down
 type mockTsBlockingDs struct {
 	Wg         sync.WaitGroup
-	NextCalled bool
+	NextCalled atomic.Bool
 }
 
 func (st *mockTsBlockingDs) Next() (TimeStamper, bool) {
 	st.Wg.Add(1)
-	st.NextCalled = true
+	st.NextCalled.Store(true)
 	st.Wg.Wait()
 	return nil, false
 }
@@ -64,6 +70,26 @@ func (st *mockTsBlockingDs) SetStartTime(startTime time.Time) {
 func (st *mockTsBlockingDs) SetEndTime(endTime time.Time) {
 }
 
+// A datasource that provides one value, then blocks on NextContext
+// until the context is done. Next is never called by the loader
+type mockCancellableDs struct {
+	tim  time.Time
+	sent bool
+}
+
+func (st *mockCancellableDs) Next() (TimeStamper, bool) {
+	panic("Next called on a CancellableSource")
+}
+
+func (st *mockCancellableDs) NextContext(ctx context.Context) (TimeStamper, bool) {
+	if !st.sent {
+		st.sent = true
+		return mockTsData{Tim: st.tim, Val: 1}, true
+	}
+	<-ctx.Done()
+	return nil, false
+}
+
 // Uses slice provided as data
 type mockSliceBackedDs struct {
 	TimeStampers []TimeStamper
@@ -103,7 +129,7 @@ func TestChangeRate(t *testing.T) {
 	pb.SendTs = func(ts TimeStamper) error {
 		callbackHit = true
 		wallDur := time.Since(pb.WallStartTime)
-		expDur := (ts.GetTimeStamp().Sub(simStartTime) / pb.rateDur)
+		expDur := pb.scaled(ts.GetTimeStamp().Sub(simStartTime))
 		timeDrift := wallDur - expDur
 		if math.Abs(timeDrift.Seconds()*1000) > 3 {
 			t.Errorf("Time = %f(ms); want less than 3(ms)", timeDrift.Seconds()*1000)
@@ -244,51 +270,50 @@ func TestLongPause(t *testing.T) {
 	}
 }
 
-// TestSendSpeed confirms that a value sent into playback is sent within
-// 3 milliseconds of the proper time
+// TestSendSpeed confirms that a value sent into playback is sent at
+// the proper time, on a mock clock so the check is exact
 func TestSendSpeed(t *testing.T) {
 	// Create a new PlayBack at 2x rate
 	var mts mockSliceBackedDs
 	simStartTime := time.Now()
 	dataTime := simStartTime.Add(time.Second * 1)
 	pb, _ := New("test", simStartTime, dataTime, &mts, 2, nil)
-	pb.init()
+	clock := &mockClock{now: simStartTime, manual: true}
+	pb.Clock = clock
 
-	// Callback measures time to first data playback send.  Since
-	// the first time stamper is 1 second after playback start,
+	// Since the first time stamper is 1 second after playback start,
 	// the first callback should be at .5 seconds given the rate is 2x
-	callbackHit := false
 	pb.SendTs = func(ts TimeStamper) error {
-		callbackHit = true
-		wallDur := time.Since(pb.WallStartTime)
-		expDur := ts.GetTimeStamp().Sub(simStartTime) / 2
-		timeDrift := wallDur - expDur
-		if math.Abs(timeDrift.Seconds()*1000) > 3 {
-			t.Errorf("Time = %f(ms); want less than 3(ms)", timeDrift.Seconds()*1000)
-		}
-
 		if ts.(mockTsData).Val != 6 {
 			t.Errorf("Val = %d; want 6", ts.(mockTsData).Val)
 		}
-
 		return nil
 	}
 
 	// Create a timestamper with a timestamp 1 second out after
-	// start time and push it into the playback data chan
+	// start time, and one 3 seconds out
 	mts.TimeStampers = []TimeStamper{
 		mockTsData{Tim: dataTime, Val: 6},
 		mockTsData{Tim: simStartTime.Add(time.Second * 3), Val: 6},
 	}
 
-	// run dataTimer, it will execute callback
-	pb.controllerStarted.Add(1)
-	pb.controller()
-	pb.termWg.Wait()
+	pb.Play()
+	done := make(chan struct{})
+	go func() {
+		pb.Wait()
+		close(done)
+	}()
+	for clock.advanceNext(done) {
+	}
 
-	// Make sure call was called
-	if !callbackHit {
-		t.Errorf("Provided PlayBack call was not executed")
+	timings := pb.Timings()
+	if len(timings) != 2 {
+		t.Fatalf("%d sends; expected 2", len(timings))
+	}
+	for i, exp := range []time.Duration{500 * time.Millisecond, 1500 * time.Millisecond} {
+		if d := timings[i].ActualWall.Sub(simStartTime); d != exp {
+			t.Errorf("send %d at %v; expected %v", i, d, exp)
+		}
 	}
 }
 
@@ -375,15 +400,14 @@ func TestSameTimeStamp(t *testing.T) {
 
 // Confirm pause works when called while PlayBack is waiting to send
 // out next timestamper.
-// For example, 2 timestamps in playback, 500ms in between packets.
-// After processing the first ts, PlayBack "waits" for 500ms before
+// For example, 2 timestamps in playback, 400ms in between packets.
+// After processing the first ts, PlayBack "waits" for 400ms before
 // sending out the second ts.  During the wait, PlayBack should still
 // respond to Quit() and Pause() API control signals.
-// This test implements the above setup, and pauses for 100ms between
-// ts1 and ts2.  So ts2 should be sent out 600ms(100ms pause + 500ms) after
-// sim start
+// This test implements the above setup on a mock clock, and pauses
+// for 100ms 250ms into the wait. So ts2 should be sent out 525ms
+// (25ms + 400ms + 100ms pause) after sim start
 func TestSendLongSleepPause(t *testing.T) {
-	// Create a new PlayBack at 2x rate
 	var mts mockSliceBackedDs
 	simStartTime := time.Now()
 	dataTime := simStartTime.Add(time.Millisecond * 25)
@@ -393,50 +417,49 @@ func TestSendLongSleepPause(t *testing.T) {
 		mockTsData{Tim: data2Time, Val: 6},
 	}
 
-	pb, _ := New("test", simStartTime, dataTime, &mts, 1, nil)
-	pb.init()
+	pb, _ := New("test", simStartTime, data2Time, &mts, 1, nil)
+	clock := &mockClock{now: simStartTime, manual: true}
+	pb.Clock = clock
 
-	cbChan := make(chan struct{})
-	// Callback measures time playback send.  Since the first time
-	// stamper is 1 second after playback start, the first callback
-	// should be at .5 seconds given the rate is 2x
+	// first lets the test know the 1st timestamper has been sent and
+	// pb is waiting on the 2nd, second that both have been sent
+	first, second := make(chan struct{}), make(chan struct{})
 	cbCount := 0
-	cbDrifts := make([]float64, 2)
 	pb.SendTs = func(ts TimeStamper) error {
 		cbCount++
-
-		// lets test know that 1st timestamper has been sent and pb is
-		// processing 2nd timestaper as soon as this call returns
-		if cbCount == 1 {
-			close(cbChan)
-		}
-		wallDur := time.Since(pb.WallStartTime)
-		expDur := ts.GetTimeStamp().Sub(simStartTime)
-		if cbCount == 2 {
-			expDur += (100 * time.Millisecond)
+		switch cbCount {
+		case 1:
+			close(first)
+		case 2:
+			close(second)
 		}
-		cbDrifts[cbCount-1] = (wallDur - expDur).Seconds() * 1000.0
 		return nil
 	}
 
-	// run send, it will execute callback
-	pb.controllerStarted.Add(1)
-	go pb.controller()
-	<-cbChan
-	time.Sleep(250 * time.Millisecond)
+	pb.Play()
+	clock.advanceNext(nil)
+	<-first
+
+	// Sleeps are cut to 250ms, pause after the first
+	clock.advanceNext(nil)
 	pb.Pause()
-	time.Sleep(100 * time.Millisecond)
+	pb.sync()
+	clock.advance(100 * time.Millisecond)
 	pb.Resume()
+	pb.sync()
+	for clock.advanceNext(second) {
+	}
 	pb.Wait()
 
 	// Make sure call was called
 	if cbCount != 2 {
-		t.Errorf("Provided PlayBack called %d, expected 2", cbCount)
+		t.Fatalf("Provided PlayBack called %d, expected 2", cbCount)
 	}
 
-	for _, td := range cbDrifts {
-		if td > 3.0 {
-			t.Errorf("Time = %f(ms); want less than 3(ms)", td)
+	timings := pb.Timings()
+	for i, exp := range []time.Duration{25 * time.Millisecond, 525 * time.Millisecond} {
+		if d := timings[i].ActualWall.Sub(simStartTime); d != exp {
+			t.Errorf("send %d at %v; expected %v", i, d, exp)
 		}
 	}
 }
@@ -448,7 +471,7 @@ func TestSimRate(t *testing.T) {
 	var mts mockTsDataSource
 	pb, _ := New("test", time.Now(), time.Now(), &mts, 2, nil)
 	dur := time.Duration(time.Minute * 4)
-	simTime := (dur / pb.rateDur)
+	simTime := pb.scaled(dur)
 	if simTime.Minutes() != 2 {
 		t.Errorf("dur(4) / 2 = %f; want 2", simTime.Minutes())
 	}
@@ -499,23 +522,93 @@ func TestCreateNoSource(t *testing.T) {
 	}
 }
 
+// A datasource that only implements TimeStampSource, it provides
+// its values whatever the bracket
+type mockNextOnlyDs struct {
+	TimeStampers []TimeStamper
+	idx          int
+}
+
+func (st *mockNextOnlyDs) Next() (TimeStamper, bool) {
+	if st.idx < len(st.TimeStampers) {
+		st.idx++
+		return st.TimeStampers[st.idx-1], true
+	}
+	return nil, false
+}
+
+// TestNextOnlySource plays a source without TimeBracket, playback
+// skips the values outside the bracket itself
+func TestNextOnlySource(t *testing.T) {
+	simStartTime := time.Now()
+	mts := &mockNextOnlyDs{}
+	for i := -1; i <= 4; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * 10 * time.Millisecond),
+			Val: int64(i)})
+	}
+	pb, err := New("test", simStartTime, simStartTime.Add(25*time.Millisecond),
+		mts, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vals []int64
+	pb.SendTs = func(ts TimeStamper) error {
+		vals = append(vals, ts.(mockTsData).Val)
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+
+	expected := []int64{0, 1, 2}
+	if len(vals) != len(expected) {
+		t.Fatalf("Got %v; expected %v", vals, expected)
+	}
+	for i := range expected {
+		if vals[i] != expected[i] {
+			t.Fatalf("Got %v; expected %v", vals, expected)
+		}
+	}
+}
+
+func TestCreateAllErrors(t *testing.T) {
+	// No source, swapped times and bad rate
+	_, err := New("test", time.Now().Add(time.Minute), time.Now(),
+		nil, 0, nil)
+
+	if err == nil {
+		t.Fatal("Got Empty error, expected error")
+	}
+	for _, expected := range []string{
+		"playBack: tsSource required",
+		"playBack: endTime before startTime",
+		"playBack: rate must be greater than 0",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Got %s error, expected it to contain %s",
+				err.Error(), expected)
+		}
+	}
+}
+
 func TestPlay(t *testing.T) {
 	mts := mockTsBlockingDs{}
 
 	// Create a new playback that uses mts
 	pb, _ := New("test", time.Now(), time.Now(), &mts, 2, nil)
 
-	// Active flag should start as false
-	if pb.replayActive {
-		t.Errorf("replay active true, expected false")
+	// Should start idle
+	if !pb.IsIdle() {
+		t.Errorf("playback not idle, expected idle")
 	}
 
 	pb.Play()
 	time.Sleep(10 * time.Millisecond)
 
-	// play worked if it set active flag to true
-	if !pb.replayActive {
-		t.Errorf("replay active false, expected true")
+	// play worked if it's playing
+	if st := pb.State(); st != Playing {
+		t.Errorf("State = %v, expected Playing", st)
 	}
 
 	// Release mts so we don't leak loader goroutine
@@ -523,7 +616,7 @@ func TestPlay(t *testing.T) {
 
 	// play should start data loading from TimeStamper Source, so
 	// confirm source's Next() was called
-	if !mts.NextCalled {
+	if !mts.NextCalled.Load() {
 		t.Error("NextCalled is false, expected true")
 	}
 
@@ -561,7 +654,7 @@ func TestPause(t *testing.T) {
 	pb.replayActive = true
 	pb.paused = false
 
-	pb.Pause()
+	pb.pause()
 
 	// Make sure pause closed pauseChan to signal a pause
 	select {
@@ -598,7 +691,7 @@ func TestPauseAlreadyPaused(t *testing.T) {
 	pb.replayActive = false
 	pb.paused = true
 
-	pb.Pause()
+	pb.pause()
 
 	// Since pb is already in the paused state,
 	// Pause() should do nothing
@@ -632,7 +725,7 @@ func TestResume(t *testing.T) {
 	close(pb.pauseChan)
 	pb.paused = true
 
-	pb.Resume()
+	pb.resume()
 
 	// Make sure resume closed resumeChan to signal a pause
 	select {
@@ -667,7 +760,7 @@ func TestQuit(t *testing.T) {
 	pb.replayActive = true
 	pb.quitChan = make(chan struct{})
 
-	pb.Quit()
+	pb.quit()
 
 	select {
 	case <-pb.quitChan:
@@ -675,3 +768,1248 @@ func TestQuit(t *testing.T) {
 		t.Error("quitChan not signaled, expected it to be signaled")
 	}
 }
+
+// TestTickEvery confirms OnTick fires on each wall clock TickEvery
+// boundary carrying the most recently sent timestamper
+func TestTickEvery(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(50 * time.Millisecond), Val: 1},
+		mockTsData{Tim: simStartTime.Add(1250 * time.Millisecond), Val: 2},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second*2), &mts, 1, nil)
+
+	// SendTs and OnTick both run on the controller, no locking needed
+	var lastSent TimeStamper
+	pb.SendTs = func(ts TimeStamper) error {
+		lastSent = ts
+		return nil
+	}
+	var tickWalls []time.Time
+	pb.TickEvery = 100 * time.Millisecond
+	pb.OnTick = func(wall time.Time, last TimeStamper) {
+		tickWalls = append(tickWalls, wall)
+		if last != lastSent {
+			t.Errorf("tick %d last = %v; want %v", len(tickWalls), last, lastSent)
+		}
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	// ~1250ms of emitting should produce at least 10 ticks
+	if len(tickWalls) < 10 {
+		t.Fatalf("Got %d ticks; expected at least 10", len(tickWalls))
+	}
+
+	// Each tick should land within 3ms after a 100ms wall boundary
+	for i, wall := range tickWalls {
+		off := wall.Sub(wall.Truncate(pb.TickEvery))
+		if off > 3*time.Millisecond {
+			t.Errorf("tick %d is %f(ms) past boundary; want less than 3(ms)",
+				i, off.Seconds()*1000)
+		}
+	}
+}
+
+// TestRunTimingWalls confirms each recorded send's actual wall time
+// minus its target wall time matches the recorded drift
+func TestRunTimingWalls(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(20 * time.Millisecond), Val: 1},
+		mockTsData{Tim: simStartTime.Add(20 * time.Millisecond), Val: 2},
+		mockTsData{Tim: simStartTime.Add(70 * time.Millisecond), Val: 3},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+
+	pb.Play()
+	pb.Wait()
+
+	timings := pb.Timings()
+	if len(timings) != 3 {
+		t.Fatalf("Got %d timings; expected 3", len(timings))
+	}
+	for i, rt := range timings {
+		if rt.TargetWall.IsZero() || rt.ActualWall.IsZero() {
+			t.Errorf("timing %d has zero wall times", i)
+		}
+		d := rt.ActualWall.Sub(rt.TargetWall) - rt.DriftDur
+		if d < -time.Microsecond || d > time.Microsecond {
+			t.Errorf("timing %d actual-target differs from drift by %v", i, d)
+		}
+	}
+}
+
+// TestReadyGate confirms nothing is sent until the ready gate is
+// signaled and that sim time starts at the signal
+func TestReadyGate(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(10 * time.Millisecond), Val: 6},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+
+	ready := make(chan struct{})
+	pb.ReadyGate = ready
+
+	var mu sync.Mutex
+	var sentWall time.Time
+	pb.SendTs = func(ts TimeStamper) error {
+		mu.Lock()
+		sentWall = time.Now()
+		mu.Unlock()
+		return nil
+	}
+
+	pb.Play()
+
+	// Withhold the ready signal well past the data's send time
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	sent := !sentWall.IsZero()
+	mu.Unlock()
+	if sent {
+		t.Fatal("data sent before ready gate signaled")
+	}
+
+	readyWall := time.Now()
+	close(ready)
+	pb.Wait()
+
+	// Data is 10ms into the sim, sim starts at the ready signal
+	d := (sentWall.Sub(readyWall) - 10*time.Millisecond).Seconds() * 1000
+	if math.Abs(d) > 3 {
+		t.Errorf("Time = %f(ms); want less than 3(ms)", d)
+	}
+}
+
+// TestClassPacing confirms critical data is paced precisely while bulk
+// data is released in a batch at the start of its granularity window
+func TestClassPacing(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	ms := func(n int) time.Time {
+		return simStartTime.Add(time.Duration(n) * time.Millisecond)
+	}
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: ms(20), Val: 0},
+		mockTsData{Tim: ms(110), Val: 1},
+		mockTsData{Tim: ms(150), Val: 1},
+		mockTsData{Tim: ms(190), Val: 1},
+		mockTsData{Tim: ms(250), Val: 0},
+	}
+	pb, _ := New("test", simStartTime, ms(500), &mts, 1, nil)
+
+	// Val 1 is bulk, everything else critical
+	pb.Classifier = func(ts TimeStamper) Class {
+		return Class(ts.(mockTsData).Val)
+	}
+	pb.ClassPacing = map[Class]Pacing{
+		ClassBulk: {Granularity: 100 * time.Millisecond},
+	}
+
+	var sendDurs []time.Duration
+	pb.SendTs = func(ts TimeStamper) error {
+		sendDurs = append(sendDurs, time.Since(pb.WallStartTime))
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	expected := []time.Duration{20, 100, 100, 100, 250}
+	if len(sendDurs) != len(expected) {
+		t.Fatalf("Got %d sends; expected %d", len(sendDurs), len(expected))
+	}
+	for i, exp := range expected {
+		d := (sendDurs[i] - exp*time.Millisecond).Seconds() * 1000
+		if math.Abs(d) > 3 {
+			t.Errorf("send %d Time = %f(ms); want less than 3(ms)", i, d)
+		}
+	}
+}
+
+// TestSourceErrCompletion confirms a source ending on an error
+// completes the run with CompletedError after sending the good data
+func TestSourceErrCompletion(t *testing.T) {
+	simStartTime := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	csvData := "tim, amt\n" +
+		"09/01/2013 17:00:00.010 UTC, 12\n" +
+		"09/01/2013 17:00:00.020 UTC, \"55\n"
+	tsSource := &CsvTsSource{
+		CsvStream: strings.NewReader(csvData),
+		CsvTsConv: func(csv []string) (TimeStamper, error) {
+			tim, err := time.Parse("01/02/2006 15:04:05.999 MST", csv[0])
+			return mockTsData{Tim: tim}, err
+		},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second),
+		tsSource, 1, nil)
+
+	cbCount := 0
+	pb.SendTs = func(ts TimeStamper) error {
+		cbCount++
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	if cbCount != 1 {
+		t.Errorf("Provided PlayBack called %d, expected 1", cbCount)
+	}
+	if pb.Completion() != CompletedError {
+		t.Errorf("Completion = %d; expected CompletedError", pb.Completion())
+	}
+	if pb.Err() == nil || pb.Err() != tsSource.Err() {
+		t.Errorf("Err = %v; expected source error %v", pb.Err(), tsSource.Err())
+	}
+}
+
+// mockNilDs is a buggy source that returns a nil value with ok true
+// after its slice values
+type mockNilDs struct {
+	mockSliceBackedDs
+}
+
+func (m *mockNilDs) Next() (TimeStamper, bool) {
+	if ts, ok := m.mockSliceBackedDs.Next(); ok {
+		return ts, true
+	}
+	return nil, true
+}
+
+// TestNilTimeStamper confirms a source returning a nil value with ok
+// true ends the run with ErrNilTimeStamper after the good data
+func TestNilTimeStamper(t *testing.T) {
+	simStartTime := time.Now()
+	var mts mockNilDs
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(10 * time.Millisecond), Val: 1},
+		mockTsData{Tim: simStartTime.Add(20 * time.Millisecond), Val: 2},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+
+	cbCount := 0
+	pb.SendTs = func(ts TimeStamper) error {
+		cbCount++
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	if cbCount != 2 {
+		t.Errorf("Provided PlayBack called %d, expected 2", cbCount)
+	}
+	if pb.Completion() != CompletedError {
+		t.Errorf("Completion = %d; expected CompletedError", pb.Completion())
+	}
+	if pb.Err() != ErrNilTimeStamper {
+		t.Errorf("Err = %v; expected ErrNilTimeStamper", pb.Err())
+	}
+}
+
+// TestStrictBracket confirms strict mode ends the run on a value
+// after EndTime, and the default sends it
+func TestStrictBracket(t *testing.T) {
+	simStartTime := time.Now()
+	for _, strict := range []bool{false, true} {
+		var mts mockSliceBackedDs
+		mts.TimeStampers = []TimeStamper{
+			mockTsData{Tim: simStartTime.Add(10 * time.Millisecond), Val: 1},
+			mockTsData{Tim: simStartTime.Add(30 * time.Millisecond), Val: 2},
+		}
+		pb, _ := New("test", simStartTime, simStartTime.Add(20*time.Millisecond),
+			&mts, 1, nil)
+		pb.StrictBracket = strict
+
+		cbCount := 0
+		pb.SendTs = func(ts TimeStamper) error {
+			cbCount++
+			return nil
+		}
+
+		pb.Play()
+		pb.Wait()
+
+		if strict {
+			if cbCount != 1 {
+				t.Errorf("strict: Provided PlayBack called %d, expected 1", cbCount)
+			}
+			if pb.Completion() != CompletedError || !errors.Is(pb.Err(), ErrOutOfBracket) {
+				t.Errorf("strict: Completion = %d, Err = %v; expected ErrOutOfBracket",
+					pb.Completion(), pb.Err())
+			}
+			continue
+		}
+		if cbCount != 2 || pb.Completion() != CompletedOK {
+			t.Errorf("Provided PlayBack called %d, Completion %d; expected 2, CompletedOK",
+				cbCount, pb.Completion())
+		}
+	}
+}
+
+// TestOutOfOrder plays a source with a value timestamped before the
+// one ahead of it under each OutOfOrderPolicy
+func TestOutOfOrder(t *testing.T) {
+	simStartTime := time.Now()
+	expected := map[OutOfOrderPolicy]string{
+		OutOfOrderClamp: "[1 2 3 4]",
+		OutOfOrderDrop:  "[1 2 4]",
+		OutOfOrderError: "[1 2]",
+	}
+	for _, policy := range []OutOfOrderPolicy{OutOfOrderClamp, OutOfOrderDrop, OutOfOrderError} {
+		ss := &SliceTsSource{Values: []TimeStamper{
+			mockTsData{Tim: simStartTime.Add(10 * time.Millisecond), Val: 1},
+			mockTsData{Tim: simStartTime.Add(20 * time.Millisecond), Val: 2},
+			mockTsData{Tim: simStartTime.Add(15 * time.Millisecond), Val: 3},
+			mockTsData{Tim: simStartTime.Add(30 * time.Millisecond), Val: 4},
+		}}
+		pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), ss, 1, nil)
+		pb.OutOfOrder = policy
+
+		var vals []int64
+		var sendDurs []time.Duration
+		pb.SendTs = func(ts TimeStamper) error {
+			vals = append(vals, ts.(mockTsData).Val)
+			sendDurs = append(sendDurs, time.Since(pb.WallStartTime))
+			return nil
+		}
+		pb.Play()
+		pb.Wait()
+
+		if fmt.Sprint(vals) != expected[policy] {
+			t.Errorf("Policy %d sent %v; expected %s", policy, vals, expected[policy])
+		}
+		if policy == OutOfOrderError {
+			if pb.Completion() != CompletedError || !errors.Is(pb.Err(), ErrOutOfOrder) {
+				t.Errorf("Completion = %d, Err = %v; expected ErrOutOfOrder",
+					pb.Completion(), pb.Err())
+			}
+			continue
+		}
+		if pb.Completion() != CompletedOK {
+			t.Errorf("Policy %d Completion = %d; expected CompletedOK", policy, pb.Completion())
+		}
+
+		// The last value is still paced at 30ms
+		d := (sendDurs[len(sendDurs)-1] - 30*time.Millisecond).Seconds() * 1000
+		if d < -3 || d > 5 {
+			t.Errorf("Policy %d last send off by %f(ms); want -3 to 5(ms)", policy, d)
+		}
+	}
+}
+
+// TestStopOnError confirms a callback error on the 3rd record ends a
+// StopOnError run after 3 callbacks, and is ignored otherwise
+func TestStopOnError(t *testing.T) {
+	simStartTime := time.Now()
+	errSend := errors.New("send failed")
+	for _, stop := range []bool{false, true} {
+		ss := pipelineSource(simStartTime, 10, time.Millisecond)
+		pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), ss, 1, nil)
+		pb.StopOnError = stop
+
+		cbCount := 0
+		pb.SendTs = func(ts TimeStamper) error {
+			cbCount++
+			if cbCount == 3 {
+				return errSend
+			}
+			return nil
+		}
+		pb.Play()
+		res := <-pb.Done()
+		pb.Wait()
+
+		if !stop {
+			if cbCount != 10 || res.Completion != CompletedOK {
+				t.Errorf("Provided PlayBack called %d, Completion %d; expected 10, CompletedOK",
+					cbCount, res.Completion)
+			}
+			continue
+		}
+		if cbCount != 3 {
+			t.Errorf("stop: Provided PlayBack called %d, expected 3", cbCount)
+		}
+		if res.Completion != CompletedError || !errors.Is(res.Err, errSend) ||
+			!errors.Is(pb.Err(), errSend) {
+			t.Errorf("stop: Completion = %d, Err = %v; expected %v", res.Completion,
+				res.Err, errSend)
+		}
+		if !pb.IsIdle() {
+			t.Error("stop: run goroutines still going after Wait")
+		}
+	}
+}
+
+// TestEmptySource confirms a source with no data completes at once
+// with CompletedNoData
+func TestEmptySource(t *testing.T) {
+	simStartTime := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	tsSource := &CsvTsSource{
+		CsvStream: strings.NewReader("tim, amt\n"),
+		CsvTsConv: func(csv []string) (TimeStamper, error) {
+			return mockTsData{}, nil
+		},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), tsSource, 1, nil)
+
+	begin := time.Now()
+	res := pb.Run()
+
+	if took := time.Since(begin); took > 100*time.Millisecond {
+		t.Errorf("Run took %v; expected well under 100ms", took)
+	}
+	if res.Completion != CompletedNoData || res.Err != nil {
+		t.Errorf("Completion = %d, Err = %v; expected CompletedNoData", res.Completion, res.Err)
+	}
+	if res.Records != 0 {
+		t.Errorf("Records = %d; expected 0", res.Records)
+	}
+}
+
+// TestPreloadBuffers confirms sending starts once PreloadBuffers are
+// loaded, not after a fixed wait
+func TestPreloadBuffers(t *testing.T) {
+	mts := &mockSlowDs{Delay: 10 * time.Millisecond}
+	simStartTime := time.Now()
+	for i := 1; i <= 20; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * time.Millisecond), Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), mts, 1, nil)
+	pb.SetBufferSizes(2, 4)
+	pb.PreloadBuffers = 2
+
+	begin := time.Now()
+	pb.Play()
+	took := time.Since(begin)
+	pb.Quit()
+	pb.Wait()
+
+	// 2 buffers of 2 values at 10ms a read
+	if took < 40*time.Millisecond || took > 200*time.Millisecond {
+		t.Errorf("Play took %v; expected 40ms to 200ms", took)
+	}
+}
+
+// mockStallDs provides its slice values then hangs in Next until
+// release is closed
+type mockStallDs struct {
+	mockSliceBackedDs
+	release chan struct{}
+}
+
+func (m *mockStallDs) Next() (TimeStamper, bool) {
+	if ts, ok := m.mockSliceBackedDs.Next(); ok {
+		return ts, true
+	}
+	<-m.release
+	return nil, false
+}
+
+// TestLoaderStall confirms a source hung in Next ends the run with
+// ErrLoaderStall and Wait returns without waiting on Next
+func TestLoaderStall(t *testing.T) {
+	simStartTime := time.Now()
+	mts := &mockStallDs{release: make(chan struct{})}
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(10 * time.Millisecond), Val: 1},
+		mockTsData{Tim: simStartTime.Add(20 * time.Millisecond), Val: 2},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), mts, 1, nil)
+	pb.SetBufferSizes(1, 1)
+	pb.LoaderStallTimeout = 100 * time.Millisecond
+
+	cbCount := 0
+	pb.SendTs = func(ts TimeStamper) error {
+		cbCount++
+		return nil
+	}
+
+	pb.Play()
+	waited := make(chan struct{})
+	go func() {
+		pb.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait did not return on a stalled source")
+	}
+
+	if cbCount != 2 {
+		t.Errorf("Provided PlayBack called %d, expected 2", cbCount)
+	}
+	if pb.Completion() != CompletedError || pb.Err() != ErrLoaderStall {
+		t.Errorf("Completion = %d, Err = %v; expected ErrLoaderStall",
+			pb.Completion(), pb.Err())
+	}
+
+	// The loader is still stuck in Next
+	if pb.IsIdle() {
+		t.Error("IsIdle true with the loader stuck; expected false")
+	}
+	close(mts.release)
+	for i := 0; !pb.IsIdle(); i++ {
+		if i == 100 {
+			t.Fatal("IsIdle false after Next returned; expected true")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestMockSourceContract confirms the mock source never returns a nil
+// value with ok true, including after it runs out
+func TestMockSourceContract(t *testing.T) {
+	src := &mockTsDataSource{
+		MaxRecs:      5,
+		DataInterval: time.Millisecond,
+		StartTime:    time.Now(),
+	}
+	okCnt := 0
+	for i := 0; i < 10; i++ {
+		ts, ok := src.Next()
+		if ok {
+			okCnt++
+		}
+		if ok && ts == nil {
+			t.Fatalf("Next %d returned nil with ok true", i)
+		}
+		if !ok && ts != nil {
+			t.Fatalf("Next %d returned a value with ok false", i)
+		}
+	}
+	if okCnt != 5 {
+		t.Errorf("Got %d values; expected 5", okCnt)
+	}
+}
+
+// TestRewriteTsBackwards confirms a rewrite that moves timestamps
+// backwards stops the run with an error before the bad send
+func TestRewriteTsBackwards(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(10 * time.Millisecond), Val: 1},
+		mockTsData{Tim: simStartTime.Add(20 * time.Millisecond), Val: 2},
+		mockTsData{Tim: simStartTime.Add(30 * time.Millisecond), Val: 3},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+
+	// Misconfigured rewrite sends the second value an hour back
+	pb.RewriteTs = func(ts TimeStamper) TimeStamper {
+		rw := ts.(mockTsData)
+		if rw.Val == 2 {
+			rw.Tim = rw.Tim.Add(-time.Hour)
+		}
+		return rw
+	}
+	cbCount := 0
+	pb.SendTs = func(ts TimeStamper) error {
+		cbCount++
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	if cbCount != 1 {
+		t.Errorf("Provided PlayBack called %d, expected 1", cbCount)
+	}
+	if pb.Completion() != CompletedError {
+		t.Errorf("Completion = %d; expected CompletedError", pb.Completion())
+	}
+	if pb.Err() == nil {
+		t.Error("Err is nil, expected rewrite error")
+	}
+}
+
+// TestLingerAfterLast confirms Wait returns LingerAfterLast after
+// the last value is sent
+func TestLingerAfterLast(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(10 * time.Millisecond), Val: 6},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+	pb.LingerAfterLast = 150 * time.Millisecond
+
+	var lastSend time.Time
+	pb.SendTs = func(ts TimeStamper) error {
+		lastSend = time.Now()
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	d := (time.Since(lastSend) - pb.LingerAfterLast).Seconds() * 1000
+	if math.Abs(d) > 3 {
+		t.Errorf("Time = %f(ms); want less than 3(ms)", d)
+	}
+	if pb.Completion() != CompletedOK {
+		t.Errorf("Completion = %d; expected CompletedOK", pb.Completion())
+	}
+}
+
+// TestOnGap confirms the reported gaps match the source spacing
+func TestOnGap(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	spacing := []time.Duration{10, 30, 0, 20}
+	tim := simStartTime
+	for _, sp := range spacing {
+		tim = tim.Add(sp * time.Millisecond)
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{Tim: tim})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+
+	var gaps []time.Duration
+	var ats []time.Time
+	pb.OnGap = func(gap time.Duration, at time.Time) {
+		gaps = append(gaps, gap)
+		ats = append(ats, at)
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	if len(gaps) != len(spacing)-1 {
+		t.Fatalf("Got %d gaps; expected %d", len(gaps), len(spacing)-1)
+	}
+	for i, gap := range gaps {
+		if gap != spacing[i+1]*time.Millisecond {
+			t.Errorf("gap %d = %v; expected %v", i, gap, spacing[i+1]*time.Millisecond)
+		}
+		at := mts.TimeStampers[i+1].GetTimeStamp()
+		if !ats[i].Equal(at) {
+			t.Errorf("gap %d at = %v; expected %v", i, ats[i], at)
+		}
+	}
+}
+
+func TestHeadTail(t *testing.T) {
+	for _, tc := range []struct {
+		recs     int
+		expected []int64
+	}{
+		{10, []int64{1, 2, 3, 8, 9, 10}},
+		{7, []int64{1, 2, 3, 5, 6, 7}},
+		{4, []int64{1, 2, 3, 4}},
+		{2, []int64{1, 2}},
+	} {
+		mts := mockTsDataSource{MaxRecs: int64(tc.recs)}
+		pb, _ := New("test", time.Now(), time.Now(), &mts, 1, nil)
+
+		var vals []int64
+		pb.SendTs = func(ts TimeStamper) error {
+			vals = append(vals, ts.(mockTsData).Val)
+			return nil
+		}
+		if err := pb.HeadTail(3); err != nil {
+			t.Fatalf("HeadTail error: %v", err)
+		}
+
+		if len(vals) != len(tc.expected) {
+			t.Fatalf("%d recs: got vals %v; expected %v", tc.recs, vals, tc.expected)
+		}
+		for i := range vals {
+			if vals[i] != tc.expected[i] {
+				t.Errorf("%d recs: got vals %v; expected %v", tc.recs, vals, tc.expected)
+				break
+			}
+		}
+	}
+}
+
+// A slice backed datasource that takes Delay to provide each value
+type mockSlowDs struct {
+	mockSliceBackedDs
+	Delay time.Duration
+}
+
+func (st *mockSlowDs) Next() (TimeStamper, bool) {
+	time.Sleep(st.Delay)
+	return st.mockSliceBackedDs.Next()
+}
+
+func TestContentionStats(t *testing.T) {
+	simStartTime := time.Now()
+
+	// Fast source, slow timer: the loader waits on a full chan
+	var fast mockSliceBackedDs
+	for i := 1; i <= 20; i++ {
+		fast.TimeStampers = append(fast.TimeStampers,
+			mockTsData{Tim: simStartTime.Add(time.Duration(i) * 10 * time.Millisecond)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &fast, 1, nil)
+	pb.tsDataBufSize = 1
+	pb.tsDataChanLen = 1
+	pb.Play()
+	pb.Wait()
+
+	cs := pb.ContentionStats()
+	if cs.TimerReads != 20 || cs.LoaderSends != 20 {
+		t.Errorf("fast source reads %d sends %d; expected 20 and 20",
+			cs.TimerReads, cs.LoaderSends)
+	}
+	if cs.LoaderWaits <= cs.TimerWaits {
+		t.Errorf("fast source loader waits %d; expected more than timer waits %d",
+			cs.LoaderWaits, cs.TimerWaits)
+	}
+
+	// Slow source, fast timer: the timer waits on an empty chan
+	slow := mockSlowDs{Delay: 100 * time.Millisecond}
+	for i := 0; i < 15; i++ {
+		slow.TimeStampers = append(slow.TimeStampers, mockTsData{Tim: simStartTime})
+	}
+	pb, _ = New("test", simStartTime, simStartTime.Add(time.Second), &slow, 1, nil)
+	pb.tsDataBufSize = 1
+	pb.Play()
+	pb.Wait()
+
+	cs = pb.ContentionStats()
+	if cs.TimerWaits <= cs.LoaderWaits {
+		t.Errorf("slow source timer waits %d; expected more than loader waits %d",
+			cs.TimerWaits, cs.LoaderWaits)
+	}
+}
+
+// TestFreezeConsumer confirms drift grows while the consumer is
+// frozen and the playback catches back up to schedule after
+func TestFreezeConsumer(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 20; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * 20 * time.Millisecond),
+			Val: int64(i),
+		})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+
+	// Freeze after the 2nd send
+	frozen := make(chan struct{})
+	var lastSendDur time.Duration
+	pb.SendTs = func(ts TimeStamper) error {
+		if ts.(mockTsData).Val == 2 {
+			pb.FreezeConsumer()
+			close(frozen)
+		}
+		lastSendDur = time.Since(pb.WallStartTime)
+		return nil
+	}
+
+	pb.Play()
+	<-frozen
+	time.Sleep(150 * time.Millisecond)
+	pb.UnfreezeConsumer()
+	pb.Wait()
+
+	// Held send went out well past its schedule
+	timings := pb.Timings()
+	if timings[2].DriftDur < 100*time.Millisecond {
+		t.Errorf("Drift after freeze = %v; expected over 100ms", timings[2].DriftDur)
+	}
+
+	// Last value sent 400ms into the sim, back on schedule
+	d := (lastSendDur - 400*time.Millisecond).Seconds() * 1000
+	if math.Abs(d) > 3 {
+		t.Errorf("Time = %f(ms); want less than 3(ms)", d)
+	}
+}
+
+// TestMinBufferSizes runs playbacks with the smallest read ahead
+// buffer and chan sizes
+func TestMinBufferSizes(t *testing.T) {
+	simStartTime := time.Now()
+	newPb := func(recs int) (*PlayBack, *mockSliceBackedDs) {
+		mts := &mockSliceBackedDs{}
+		for i := 1; i <= recs; i++ {
+			mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+				Tim: simStartTime.Add(time.Duration(i/10) * time.Millisecond),
+				Val: int64(i),
+			})
+		}
+		pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), mts, 1, nil)
+		pb.tsDataBufSize = 1
+		pb.tsDataChanLen = 1
+		return pb, mts
+	}
+
+	// All data delivered in order
+	pb, _ := newPb(50)
+	var vals []int64
+	pb.SendTs = func(ts TimeStamper) error {
+		vals = append(vals, ts.(mockTsData).Val)
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+	if len(vals) != 50 {
+		t.Fatalf("Got %d values; expected 50", len(vals))
+	}
+	for i, val := range vals {
+		if val != int64(i+1) {
+			t.Fatalf("value %d is %d; expected %d", i, val, i+1)
+		}
+	}
+
+	// Quit with the loader blocked on the full chan, the loader
+	// should still shut down and close the data chan
+	pb, _ = newPb(500)
+	pb.SendTs = func(ts TimeStamper) error {
+		if ts.(mockTsData).Val == 5 {
+			pb.Quit()
+		}
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+	timeout := time.After(100 * time.Millisecond)
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-pb.tsDataChan:
+			closed = !ok
+		case <-timeout:
+			t.Fatal("tsDataChan is still open, expected it to be closed")
+		}
+	}
+}
+
+// A slice backed datasource that reports its time range
+type mockRangedDs struct {
+	mockSliceBackedDs
+}
+
+func (st *mockRangedDs) TimeRange() (first, last time.Time, ok bool) {
+	if len(st.TimeStampers) == 0 {
+		return first, last, false
+	}
+	return st.TimeStampers[0].GetTimeStamp(),
+		st.TimeStampers[len(st.TimeStampers)-1].GetTimeStamp(), true
+}
+
+func TestDetectedRange(t *testing.T) {
+	simStartTime := time.Now()
+	firstTime := simStartTime.Add(50 * time.Millisecond)
+	lastTime := simStartTime.Add(80 * time.Millisecond)
+	data := []TimeStamper{
+		mockTsData{Tim: firstTime},
+		mockTsData{Tim: simStartTime.Add(60 * time.Millisecond)},
+		mockTsData{Tim: lastTime},
+	}
+
+	// Plain source, only the first timestamp can be detected
+	mts := &mockSliceBackedDs{TimeStampers: data}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), mts, 1, nil)
+	if _, _, ok := pb.DetectedRange(); ok {
+		t.Error("range detected before Play, expected not ok")
+	}
+	pb.Play()
+	first, last, ok := pb.DetectedRange()
+	if !ok || !first.Equal(firstTime) || !last.IsZero() {
+		t.Errorf("range = %v, %v, %t; expected %v, zero time, true",
+			first, last, ok, firstTime)
+	}
+	pb.Wait()
+
+	// Ranged source, last is known too
+	rts := &mockRangedDs{mockSliceBackedDs{TimeStampers: data}}
+	pb, _ = New("test", simStartTime, simStartTime.Add(time.Second), rts, 1, nil)
+	pb.Play()
+	first, last, ok = pb.DetectedRange()
+	if !ok || !first.Equal(firstTime) || !last.Equal(lastTime) {
+		t.Errorf("range = %v, %v, %t; expected %v, %v, true",
+			first, last, ok, firstTime, lastTime)
+	}
+	pb.Wait()
+}
+
+// TestDateShift confirms values are moved to the shift date keeping
+// their time of day, including across a DST change
+func TestDateShift(t *testing.T) {
+	loc, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skip("America/Chicago time zone not available")
+	}
+
+	// Last week's 10:00am replayed today
+	now := time.Now().In(loc)
+	lastWeek := now.AddDate(0, 0, -7)
+	simStartTime := time.Date(lastWeek.Year(), lastWeek.Month(), lastWeek.Day(),
+		10, 0, 0, 0, loc)
+	mts := mockSliceBackedDs{
+		TimeStampers: []TimeStamper{mockTsData{Tim: simStartTime, Val: 6}},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+	pb.DateShift = now
+
+	var sent TimeStamper
+	pb.SendTs = func(ts TimeStamper) error {
+		sent = ts
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+
+	expected := time.Date(now.Year(), now.Month(), now.Day(), 10, 0, 0, 0, loc)
+	if sent == nil || !sent.GetTimeStamp().Equal(expected) {
+		t.Fatalf("Sent %v; expected timestamp %v", sent, expected)
+	}
+	if sent.(RetimedTs).TimeStamper.(mockTsData).Val != 6 {
+		t.Errorf("Sent %v; expected the original value wrapped", sent)
+	}
+
+	// CDT 10:00am shifted past the end of DST is CST 10:00am
+	pb.DateShift = time.Date(2013, 11, 5, 0, 0, 0, 0, loc)
+	cdt := time.Date(2013, 11, 1, 10, 0, 0, 0, loc)
+	cst := time.Date(2013, 11, 5, 10, 0, 0, 0, loc)
+	rw := pb.rewrite(mockTsData{Tim: cdt})
+	if !rw.GetTimeStamp().Equal(cst) {
+		t.Errorf("Rewrote %v to %v; expected %v", cdt, rw.GetTimeStamp(), cst)
+	}
+}
+
+// TestTimestampResolution confirms nanosecond apart values are sent
+// truncated to the millisecond, grouped by the truncated instant
+func TestTimestampResolution(t *testing.T) {
+	simStartTime := time.Now().Truncate(time.Millisecond)
+	base := simStartTime.Add(20 * time.Millisecond)
+	mts := mockSliceBackedDs{TimeStampers: []TimeStamper{
+		mockTsData{Tim: base.Add(1), Val: 1},
+		mockTsData{Tim: base.Add(2), Val: 2},
+		mockTsData{Tim: base.Add(500 * time.Microsecond), Val: 3},
+		mockTsData{Tim: base.Add(5*time.Millisecond + 7), Val: 4},
+		mockTsData{Tim: base.Add(6*time.Millisecond - 1), Val: 5},
+	}}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+	pb.TimestampResolution = time.Millisecond
+
+	var sent []time.Time
+	pb.SendTs = func(ts TimeStamper) error {
+		sent = append(sent, ts.GetTimeStamp())
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+
+	group := base.Add(5 * time.Millisecond)
+	expected := []time.Time{base, base, base, group, group}
+	if len(sent) != len(expected) {
+		t.Fatalf("Got %d sends; expected %d", len(sent), len(expected))
+	}
+	for i, exp := range expected {
+		if !sent[i].Equal(exp) {
+			t.Errorf("send %d timestamp %v; expected %v", i, sent[i], exp)
+		}
+	}
+
+	// Values after the first of a group go out without pacing
+	for i, rt := range pb.Timings() {
+		grouped := i == 1 || i == 2 || i == 4
+		if grouped && rt.SleepDur != 0 {
+			t.Errorf("send %d slept %v; expected sent with its group", i, rt.SleepDur)
+		}
+	}
+}
+
+// TestRewriteOrigin confirms rewritten timestamps start at the origin
+// and follow the rate
+func TestRewriteOrigin(t *testing.T) {
+	simStartTime := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	origin := time.Date(2020, 1, 2, 9, 30, 0, 0, time.UTC)
+	mts := mockSliceBackedDs{TimeStampers: []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(20 * time.Millisecond), Val: 1},
+		mockTsData{Tim: simStartTime.Add(60 * time.Millisecond), Val: 2},
+		mockTsData{Tim: simStartTime.Add(100 * time.Millisecond), Val: 3},
+	}}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 2, nil)
+	pb.RewriteOrigin = origin
+
+	var sent []TimeStamper
+	pb.SendTs = func(ts TimeStamper) error {
+		sent = append(sent, ts)
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+
+	// Sim offsets halved by the 2x rate
+	expected := []time.Time{
+		origin.Add(10 * time.Millisecond),
+		origin.Add(30 * time.Millisecond),
+		origin.Add(50 * time.Millisecond),
+	}
+	if len(sent) != len(expected) {
+		t.Fatalf("Got %d sends; expected %d", len(sent), len(expected))
+	}
+	for i, exp := range expected {
+		if !sent[i].GetTimeStamp().Equal(exp) {
+			t.Errorf("send %d timestamp %v; expected %v", i, sent[i].GetTimeStamp(), exp)
+		}
+	}
+	if sent[1].(RetimedTs).TimeStamper.(mockTsData).Val != 2 {
+		t.Errorf("Sent %v; expected the original value wrapped", sent[1])
+	}
+
+	// A rate change rescales from the previous value on
+	pb.init()
+	pb.setRate(1)
+	pb.fromOrigin(simStartTime.Add(20 * time.Millisecond))
+	pb.setRate(4)
+	if got := pb.fromOrigin(simStartTime.Add(60 * time.Millisecond)); !got.Equal(origin.Add(30 * time.Millisecond)) {
+		t.Errorf("after rate change %v; expected origin + 30ms", got)
+	}
+}
+
+// TestNoGoroutineLeaks confirms the run's goroutines are all gone
+// when Wait returns, for a completed run and a quit run
+func TestNoGoroutineLeaks(t *testing.T) {
+	simStartTime := time.Now()
+	before := runtime.NumGoroutine()
+
+	// Run to completion
+	mts := mockSliceBackedDs{TimeStampers: []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(10 * time.Millisecond)},
+		mockTsData{Tim: simStartTime.Add(20 * time.Millisecond)},
+	}}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+	pb.Play()
+	pb.Wait()
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines after completed run; expected %d", n, before)
+	}
+
+	// Quit with the timer in a long sleep and the loader blocked
+	// on a full chan
+	mts = mockSliceBackedDs{}
+	for i := 0; i < 50; i++ {
+		mts.TimeStampers = append(mts.TimeStampers,
+			mockTsData{Tim: simStartTime.Add(time.Hour)})
+	}
+	pb, _ = New("test", simStartTime, simStartTime.Add(2*time.Hour), &mts, 1, nil)
+	pb.tsDataBufSize = 1
+	pb.tsDataChanLen = 1
+	pb.Play()
+	time.Sleep(10 * time.Millisecond)
+	pb.Quit()
+	pb.Wait()
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines after quit run; expected %d", n, before)
+	}
+}
+
+// TestConcurrentControl hammers Pause, Resume and Quit from several
+// goroutines, run with -race. Expect no panic, Wait to return and a
+// quit, stopped playback
+func TestConcurrentControl(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 1000; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * time.Millisecond),
+			Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Hour), &mts, 1, nil)
+	pb.Play()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				switch (g + i) % 3 {
+				case 0:
+					pb.Pause()
+				case 1:
+					pb.Resume()
+				case 2:
+					pb.Quit()
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	pb.Wait()
+
+	// Late calls on a finished run are no-ops
+	pb.Quit()
+	pb.Pause()
+	pb.Resume()
+
+	if !pb.life.idle() {
+		t.Error("run goroutines still running after Wait")
+	}
+	if c := pb.Completion(); c != CompletedQuit {
+		t.Errorf("Completion = %d; expected CompletedQuit", c)
+	}
+	if pb.replayActive || pb.running {
+		t.Errorf("active %t, running %t after Wait; expected false",
+			pb.replayActive, pb.running)
+	}
+}
+
+// TestWaitFinalSend confirms Wait doesn't return until a slow
+// callback for the final value returns, when the data runs out and
+// when quit during the callback
+func TestWaitFinalSend(t *testing.T) {
+	for _, quit := range []bool{false, true} {
+		var mts mockSliceBackedDs
+		simStartTime := time.Now()
+		mts.TimeStampers = []TimeStamper{
+			mockTsData{Tim: simStartTime.Add(10 * time.Millisecond), Val: 1},
+		}
+		pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+
+		var sendDone atomic.Bool
+		sending := make(chan struct{})
+		pb.SendTs = func(ts TimeStamper) error {
+			close(sending)
+			time.Sleep(200 * time.Millisecond)
+			sendDone.Store(true)
+			return nil
+		}
+
+		pb.Play()
+		if quit {
+			<-sending
+			pb.Quit()
+		}
+		pb.Wait()
+
+		if !sendDone.Load() {
+			t.Errorf("quit %t: Wait returned before the final SendTs", quit)
+		}
+	}
+}
+
+// TestSetBufferSizes confirms new sizes are refused mid run and
+// used by the next run
+func TestSetBufferSizes(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 6; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * time.Millisecond),
+			Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+
+	if err := pb.SetBufferSizes(0, 1); err == nil {
+		t.Error("SetBufferSizes(0, 1) nil error; expected an error")
+	}
+
+	pb.Play()
+	if err := pb.SetBufferSizes(2, 3); err == nil {
+		t.Error("SetBufferSizes while running nil error; expected an error")
+	}
+	pb.Wait()
+
+	// Default 500 value buffers, all six in one
+	if sends := pb.ContentionStats().LoaderSends; sends != 1 {
+		t.Errorf("first run loader sends %d; expected 1", sends)
+	}
+
+	if err := pb.SetBufferSizes(2, 3); err != nil {
+		t.Fatal(err)
+	}
+	mts.idx = 0
+	pb.Play()
+	if cap(pb.tsDataChan) != 3 {
+		t.Errorf("data chan len %d; expected 3", cap(pb.tsDataChan))
+	}
+	pb.Wait()
+
+	if sends := pb.ContentionStats().LoaderSends; sends != 3 {
+		t.Errorf("second run loader sends %d; expected 3", sends)
+	}
+}
+
+// TestIsIdle confirms a PlayBack is idle before Play, busy through
+// the run and after quit until Wait returns, and idle after
+func TestIsIdle(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(50 * time.Millisecond), Val: 1},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+
+	if !pb.IsIdle() {
+		t.Error("IsIdle false before Play; expected true")
+	}
+
+	// Hold the run in the callback
+	release := make(chan struct{})
+	sending := make(chan struct{})
+	pb.SendTs = func(ts TimeStamper) error {
+		close(sending)
+		<-release
+		return nil
+	}
+	pb.Play()
+	if pb.IsIdle() {
+		t.Error("IsIdle true while running; expected false")
+	}
+
+	<-sending
+	pb.Quit()
+	if pb.IsIdle() {
+		t.Error("IsIdle true after Quit before Wait; expected false")
+	}
+
+	close(release)
+	pb.Wait()
+	if !pb.IsIdle() {
+		t.Error("IsIdle false after Wait; expected true")
+	}
+
+	// Idle is safe to reconfigure
+	if err := pb.SetBufferSizes(10, 2); err != nil {
+		t.Errorf("SetBufferSizes when idle: %v", err)
+	}
+}
+
+// TestCancellableSource quits while the loader is blocked in the
+// source's NextContext, Quit cancels it and Wait returns
+func TestCancellableSource(t *testing.T) {
+	simStartTime := time.Now()
+	src := &mockCancellableDs{tim: simStartTime}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), src, 1, nil)
+	if err := pb.SetBufferSizes(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	sent := make(chan struct{})
+	pb.SendTs = func(ts TimeStamper) error {
+		close(sent)
+		return nil
+	}
+	pb.Play()
+	<-sent
+	pb.Quit()
+
+	waited := make(chan struct{})
+	go func() {
+		pb.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("Wait blocked on the source")
+	}
+	if !pb.IsIdle() {
+		t.Error("Not idle after Wait; expected the loader gone")
+	}
+}
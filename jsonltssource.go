@@ -0,0 +1,139 @@
+package gopeat
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"time"
+)
+
+// errJSONLNoStartTime is the JSONLTsSource error when Next is called
+// before the start time is set
+var errJSONLNoStartTime = errors.New("jsonlTsSource: start time not set")
+
+// JSONToTs converts a line of JSON to a TimeStamper value. Like
+// CsvToTs it returns the value and a nil error for a line to play, a
+// nil value and a nil error for a line to skip, and an error for a
+// line that stops the source.
+type JSONToTs func([]byte) (TimeStamper, error)
+
+// JSONLTsSource implements a time stamped data source for newline
+// delimited JSON, one value per line. Client must provide JSONToTs to
+// convert a line to a timestamper value, for example with
+// json.Unmarshal. Blank lines are skipped.
+//
+// As with CsvTsSource, records before the start time are skipped and
+// the first record after the end time ends the source. MaxRecs above
+// zero ends the source after that many records.
+type JSONLTsSource struct {
+	Symbol     string
+	JSONStream io.Reader
+	JSONToTs   JSONToTs
+	MaxRecs    int64
+
+	reader    *bufio.Reader
+	startTime time.Time
+	endTime   time.Time
+	recCount  int64
+	skipCount int64
+	err       error
+
+	// done is set once Next has run out, later calls stay done
+	done bool
+}
+
+// Next implements an iterator for the JSON lines in the bracket. Once
+// Next returns false, on the end of the data, MaxRecs or an error, it
+// keeps returning false.
+func (st *JSONLTsSource) Next() (TimeStamper, bool) {
+	if st.done {
+		return nil, false
+	}
+	if st.startTime.IsZero() {
+		st.err, st.done = errJSONLNoStartTime, true
+		return nil, false
+	}
+	ts, ok := st.next()
+	st.done = !ok
+	return ts, ok
+}
+
+// next reads the next value in the bracket
+func (st *JSONLTsSource) next() (TimeStamper, bool) {
+	if st.MaxRecs > 0 && st.recCount >= st.MaxRecs {
+		return nil, false
+	}
+	if st.reader == nil {
+		st.reader = bufio.NewReader(st.JSONStream)
+	}
+	for {
+		line, err := st.reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			st.err = err
+			return nil, false
+		}
+		eof := err == io.EOF
+
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			ts, err := st.JSONToTs(line)
+			if err != nil {
+				st.err = err
+				return nil, false
+			}
+			switch {
+			case ts == nil:
+				// Skipped by the converter
+			case ts.GetTimeStamp().Before(st.startTime):
+				st.skipCount++
+			case !st.endTime.IsZero() && ts.GetTimeStamp().After(st.endTime):
+				return nil, false
+			default:
+				st.recCount++
+				return ts, true
+			}
+		}
+		if eof {
+			return nil, false
+		}
+	}
+}
+
+// Err returns the read or conversion error that stopped Next, nil if
+// Next stopped at the end of the data
+func (st *JSONLTsSource) Err() error {
+	return st.err
+}
+
+// Skipped returns the count of records skipped for being before the
+// start time, see SkipCounter
+func (st *JSONLTsSource) Skipped() int64 {
+	return st.skipCount
+}
+
+// Reset seeks the JSON data back to the beginning, the JSONStream must
+// be an io.Seeker. See Resettable
+func (st *JSONLTsSource) Reset() error {
+	seeker, ok := st.JSONStream.(io.Seeker)
+	if !ok {
+		return errors.New("jsonlTsSource: JSONStream can't seek")
+	}
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	st.reader = nil
+	st.recCount, st.skipCount = 0, 0
+	st.err, st.done = nil, false
+	return nil
+}
+
+// SetStartTime sets min timpstamp for data provided
+func (st *JSONLTsSource) SetStartTime(startTime time.Time) {
+	st.startTime = startTime
+}
+
+// SetEndTime sets max timpstamp for data provided
+func (st *JSONLTsSource) SetEndTime(endTime time.Time) {
+	st.endTime = endTime
+}
@@ -0,0 +1,77 @@
+package gopeat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// jsonlConv converts {"t": ..., "v": ...} lines, skipping "skip" ones
+func jsonlConv(line []byte) (TimeStamper, error) {
+	var ev struct {
+		T    time.Time `json:"t"`
+		V    int64     `json:"v"`
+		Skip bool      `json:"skip"`
+	}
+	if err := json.Unmarshal(line, &ev); err != nil {
+		return nil, err
+	}
+	if ev.Skip {
+		return nil, nil
+	}
+	return mockTsData{Tim: ev.T, Val: ev.V}, nil
+}
+
+// jsonlData returns n JSON lines a second apart from start, with a
+// blank and a skipped line after the first
+func jsonlData(start time.Time, n int) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&sb, `{"t": %q, "v": %d}`+"\n",
+			start.Add(time.Duration(i)*time.Second).Format(time.RFC3339Nano), i)
+		if i == 1 {
+			sb.WriteString("\n{\"skip\": true}\n")
+		}
+	}
+	return sb.String()
+}
+
+// TestJSONLTsSource confirms the lines before the start are skipped,
+// the first line after the end ends the source, and MaxRecs and bad
+// JSON stop it
+func TestJSONLTsSource(t *testing.T) {
+	start := time.Now()
+	st := &JSONLTsSource{JSONStream: strings.NewReader(jsonlData(start, 10)),
+		JSONToTs: jsonlConv}
+	pb, _ := New("test", start.Add(3*time.Second), start.Add(6*time.Second), st, 1000, nil)
+	var vals []int64
+	pb.SendTs = func(ts TimeStamper) error {
+		vals = append(vals, ts.(mockTsData).Val)
+		return nil
+	}
+	res := pb.Run()
+	if fmt.Sprint(vals) != "[3 4 5 6]" || res.Skipped != 2 || res.Completion != CompletedOK {
+		t.Errorf("Got %v skipped %d, %v; expected [3 4 5 6] skipped 2, CompletedOK",
+			vals, res.Skipped, res.Completion)
+	}
+
+	st = &JSONLTsSource{JSONStream: strings.NewReader(jsonlData(start, 10)),
+		JSONToTs: jsonlConv, MaxRecs: 2}
+	st.SetStartTime(start)
+	n := 0
+	for _, ok := st.Next(); ok; _, ok = st.Next() {
+		n++
+	}
+	if n != 2 || st.Err() != nil {
+		t.Errorf("MaxRecs 2 got %d, %v; expected 2, nil", n, st.Err())
+	}
+
+	st = &JSONLTsSource{JSONStream: strings.NewReader("{\"t\": oops}\n"),
+		JSONToTs: jsonlConv}
+	st.SetStartTime(start)
+	if _, ok := st.Next(); ok || st.Err() == nil {
+		t.Errorf("Bad JSON Next ok %t, Err %v; expected false, an error", ok, st.Err())
+	}
+}
@@ -0,0 +1,63 @@
+package gopeat
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Goroutine stages of a playback run, teardown waits on them in order
+const (
+	// dataTimer, stops sending to the controller
+	stageTimer = iota
+
+	// loadTimeStampedData, stops reading the source
+	stageLoader
+
+	// Sink goroutines, send what they buffered
+	stageSinks
+
+	numStages
+)
+
+// lifecycle tracks the goroutines the controller spawns for a
+// playback run. The controller tears a run down by waiting on each
+// stage in order, after which no goroutine from the run is left.
+type lifecycle struct {
+	stages    [numStages]sync.WaitGroup
+	abandoned [numStages]atomic.Bool
+	running   atomic.Int32
+}
+
+// spawn runs f on a new goroutine tracked under stage
+func (lc *lifecycle) spawn(stage int, f func()) {
+	lc.stages[stage].Add(1)
+	lc.running.Add(1)
+	go func() {
+		// Not running once teardown sees the stage done
+		defer lc.stages[stage].Done()
+		defer lc.running.Add(-1)
+		f()
+	}()
+}
+
+// abandon has teardown skip waiting on stage's goroutines, they are
+// stuck and still counted as running
+func (lc *lifecycle) abandon(stage int) {
+	lc.abandoned[stage].Store(true)
+}
+
+// teardown blocks until the goroutines of every stage not abandoned
+// are done, stage by stage
+func (lc *lifecycle) teardown() {
+	for i := range lc.stages {
+		if lc.abandoned[i].Swap(false) {
+			continue
+		}
+		lc.stages[i].Wait()
+	}
+}
+
+// idle reports if no tracked goroutine is running
+func (lc *lifecycle) idle() bool {
+	return lc.running.Load() == 0
+}
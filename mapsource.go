@@ -0,0 +1,51 @@
+package gopeat
+
+import "context"
+
+// MappedSource is a time stamped data source that provides a source's
+// values converted by a func, see MapSource
+type MappedSource struct {
+	sourceWrap
+	fn  func(TimeStamper) (TimeStamper, error)
+	err error
+}
+
+// MapSource returns a source that provides src's values converted by
+// fn, for example to an internal type or with timestamps rounded to
+// the millisecond. Playback paces on the GetTimeStamp of the values fn
+// returns, so fn may alter timing, it must keep them timestamp ordered.
+// The time bracket applies to src's values, before fn. An error from
+// fn ends the source, playback completes with it, see Err.
+func MapSource(src TimeStampSource, fn func(TimeStamper) (TimeStamper, error)) *MappedSource {
+	return &MappedSource{sourceWrap: sourceWrap{src: src}, fn: fn}
+}
+
+// Next implements an iterator for the converted values
+func (ms *MappedSource) Next() (TimeStamper, bool) {
+	return ms.NextContext(context.Background())
+}
+
+// NextContext is Next, reading a CancellableSource with ctx, see
+// CancellableSource
+func (ms *MappedSource) NextContext(ctx context.Context) (TimeStamper, bool) {
+	if ms.err != nil {
+		return nil, false
+	}
+	tsData, ok := ms.next(ctx)
+	if !ok || tsData == nil {
+		return tsData, ok
+	}
+	if tsData, ms.err = ms.fn(tsData); ms.err != nil {
+		return nil, false
+	}
+	return tsData, true
+}
+
+// Err returns the fn error that ended the source, or the source's
+// error, see ErrSource
+func (ms *MappedSource) Err() error {
+	if ms.err != nil {
+		return ms.err
+	}
+	return ms.sourceWrap.Err()
+}
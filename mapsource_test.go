@@ -0,0 +1,61 @@
+package gopeat
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestMapSource plays a source with every timestamp shifted an hour
+// on, in order, on an external clock past the shift so nothing sleeps,
+// then a fn error ends the run
+func TestMapSource(t *testing.T) {
+	simStartTime := time.Now()
+	shift := func(ts TimeStamper) (TimeStamper, error) {
+		md := ts.(mockTsData)
+		md.Tim = md.Tim.Add(time.Hour)
+		return md, nil
+	}
+	ms := MapSource(pipelineSource(simStartTime, 10, time.Millisecond), shift)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), ms, 1, nil)
+	clock := make(chan time.Time, 1)
+	clock <- simStartTime.Add(2 * time.Hour)
+	pb.ExternalClock = clock
+
+	var tims []time.Time
+	pb.SendTs = func(ts TimeStamper) error {
+		tims = append(tims, ts.GetTimeStamp())
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+	if len(tims) != 10 {
+		t.Fatalf("Got %d values; expected 10", len(tims))
+	}
+	for i, tim := range tims {
+		expected := simStartTime.Add(time.Hour + time.Duration(i+1)*time.Millisecond)
+		if !tim.Equal(expected) {
+			t.Errorf("Value %d at %v; expected %v", i, tim, expected)
+		}
+	}
+
+	errBad := errors.New("bad value")
+	ms = MapSource(pipelineSource(simStartTime, 10, time.Millisecond),
+		func(ts TimeStamper) (TimeStamper, error) {
+			if ts.(mockTsData).Val == 5 {
+				return nil, errBad
+			}
+			return ts, nil
+		})
+	pb, _ = New("test", simStartTime, simStartTime.Add(time.Minute), ms, 1, nil)
+	sent := 0
+	pb.SendTs = func(ts TimeStamper) error {
+		sent++
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+	if sent != 4 || !errors.Is(pb.Err(), errBad) {
+		t.Errorf("Sent %d, Err %v; expected 4, %v", sent, pb.Err(), errBad)
+	}
+}
@@ -0,0 +1,125 @@
+package gopeat
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// MergedSource is a time stamped data source that interleaves sources
+// into one timestamp ordered stream, see MergeSources
+type MergedSource struct {
+	sources []sourceWrap
+	primed  int
+	refill  bool
+	heads   mergeHeads
+}
+
+// MergeSources returns a source that provides the records of all the
+// sources, each time ordered itself, as one time ordered stream, for
+// example trades and quotes from separate files. Records with equal
+// timestamps are provided in sources order. A source that runs out
+// drops out of the merge, the rest carry on.
+func MergeSources(sources ...TimeStampSource) *MergedSource {
+	ms := &MergedSource{sources: make([]sourceWrap, len(sources))}
+	for i, src := range sources {
+		ms.sources[i].src = src
+	}
+	return ms
+}
+
+// mergeHead is a source's next record in the merge
+type mergeHead struct {
+	tsData TimeStamper
+	src    int
+}
+
+// mergeHeads is a min heap of the heads by timestamp then source
+type mergeHeads []mergeHead
+
+func (mh mergeHeads) Len() int { return len(mh) }
+
+func (mh mergeHeads) Less(i, j int) bool {
+	ti, tj := mh[i].tsData.GetTimeStamp(), mh[j].tsData.GetTimeStamp()
+	if ti.Equal(tj) {
+		return mh[i].src < mh[j].src
+	}
+	return ti.Before(tj)
+}
+
+func (mh mergeHeads) Swap(i, j int) { mh[i], mh[j] = mh[j], mh[i] }
+
+func (mh *mergeHeads) Push(x any) { *mh = append(*mh, x.(mergeHead)) }
+
+func (mh *mergeHeads) Pop() any {
+	old := *mh
+	head := old[len(old)-1]
+	*mh = old[:len(old)-1]
+	return head
+}
+
+// Next implements an iterator for the merged records, the earliest
+// head of the sources
+func (ms *MergedSource) Next() (TimeStamper, bool) {
+	return ms.NextContext(context.Background())
+}
+
+// NextContext is Next, reading a CancellableSource with ctx, see
+// CancellableSource. A nil record from a source is passed on as is,
+// the source's head is read again on the next call.
+func (ms *MergedSource) NextContext(ctx context.Context) (TimeStamper, bool) {
+	// Read each source's first record into the heads
+	for ; ms.primed < len(ms.sources); ms.primed++ {
+		tsData, ok := ms.sources[ms.primed].next(ctx)
+		if ok && tsData == nil {
+			return nil, true
+		}
+		if ok {
+			heap.Push(&ms.heads, mergeHead{tsData: tsData, src: ms.primed})
+		}
+	}
+
+	// Replace the head sent last with its source's next record
+	if ms.refill {
+		tsData, ok := ms.sources[ms.heads[0].src].next(ctx)
+		if ok && tsData == nil {
+			return nil, true
+		}
+		if ok {
+			ms.heads[0].tsData = tsData
+			heap.Fix(&ms.heads, 0)
+		} else {
+			heap.Pop(&ms.heads)
+		}
+		ms.refill = false
+	}
+	if len(ms.heads) == 0 {
+		return nil, false
+	}
+	ms.refill = true
+	return ms.heads[0].tsData, true
+}
+
+// Err returns the first source error, see ErrSource
+func (ms *MergedSource) Err() error {
+	for i := range ms.sources {
+		if err := ms.sources[i].Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetStartTime sets min timpstamp for data provided
+func (ms *MergedSource) SetStartTime(startTime time.Time) {
+	for i := range ms.sources {
+		ms.sources[i].SetStartTime(startTime)
+	}
+}
+
+// SetEndTime sets max timpstamp for data provided
+func (ms *MergedSource) SetEndTime(endTime time.Time) {
+	for i := range ms.sources {
+		ms.sources[i].SetEndTime(endTime)
+	}
+}
@@ -0,0 +1,90 @@
+package gopeat
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestMergeSources merges trades and quotes with interleaved
+// timestamps, ties go in sources order and the merge carries on after
+// the shorter source runs out
+func TestMergeSources(t *testing.T) {
+	start := time.Now()
+	rec := func(ms int, val int64) TimeStamper {
+		return mockTsData{Tim: start.Add(time.Duration(ms) * time.Millisecond), Val: val}
+	}
+	trades := &mockSliceBackedDs{TimeStampers: []TimeStamper{
+		rec(1, 1), rec(3, 3), rec(5, 5), rec(5, 6),
+	}}
+	quotes := &mockSliceBackedDs{TimeStampers: []TimeStamper{
+		rec(2, 20), rec(3, 30), rec(4, 40), rec(7, 70), rec(8, 80),
+	}}
+	ms := MergeSources(trades, quotes)
+
+	var vals []int64
+	for ts, ok := ms.Next(); ok; ts, ok = ms.Next() {
+		vals = append(vals, ts.(mockTsData).Val)
+	}
+	expected := "[1 20 3 30 40 5 6 70 80]"
+	if fmt.Sprint(vals) != expected {
+		t.Errorf("Got %v; expected %s", vals, expected)
+	}
+	if _, ok := ms.Next(); ok {
+		t.Error("Next ok after the merge ran out")
+	}
+}
+
+// TestMergeSourcesBracket plays a merge with a source that doesn't
+// bracket itself, the merge skips its values outside the bracket
+func TestMergeSourcesBracket(t *testing.T) {
+	start := time.Now()
+	rec := func(ms int, val int64) TimeStamper {
+		return mockTsData{Tim: start.Add(time.Duration(ms) * time.Millisecond), Val: val}
+	}
+	bracketed := pipelineSource(start, 3, 10*time.Millisecond)
+	nextOnly := &mockNextOnlyDs{TimeStampers: []TimeStamper{
+		rec(-5, -5), rec(15, 15), rec(25, 25), rec(45, 45),
+	}}
+	pb, _ := New("test", start, start.Add(40*time.Millisecond),
+		MergeSources(bracketed, nextOnly), 1, nil)
+
+	var vals []int64
+	pb.SendTs = func(ts TimeStamper) error {
+		vals = append(vals, ts.(mockTsData).Val)
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+
+	expected := "[1 15 2 25 3]"
+	if fmt.Sprint(vals) != expected {
+		t.Errorf("Got %v; expected %s", vals, expected)
+	}
+}
+
+// TestMergeSourcesNil confirms a nil record from a source is passed
+// on as is and the merge carries on
+func TestMergeSourcesNil(t *testing.T) {
+	start := time.Now()
+	rec := func(ms int, val int64) TimeStamper {
+		return mockTsData{Tim: start.Add(time.Duration(ms) * time.Millisecond), Val: val}
+	}
+	a := &mockNextOnlyDs{TimeStampers: []TimeStamper{nil, rec(1, 1), rec(3, 3)}}
+	b := &mockSliceBackedDs{TimeStampers: []TimeStamper{rec(2, 2), nil, rec(4, 4)}}
+	ms := MergeSources(a, b)
+
+	// 0 stands for a nil record
+	var vals []int64
+	for ts, ok := ms.Next(); ok; ts, ok = ms.Next() {
+		if ts == nil {
+			vals = append(vals, 0)
+			continue
+		}
+		vals = append(vals, ts.(mockTsData).Val)
+	}
+	expected := "[0 1 2 0 3 4]"
+	if fmt.Sprint(vals) != expected {
+		t.Errorf("Got %v; expected %s", vals, expected)
+	}
+}
@@ -0,0 +1,54 @@
+package gopeat
+
+// Metadataer is implemented by TimeStamper values that carry tags,
+// a symbol or venue for example. Routing and classification read the
+// tags through it so they don't need the client's concrete types.
+type Metadataer interface {
+	Metadata() map[string]string
+}
+
+// MetadataOf returns the tags of tsData, looking through playback's
+// own wrappers like RawTs and RetimedTs. Values that don't implement
+// Metadataer have no tags, nil is returned.
+func MetadataOf(tsData TimeStamper) map[string]string {
+	switch ts := tsData.(type) {
+	case Metadataer:
+		return ts.Metadata()
+	case RawTs:
+		return MetadataOf(ts.TimeStamper)
+	case RetimedTs:
+		return MetadataOf(ts.TimeStamper)
+	case PreviewTs:
+		return MetadataOf(ts.TimeStamper)
+	case ReplayTs:
+		return MetadataOf(ts.TimeStamper)
+	}
+	return nil
+}
+
+// RouteBy returns a SendTs callback that sends each value to the
+// route for its key tag. Values without a route for their tag, or
+// without the tag, go to fallback, which may be nil to drop them.
+func RouteBy(key string, routes map[string]OnTsDataReady,
+	fallback OnTsDataReady) OnTsDataReady {
+	return func(ts TimeStamper) error {
+		if send, ok := routes[MetadataOf(ts)[key]]; ok {
+			return send(ts)
+		}
+		if fallback != nil {
+			return fallback(ts)
+		}
+		return nil
+	}
+}
+
+// ClassifyBy returns a Classifier that assigns each value the Class
+// for its key tag, values without a Class for their tag get def
+func ClassifyBy(key string, classes map[string]Class, def Class) func(TimeStamper) Class {
+	return func(ts TimeStamper) Class {
+		if c, ok := classes[MetadataOf(ts)[key]]; ok {
+			return c
+		}
+		return def
+	}
+}
@@ -0,0 +1,81 @@
+package gopeat
+
+import (
+	"testing"
+	"time"
+)
+
+// mockTaggedTs is time stamped data with tags
+type mockTaggedTs struct {
+	mockTsData
+	tags map[string]string
+}
+
+func (m mockTaggedTs) Metadata() map[string]string {
+	return m.tags
+}
+
+// TestRouteByMetadata confirms values are routed by a metadata tag
+// without the router knowing the value type
+func TestRouteByMetadata(t *testing.T) {
+	simStartTime := time.Now()
+	tagged := func(n int, sym string) TimeStamper {
+		return mockTaggedTs{
+			mockTsData: mockTsData{
+				Tim: simStartTime.Add(time.Duration(n) * time.Millisecond),
+				Val: int64(n)},
+			tags: map[string]string{"symbol": sym, "venue": "X"},
+		}
+	}
+	var mts mockSliceBackedDs
+	mts.TimeStampers = []TimeStamper{
+		tagged(1, "ES"),
+		tagged(2, "NQ"),
+		tagged(3, "ES"),
+		mockTsData{Tim: simStartTime.Add(4 * time.Millisecond), Val: 4},
+		tagged(5, "CL"),
+	}
+
+	got := map[string][]int64{}
+	route := func(name string) OnTsDataReady {
+		return func(ts TimeStamper) error {
+			switch v := ts.(type) {
+			case mockTaggedTs:
+				got[name] = append(got[name], v.Val)
+			case mockTsData:
+				got[name] = append(got[name], v.Val)
+			}
+			return nil
+		}
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+	pb.SendTs = RouteBy("symbol", map[string]OnTsDataReady{
+		"ES": route("ES"),
+		"NQ": route("NQ"),
+	}, route("other"))
+
+	pb.Play()
+	pb.Wait()
+
+	expected := map[string][]int64{"ES": {1, 3}, "NQ": {2}, "other": {4, 5}}
+	for name, vals := range expected {
+		if len(got[name]) != len(vals) {
+			t.Fatalf("route %s got %v; expected %v", name, got[name], vals)
+		}
+		for i, v := range vals {
+			if got[name][i] != v {
+				t.Errorf("route %s got %v; expected %v", name, got[name], vals)
+			}
+		}
+	}
+
+	// Tags are found through playback's wrappers
+	wrapped := RawTs{TimeStamper: retime(tagged(6, "NQ"), simStartTime)}
+	if sym := MetadataOf(wrapped)["symbol"]; sym != "NQ" {
+		t.Errorf("wrapped symbol %q; expected NQ", sym)
+	}
+	classify := ClassifyBy("symbol", map[string]Class{"NQ": ClassBulk}, ClassCritical)
+	if c := classify(wrapped); c != ClassBulk {
+		t.Errorf("wrapped class %d; expected ClassBulk", c)
+	}
+}
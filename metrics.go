@@ -0,0 +1,51 @@
+package gopeat
+
+import "time"
+
+// Metric names a PlayBack reports to its MetricsSink
+const (
+	// MetricDrift gauge is the drift of the latest send in seconds,
+	// see RunTiming.DriftDur
+	MetricDrift = "gopeat_drift_seconds"
+
+	// MetricRecordsSent counter counts the values sent
+	MetricRecordsSent = "gopeat_records_sent_total"
+
+	// MetricBufferFill gauge is the count of read ahead buffers
+	// loaded and waiting to be paced at the latest send
+	MetricBufferFill = "gopeat_buffer_fill"
+
+	// MetricPauseTime counter is the wall time paused in seconds,
+	// counted as each pause ends
+	MetricPauseTime = "gopeat_pause_seconds_total"
+)
+
+// MetricsSink receives a playback's metrics as they change, for
+// example to expose them for scraping, see the promsink package for
+// Prometheus. symbol is the PlayBack's Symbol, so one MetricsSink can
+// serve several playbacks. The metrics are reported from the
+// playback's goroutines, a MetricsSink shared by playbacks must be
+// safe for concurrent use and should return quickly, it runs on the
+// send path.
+type MetricsSink interface {
+	SetGauge(name, symbol string, value float64)
+	AddCounter(name, symbol string, delta float64)
+}
+
+// sendMetrics reports the metrics of a send timed rt
+func (pb *PlayBack) sendMetrics(rt RunTiming) {
+	if pb.Metrics == nil {
+		return
+	}
+	pb.Metrics.SetGauge(MetricDrift, pb.Symbol, rt.DriftDur.Seconds())
+	pb.Metrics.AddCounter(MetricRecordsSent, pb.Symbol, 1)
+	pb.Metrics.SetGauge(MetricBufferFill, pb.Symbol, float64(len(pb.tsDataChan)))
+}
+
+// pauseMetrics reports a pause of d
+func (pb *PlayBack) pauseMetrics(d time.Duration) {
+	if pb.Metrics == nil {
+		return
+	}
+	pb.Metrics.AddCounter(MetricPauseTime, pb.Symbol, d.Seconds())
+}
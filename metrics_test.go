@@ -0,0 +1,56 @@
+package gopeat
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockMetrics records the metrics reported
+type mockMetrics struct {
+	mu       sync.Mutex
+	gauges   map[string]float64
+	counters map[string]float64
+}
+
+func (mm *mockMetrics) SetGauge(name, symbol string, value float64) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.gauges[symbol+" "+name] = value
+}
+
+func (mm *mockMetrics) AddCounter(name, symbol string, delta float64) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.counters[symbol+" "+name] += delta
+}
+
+// TestMetrics confirms a run with a pause reports every send and the
+// pause time
+func TestMetrics(t *testing.T) {
+	simStartTime := time.Now()
+	ss := pipelineSource(simStartTime, 10, 5*time.Millisecond)
+	pb, _ := New("ES", simStartTime, simStartTime.Add(time.Minute), ss, 1, nil)
+	mm := &mockMetrics{gauges: map[string]float64{}, counters: map[string]float64{}}
+	pb.Metrics = mm
+
+	pb.Play()
+	time.Sleep(10 * time.Millisecond)
+	pb.Pause()
+	time.Sleep(30 * time.Millisecond)
+	pb.Resume()
+	pb.Wait()
+
+	if n := mm.counters["ES "+MetricRecordsSent]; n != 10 {
+		t.Errorf("%s = %f; expected 10", MetricRecordsSent, n)
+	}
+	if p := mm.counters["ES "+MetricPauseTime]; p < 0.025 || p > 0.1 {
+		t.Errorf("%s = %f; expected about 0.03", MetricPauseTime, p)
+	}
+	if _, ok := mm.gauges["ES "+MetricDrift]; !ok {
+		t.Errorf("%s not reported", MetricDrift)
+	}
+	if _, ok := mm.gauges["ES "+MetricBufferFill]; !ok {
+		t.Errorf("%s not reported", MetricBufferFill)
+	}
+}
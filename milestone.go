@@ -0,0 +1,21 @@
+package gopeat
+
+import "time"
+
+// milestones calls OnMilestone for each milestone at reaches. next
+// numbers the next milestone, it's advanced past the ones reached.
+func (pb *PlayBack) milestones(at time.Time, next *int) {
+	frac := pb.MilestoneFraction
+	if pb.OnMilestone == nil || frac <= 0 || frac > 1 {
+		return
+	}
+	span := pb.EndTime.Sub(pb.StartTime)
+	for ; float64(*next)*frac <= 1; *next++ {
+		fraction := float64(*next) * frac
+		simTime := pb.StartTime.Add(time.Duration(fraction * float64(span)))
+		if at.Before(simTime) {
+			return
+		}
+		pb.OnMilestone(fraction, simTime)
+	}
+}
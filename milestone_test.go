@@ -0,0 +1,56 @@
+package gopeat
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestMilestones confirms OnMilestone fires at each 10% of the sim
+// time span, once per milestone even when a gap crosses several
+func TestMilestones(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for _, ms := range []int{5, 15, 25, 65, 95, 100} {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(ms) * time.Millisecond),
+			Val: int64(ms)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(100*time.Millisecond),
+		&mts, 1, nil)
+	pb.MilestoneFraction = 0.1
+
+	var lastSent int64
+	pb.SendTs = func(ts TimeStamper) error {
+		lastSent = ts.(mockTsData).Val
+		return nil
+	}
+	type milestone struct {
+		fraction float64
+		sentAt   int64
+	}
+	var got []milestone
+	pb.OnMilestone = func(fraction float64, simTime time.Time) {
+		exp := simStartTime.Add(time.Duration(fraction * float64(100*time.Millisecond)))
+		if !simTime.Equal(exp) {
+			t.Errorf("milestone %f simTime %v; expected %v", fraction, simTime, exp)
+		}
+		got = append(got, milestone{fraction, lastSent})
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	// The value sent when each milestone is reached
+	expected := []milestone{{0.1, 15}, {0.2, 25}, {0.3, 65}, {0.4, 65},
+		{0.5, 65}, {0.6, 65}, {0.7, 95}, {0.8, 95}, {0.9, 95}, {1, 100}}
+	if len(got) != len(expected) {
+		t.Fatalf("Got %v; expected %v", got, expected)
+	}
+	for i := range expected {
+		if math.Abs(got[i].fraction-expected[i].fraction) > 1e-9 ||
+			got[i].sentAt != expected[i].sentAt {
+			t.Errorf("milestone %d %v; expected %v", i, got[i], expected[i])
+		}
+	}
+}
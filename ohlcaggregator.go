@@ -0,0 +1,115 @@
+package gopeat
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// Bar is an OHLC bar of trade prices. A Bar's timestamp is its
+// close time, End, so playback sends it as the bar closes.
+type Bar struct {
+	Start time.Time
+	End   time.Time
+	Open  float64
+	High  float64
+	Low   float64
+	Close float64
+
+	// Count of trades in the bar, 0 for an empty bar which carries
+	// the previous bar's close as all four prices
+	Count int
+}
+
+// GetTimeStamp returns the bar close time
+func (b Bar) GetTimeStamp() time.Time {
+	return b.End
+}
+
+// OHLCAggregator is a time stamped data source that aggregates the
+// trades from another source into OHLC Bars, see NewOHLCAggregator
+type OHLCAggregator struct {
+	sourceWrap
+	barDur time.Duration
+	price  func(TimeStamper) float64
+
+	started   bool
+	barStart  time.Time
+	pending   TimeStamper
+	lastClose float64
+}
+
+// NewOHLCAggregator returns a source that aggregates src's trades
+// into OHLC Bars. Bars are barDur long and aligned to barDur
+// boundaries, for example one minute bars start on the minute. price
+// returns the trade price of src's values. Bars with no trades after
+// the first trade are sent as empty bars. barDur must be greater
+// than 0.
+func NewOHLCAggregator(src TimeStampSource, barDur time.Duration,
+	price func(TimeStamper) float64) (*OHLCAggregator, error) {
+	if barDur <= 0 {
+		return nil, errors.New("playBack: bar duration must be greater than 0")
+	}
+	return &OHLCAggregator{sourceWrap: sourceWrap{src: src}, barDur: barDur,
+		price: price}, nil
+}
+
+// Next implements an iterator for the bars
+func (ag *OHLCAggregator) Next() (TimeStamper, bool) {
+	return ag.NextContext(context.Background())
+}
+
+// NextContext is Next, returning false once ctx is done, see
+// CancellableSource. A bar is built by reading the source until a
+// trade past the bar's end shows up.
+func (ag *OHLCAggregator) NextContext(ctx context.Context) (TimeStamper, bool) {
+	if !ag.started {
+		ag.started = true
+		ag.pending = ag.nextTrade(ctx)
+		if ag.pending != nil {
+			ag.barStart = ag.pending.GetTimeStamp().Truncate(ag.barDur)
+		}
+	}
+	if ag.pending == nil {
+		return nil, false
+	}
+
+	bar := Bar{Start: ag.barStart, End: ag.barStart.Add(ag.barDur)}
+	for ag.pending != nil && ag.pending.GetTimeStamp().Before(bar.End) {
+		price := ag.price(ag.pending)
+		if bar.Count == 0 {
+			bar.Open, bar.High, bar.Low = price, price, price
+		}
+		bar.High = math.Max(bar.High, price)
+		bar.Low = math.Min(bar.Low, price)
+		bar.Close = price
+		bar.Count++
+
+		ag.pending = ag.nextTrade(ctx)
+	}
+
+	// Cut short, not a whole bar
+	if ctx.Err() != nil {
+		return nil, false
+	}
+
+	// No trades, carry the last close
+	if bar.Count == 0 {
+		bar.Open, bar.High, bar.Low, bar.Close =
+			ag.lastClose, ag.lastClose, ag.lastClose, ag.lastClose
+	}
+	ag.lastClose = bar.Close
+	ag.barStart = bar.End
+	return bar, true
+}
+
+// nextTrade reads the next trade from the source, nil when the
+// source has no more trades
+func (ag *OHLCAggregator) nextTrade(ctx context.Context) TimeStamper {
+	trade, ok := ag.next(ctx)
+	if !ok {
+		return nil
+	}
+	return trade
+}
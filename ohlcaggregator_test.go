@@ -0,0 +1,104 @@
+package gopeat
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestOHLCAggregator builds bars from trades, an empty bar carries the
+// last close and bars are sent as they close
+func TestOHLCAggregator(t *testing.T) {
+	simStartTime := time.Date(2013, 9, 3, 10, 0, 0, 0, time.UTC)
+	ms := func(n int) time.Time {
+		return simStartTime.Add(time.Duration(n) * time.Millisecond)
+	}
+
+	// Trades with prices in Val, none in the 200-300ms bar
+	var mts mockSliceBackedDs
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: ms(10), Val: 100},
+		mockTsData{Tim: ms(30), Val: 105},
+		mockTsData{Tim: ms(50), Val: 99},
+		mockTsData{Tim: ms(120), Val: 101},
+		mockTsData{Tim: ms(320), Val: 103},
+	}
+	ag, err := NewOHLCAggregator(&mts, 100*time.Millisecond, func(ts TimeStamper) float64 {
+		return float64(ts.(mockTsData).Val)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, _ := New("test", simStartTime, ms(500), ag, 1, nil)
+
+	var bars []Bar
+	var sendDurs []time.Duration
+	pb.SendTs = func(ts TimeStamper) error {
+		sendDurs = append(sendDurs, time.Since(pb.WallStartTime))
+		bars = append(bars, ts.(Bar))
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	expected := []Bar{
+		{Start: ms(0), End: ms(100), Open: 100, High: 105, Low: 99, Close: 99, Count: 3},
+		{Start: ms(100), End: ms(200), Open: 101, High: 101, Low: 101, Close: 101, Count: 1},
+		{Start: ms(200), End: ms(300), Open: 101, High: 101, Low: 101, Close: 101, Count: 0},
+		{Start: ms(300), End: ms(400), Open: 103, High: 103, Low: 103, Close: 103, Count: 1},
+	}
+	if len(bars) != len(expected) {
+		t.Fatalf("Got %d bars; expected %d", len(bars), len(expected))
+	}
+	for i, bar := range bars {
+		if bar != expected[i] {
+			t.Errorf("bar %d = %+v; expected %+v", i, bar, expected[i])
+		}
+
+		// Bars are sent as they close
+		d := (sendDurs[i] - bar.End.Sub(simStartTime)).Seconds() * 1000
+		if math.Abs(d) > 3 {
+			t.Errorf("bar %d Time = %f(ms); want less than 3(ms)", i, d)
+		}
+	}
+}
+
+// TestOHLCAggregatorBracket confirms the trades of a source that
+// doesn't bracket itself are kept to the playback bracket, and a bar
+// duration of 0 is rejected
+func TestOHLCAggregatorBracket(t *testing.T) {
+	simStartTime := time.Date(2013, 9, 3, 10, 0, 0, 0, time.UTC)
+	price := func(ts TimeStamper) float64 {
+		return float64(ts.(mockTsData).Val)
+	}
+	if _, err := NewOHLCAggregator(&mockNextOnlyDs{}, 0, price); err == nil {
+		t.Error("bar duration 0 accepted; expected error")
+	}
+
+	src := &mockNextOnlyDs{TimeStampers: []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(-time.Hour), Val: 50},
+		mockTsData{Tim: simStartTime.Add(10 * time.Millisecond), Val: 100},
+		mockTsData{Tim: simStartTime.Add(20 * time.Millisecond), Val: 101},
+		mockTsData{Tim: simStartTime.Add(time.Hour), Val: 150},
+	}}
+	ag, _ := NewOHLCAggregator(src, 100*time.Millisecond, price)
+	clock := make(chan time.Time, 1)
+	clock <- simStartTime.Add(time.Minute)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), ag, 1, nil)
+	pb.ExternalClock = clock
+
+	var bars []Bar
+	pb.SendTs = func(ts TimeStamper) error {
+		bars = append(bars, ts.(Bar))
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+
+	expected := Bar{Start: simStartTime, End: simStartTime.Add(100 * time.Millisecond),
+		Open: 100, High: 101, Low: 100, Close: 101, Count: 2}
+	if len(bars) != 1 || bars[0] != expected {
+		t.Errorf("Got %+v; expected [%+v]", bars, expected)
+	}
+}
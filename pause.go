@@ -0,0 +1,54 @@
+package gopeat
+
+import "time"
+
+// pauseSpan is a finished pause, start to end in wall time
+type pauseSpan struct {
+	start, end time.Time
+}
+
+// creditPause records a finished pause for the timer to take out of
+// its wall time between sends
+func (pb *PlayBack) creditPause(start, end time.Time) {
+	pb.pauseMu.Lock()
+	pb.pauses = append(pb.pauses, pauseSpan{start, end})
+	pb.pauseMu.Unlock()
+}
+
+// pausedBetween returns the pause time credited that falls between
+// the wall times from and to
+func (pb *PlayBack) pausedBetween(from, to time.Time) time.Duration {
+	pb.pauseMu.RLock()
+	defer pb.pauseMu.RUnlock()
+	var paused time.Duration
+	for _, ps := range pb.pauses {
+		start, end := ps.start, ps.end
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			paused += end.Sub(start)
+		}
+	}
+	return paused
+}
+
+// trimPauses drops the pause time up to the wall time t, the timer
+// has accounted for it. Pause time after t is kept for the next send.
+func (pb *PlayBack) trimPauses(t time.Time) {
+	pb.pauseMu.Lock()
+	defer pb.pauseMu.Unlock()
+	kept := pb.pauses[:0]
+	for _, ps := range pb.pauses {
+		if ps.end.After(t) {
+			if ps.start.Before(t) {
+				ps.start = t
+			}
+			kept = append(kept, ps)
+		}
+	}
+	pb.pauses = kept
+}
@@ -0,0 +1,70 @@
+package gopeat
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestBackToBackPauses confirms two pauses within one record interval
+// are both credited, the second send is late by their total
+func TestBackToBackPauses(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(100 * time.Millisecond), Val: 1},
+		mockTsData{Tim: simStartTime.Add(200 * time.Millisecond), Val: 2},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+
+	var sendDurs []time.Duration
+	pb.SendTs = func(ts TimeStamper) error {
+		sendDurs = append(sendDurs, time.Since(pb.WallStartTime))
+		if ts.(mockTsData).Val == 1 {
+			go func() {
+				for i := 0; i < 2; i++ {
+					pb.Pause()
+					time.Sleep(50 * time.Millisecond)
+					pb.Resume()
+				}
+			}()
+		}
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	expected := []time.Duration{100, 300}
+	if len(sendDurs) != len(expected) {
+		t.Fatalf("Got %d sends; expected %d", len(sendDurs), len(expected))
+	}
+	for i, exp := range expected {
+		d := (sendDurs[i] - exp*time.Millisecond).Seconds() * 1000
+		if math.Abs(d) > 3 {
+			t.Errorf("send %d Time = %f(ms); want less than 3(ms)", i, d)
+		}
+	}
+}
+
+// TestPauseAfterSendCarriesOver confirms pause time credited after a
+// send is kept for the next send instead of reset with the send
+func TestPauseAfterSendCarriesOver(t *testing.T) {
+	var pb PlayBack
+	base := time.Now()
+	at := func(ms int) time.Time {
+		return base.Add(time.Duration(ms) * time.Millisecond)
+	}
+
+	// A pause before the send, then one spanning it
+	pb.creditPause(at(10), at(20))
+	pb.creditPause(at(90), at(130))
+	if paused := pb.pausedBetween(at(0), at(100)); paused != 20*time.Millisecond {
+		t.Errorf("paused %v before the send; expected 20ms", paused)
+	}
+
+	pb.trimPauses(at(100))
+	if paused := pb.pausedBetween(at(100), at(200)); paused != 30*time.Millisecond {
+		t.Errorf("paused %v after the send; expected 30ms", paused)
+	}
+}
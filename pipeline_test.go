@@ -0,0 +1,151 @@
+package gopeat
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pipelineSource returns n values every apart from start
+func pipelineSource(start time.Time, n int, every time.Duration) *SliceTsSource {
+	ss := &SliceTsSource{RequireSorted: true}
+	for i := 1; i <= n; i++ {
+		ss.Values = append(ss.Values, mockTsData{
+			Tim: start.Add(time.Duration(i) * every), Val: int64(i)})
+	}
+	return ss
+}
+
+// pipelineRun plays pb in small buffers so the loader hands the timer
+// many of them, failing if Wait doesn't return or a value is out of
+// order, and returns the values sent
+func pipelineRun(t *testing.T, pb *PlayBack) []int64 {
+	var vals []int64
+	pb.SendTs = func(ts TimeStamper) error {
+		vals = append(vals, ts.(mockTsData).Val)
+		return nil
+	}
+	if err := pb.SetBufferSizes(64, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	waited := make(chan struct{})
+	go func() {
+		pb.Play()
+		pb.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait blocked")
+	}
+
+	if pb.Completion() != CompletedOK || pb.Err() != nil {
+		t.Fatalf("Completion, Err = %v, %v; expected CompletedOK", pb.Completion(), pb.Err())
+	}
+	for i, val := range vals {
+		if val != int64(i+1) {
+			t.Fatalf("Value %d = %d; expected %d", i, val, i+1)
+		}
+	}
+	return vals
+}
+
+// TestPipeline runs 5000 values through the loader, timer and
+// controller slaved to an external clock that's already at the end,
+// so nothing sleeps, and confirms every value arrives in order
+func TestPipeline(t *testing.T) {
+	simStartTime := time.Now()
+	ss := pipelineSource(simStartTime, 5000, time.Second)
+	endTime := simStartTime.Add(time.Hour * 2)
+	pb, _ := New("test", simStartTime, endTime, ss, 1, nil)
+
+	clock := make(chan time.Time, 1)
+	clock <- endTime
+	pb.ExternalClock = clock
+
+	vals := pipelineRun(t, pb)
+	if len(vals) != 5000 {
+		t.Errorf("Got %d values; expected 5000", len(vals))
+	}
+	if n := len(pb.Timings()); n != 5000 {
+		t.Errorf("Timings = %d; expected 5000", n)
+	}
+}
+
+// TestPipelinePaced runs 1000 values 10ms apart at 100x on the real
+// clock, every value arrives in order and the run takes its 100ms
+func TestPipelinePaced(t *testing.T) {
+	simStartTime := time.Now()
+	ss := pipelineSource(simStartTime, 1000, 10*time.Millisecond)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), ss, 100, nil)
+
+	vals := pipelineRun(t, pb)
+	if len(vals) != 1000 {
+		t.Errorf("Got %d values; expected 1000", len(vals))
+	}
+
+	stats := pb.DriftStats()
+	if stats.ExpectedRunDuration != 100*time.Millisecond {
+		t.Errorf("ExpectedRunDuration = %v; expected 100ms", stats.ExpectedRunDuration)
+	}
+	d := (stats.ActualRunDuration - stats.ExpectedRunDuration).Seconds() * 1000
+	if d < -5 || d > 20 {
+		t.Errorf("Run off by %f(ms); want -5 to 20(ms)", d)
+	}
+}
+
+// TestNoPacing streams a day of values a second apart with NoPacing,
+// every value arrives in order with no sleep between sends
+func TestNoPacing(t *testing.T) {
+	simStartTime := time.Now()
+	ss := pipelineSource(simStartTime, 86400, time.Second)
+	pb, _ := New("test", simStartTime, simStartTime.Add(24*time.Hour), ss, 1, nil)
+	pb.NoPacing = true
+	if vals := pipelineRun(t, pb); len(vals) != 86400 {
+		t.Errorf("%d values; expected 86400", len(vals))
+	}
+	for _, rt := range pb.Timings() {
+		if rt.SleepDur != 0 {
+			t.Fatalf("record %d slept %v; expected no sleeps", rt.RecNum, rt.SleepDur)
+		}
+	}
+}
+
+// TestNoPacingControl confirms a NoPacing run pauses, resumes and
+// quits
+func TestNoPacingControl(t *testing.T) {
+	simStartTime := time.Now()
+	ss := pipelineSource(simStartTime, 200000, time.Second)
+	pb, _ := New("test", simStartTime, simStartTime.Add(200000*time.Second), ss, 1, nil)
+	pb.NoPacing = true
+	var sent atomic.Int64
+	pb.SendTs = func(ts TimeStamper) error {
+		sent.Add(1)
+		return nil
+	}
+	pb.Play()
+
+	pb.Pause()
+	pb.sync()
+	held := sent.Load()
+	time.Sleep(20 * time.Millisecond)
+	if cnt := sent.Load(); cnt > held+1 {
+		t.Errorf("%d sends while paused", cnt-held)
+	}
+	pb.Resume()
+	time.Sleep(20 * time.Millisecond)
+	if cnt := sent.Load(); cnt <= held+1 {
+		t.Error("no sends after Resume")
+	}
+
+	pb.Quit()
+	pb.Wait()
+	if c := pb.Completion(); c != CompletedQuit {
+		t.Errorf("Completion = %d; expected CompletedQuit", c)
+	}
+	if !pb.IsIdle() {
+		t.Error("not idle after Wait")
+	}
+}
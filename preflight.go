@@ -0,0 +1,74 @@
+package gopeat
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotCountable is the Preflight error for a source that can't be
+// counted, like a live feed that waits for its values
+var ErrNotCountable = errors.New("playBack: source can't be counted")
+
+// Counter is implemented by a source that counts its values in the
+// time bracket itself, faster than reading them through Next, or
+// can't be counted and returns ErrNotCountable. span is the sim time
+// from the first to the last value counted.
+type Counter interface {
+	Count() (records int64, span time.Duration, err error)
+}
+
+// Preflight returns the count of values in the StartTime to EndTime
+// bracket and the sim time from the first to the last, for example to
+// show an ETA before a long run. No callbacks are called. A Counter
+// source counts itself, any other source is read through to the end
+// and Reset if it's Resettable, otherwise the source is used up and a
+// run after Preflight plays what it read ahead for AutoTune or
+// EstimateRunTime only. Don't preflight a live source that isn't a
+// Counter, the read waits on it.
+func (pb *PlayBack) Preflight() (records int64, span time.Duration, err error) {
+	if !pb.IsIdle() {
+		return 0, 0, errors.New("playBack: can't preflight while running")
+	}
+	if c, ok := pb.TsDataSource.(Counter); ok {
+		return c.Count()
+	}
+
+	// Values read ahead count first
+	var first, last time.Time
+	count := func(tsData TimeStamper) {
+		if tsData == nil {
+			return
+		}
+		records++
+		last = tsData.GetTimeStamp()
+		if records == 1 {
+			first = last
+		}
+	}
+	for _, tsData := range pb.primed {
+		count(tsData)
+	}
+	if !pb.primedDone {
+		for {
+			tsData, ok := pb.sourceNext(context.Background())
+			if !ok {
+				break
+			}
+			count(tsData)
+		}
+	}
+	if es, ok := pb.TsDataSource.(ErrSource); ok && es.Err() != nil {
+		return 0, 0, es.Err()
+	}
+
+	if rs, ok := pb.TsDataSource.(Resettable); ok {
+		if err := rs.Reset(); err != nil {
+			return 0, 0, err
+		}
+		pb.primed, pb.primedDone = nil, false
+	} else {
+		pb.primedDone = true
+	}
+	return records, last.Sub(first), nil
+}
@@ -0,0 +1,72 @@
+package gopeat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPreflight counts a csv source's records in the bracket, then
+// the run still plays them all
+func TestPreflight(t *testing.T) {
+	start := time.Now()
+	var sb strings.Builder
+	sb.WriteString("tim, amt\n")
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&sb, "%s, %d\n", start.Add(time.Duration(i)*time.Second).
+			Format(time.RFC3339Nano), i)
+	}
+	conv := func(csv []string) (TimeStamper, error) {
+		tim, err := time.Parse(time.RFC3339Nano, csv[0])
+		if err != nil {
+			return nil, err
+		}
+		val, err := strconv.ParseInt(strings.TrimSpace(csv[1]), 10, 64)
+		return mockTsData{Tim: tim, Val: val}, err
+	}
+	st := &CsvTsSource{CsvStream: strings.NewReader(sb.String()), CsvTsConv: conv}
+	pb, _ := New("test", start.Add(2*time.Second), start.Add(8*time.Second), st, 1000, nil)
+
+	records, span, err := pb.Preflight()
+	if records != 7 || span != 6*time.Second || err != nil {
+		t.Errorf("Preflight = %d, %v, %v; expected 7, 6s, nil", records, span, err)
+	}
+	if res := pb.Run(); res.Records != 7 {
+		t.Errorf("Run Records = %d; expected 7", res.Records)
+	}
+}
+
+// TestPreflightSources counts a Counter without reading it, refuses a
+// live source and uses up a source that can't be reset
+func TestPreflightSources(t *testing.T) {
+	start := time.Now()
+	ss := pipelineSource(start, 10, time.Second)
+	pb, _ := New("test", start.Add(3*time.Second), start.Add(20*time.Second), ss, 1, nil)
+	records, span, err := pb.Preflight()
+	if records != 8 || span != 7*time.Second || err != nil || ss.idx != 0 {
+		t.Errorf("Slice Preflight = %d, %v, %v read %d; expected 8, 7s, nil read 0",
+			records, span, err, ss.idx)
+	}
+
+	pb, _ = New("test", start, start.Add(time.Minute), &ChanTsSource{}, 1, nil)
+	if _, _, err := pb.Preflight(); err != ErrNotCountable {
+		t.Errorf("Chan Preflight err = %v; expected ErrNotCountable", err)
+	}
+
+	var mts mockNextOnlyDs
+	for i := 1; i <= 5; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: start.Add(time.Duration(i) * time.Millisecond), Val: int64(i)})
+	}
+	pb, _ = New("test", start, start.Add(3*time.Millisecond), &mts, 1, nil)
+	records, span, err = pb.Preflight()
+	if records != 3 || span != 2*time.Millisecond || err != nil {
+		t.Errorf("Next only Preflight = %d, %v, %v; expected 3, 2ms, nil",
+			records, span, err)
+	}
+	if res := pb.Run(); res.Records != 0 {
+		t.Errorf("Run Records = %d; expected 0, source used up", res.Records)
+	}
+}
@@ -0,0 +1,64 @@
+package gopeat
+
+import "time"
+
+// Progress is a snapshot of how far along a run is, see
+// PlayBack.Progress
+type Progress struct {
+	// RecordsSent to the client, and the source timestamp of the last
+	// one, zero until the first send
+	RecordsSent    int64
+	CurrentSimTime time.Time
+
+	// FractionComplete is how far CurrentSimTime is from StartTime to
+	// EndTime, 0 to 1
+	FractionComplete float64
+
+	// ElapsedWall is the wall time since the run started sending,
+	// pauses included, it stops at the end of the run
+	ElapsedWall time.Duration
+}
+
+// Progress returns how far along the current or last run is, for
+// example for a progress bar. Safe to call from any goroutine while
+// the run plays.
+func (pb *PlayBack) Progress() Progress {
+	pb.progressMu.RLock()
+	p := Progress{RecordsSent: pb.sentCnt, CurrentSimTime: pb.sentSimTime}
+	wallStart, wallEnd := pb.progressStart, pb.progressEnd
+	pb.progressMu.RUnlock()
+
+	switch {
+	case wallStart.IsZero():
+	case wallEnd.IsZero():
+		p.ElapsedWall = pb.clock().Now().Sub(wallStart)
+	default:
+		p.ElapsedWall = wallEnd.Sub(wallStart)
+	}
+
+	span := pb.EndTime.Sub(pb.StartTime)
+	if span > 0 && !p.CurrentSimTime.IsZero() {
+		p.FractionComplete = float64(p.CurrentSimTime.Sub(pb.StartTime)) / float64(span)
+		p.FractionComplete = min(max(p.FractionComplete, 0), 1)
+	}
+	return p
+}
+
+// progressSent counts a send of the value at simTime
+func (pb *PlayBack) progressSent(simTime time.Time) {
+	pb.progressMu.Lock()
+	pb.sentCnt++
+	pb.sentSimTime = simTime
+	pb.progressMu.Unlock()
+}
+
+// progressWall marks the wall time the run started sending, or ended
+func (pb *PlayBack) progressWall(wall time.Time, end bool) {
+	pb.progressMu.Lock()
+	if end {
+		pb.progressEnd = wall
+	} else {
+		pb.progressStart = wall
+	}
+	pb.progressMu.Unlock()
+}
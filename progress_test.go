@@ -0,0 +1,49 @@
+package gopeat
+
+import (
+	"testing"
+	"time"
+)
+
+// TestProgress reads Progress while the run plays, the counts only go
+// up, and the finished run is all sent
+func TestProgress(t *testing.T) {
+	simStartTime := time.Now()
+	ss := pipelineSource(simStartTime, 100, time.Millisecond)
+	pb, _ := New("test", simStartTime, simStartTime.Add(100*time.Millisecond), ss, 2, nil)
+	pb.SendTs = func(ts TimeStamper) error { return nil }
+
+	if p := pb.Progress(); p != (Progress{}) {
+		t.Errorf("Progress before Play = %+v; expected zero", p)
+	}
+
+	polled := make(chan Progress)
+	pb.Play()
+	go func() {
+		var last Progress
+		for !pb.IsIdle() {
+			p := pb.Progress()
+			if p.RecordsSent < last.RecordsSent ||
+				p.CurrentSimTime.Before(last.CurrentSimTime) ||
+				p.FractionComplete < last.FractionComplete {
+				t.Errorf("Progress %+v went back from %+v", p, last)
+			}
+			last = p
+			time.Sleep(time.Millisecond)
+		}
+		polled <- last
+	}()
+	pb.Wait()
+	if last := <-polled; last.RecordsSent == 0 {
+		t.Error("Progress RecordsSent stayed 0 while running")
+	}
+
+	p := pb.Progress()
+	if p.RecordsSent != 100 || !p.CurrentSimTime.Equal(simStartTime.Add(100*time.Millisecond)) ||
+		p.FractionComplete != 1 {
+		t.Errorf("Progress = %+v; expected 100 sent to the end time", p)
+	}
+	if p.ElapsedWall != pb.Progress().ElapsedWall || p.ElapsedWall < 40*time.Millisecond {
+		t.Errorf("ElapsedWall %v; expected about 50ms and stopped", p.ElapsedWall)
+	}
+}
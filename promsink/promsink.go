@@ -0,0 +1,79 @@
+//go:build prometheus
+
+// Package promsink exposes go-peat playback metrics to Prometheus, it
+// is built with the prometheus build tag so the gopeat package doesn't
+// depend on the Prometheus client:
+//
+//	go build -tags prometheus
+//
+// The metrics are labeled by playback symbol:
+//
+//	gopeat_drift_seconds        gauge, drift of the latest send
+//	gopeat_records_sent_total   counter, values sent
+//	gopeat_buffer_fill          gauge, read ahead buffers waiting
+//	gopeat_pause_seconds_total  counter, wall time paused
+package promsink
+
+import (
+	"github.com/michelpmcdonald/go-peat"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Sink is a gopeat.MetricsSink keeping the metrics in Prometheus
+// collectors, one Sink serves any number of playbacks
+type Sink struct {
+	gauges   map[string]*prometheus.GaugeVec
+	counters map[string]*prometheus.CounterVec
+}
+
+// New returns a Sink with its collectors registered with reg
+func New(reg prometheus.Registerer) (*Sink, error) {
+	sk := &Sink{
+		gauges: map[string]*prometheus.GaugeVec{
+			gopeat.MetricDrift: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: gopeat.MetricDrift,
+				Help: "Drift of the latest send from its schedule in seconds.",
+			}, []string{"symbol"}),
+			gopeat.MetricBufferFill: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: gopeat.MetricBufferFill,
+				Help: "Read ahead buffers loaded and waiting to be paced.",
+			}, []string{"symbol"}),
+		},
+		counters: map[string]*prometheus.CounterVec{
+			gopeat.MetricRecordsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: gopeat.MetricRecordsSent,
+				Help: "Values sent.",
+			}, []string{"symbol"}),
+			gopeat.MetricPauseTime: prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: gopeat.MetricPauseTime,
+				Help: "Wall time paused in seconds.",
+			}, []string{"symbol"}),
+		},
+	}
+	for _, g := range sk.gauges {
+		if err := reg.Register(g); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range sk.counters {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return sk, nil
+}
+
+// SetGauge sets the symbol's gauge, an unknown name is ignored
+func (sk *Sink) SetGauge(name, symbol string, value float64) {
+	if g, ok := sk.gauges[name]; ok {
+		g.WithLabelValues(symbol).Set(value)
+	}
+}
+
+// AddCounter adds delta to the symbol's counter, an unknown name is
+// ignored
+func (sk *Sink) AddCounter(name, symbol string, delta float64) {
+	if c, ok := sk.counters[name]; ok {
+		c.WithLabelValues(symbol).Add(delta)
+	}
+}
@@ -0,0 +1,153 @@
+package gopeat
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// RateTrigger switches the playback rate once playback reaches the
+// sim time At, the sim time analogue of calling SetRate
+type RateTrigger struct {
+	At   time.Time
+	Rate float64
+}
+
+// RatioRate returns the sim rate that plays simSpan of sim time every
+// wallSpan of wall time, 5 minutes per second is a rate of 300
+func RatioRate(simSpan, wallSpan time.Duration) float64 {
+	return float64(simSpan) / float64(wallSpan)
+}
+
+// SetRatioRate sets the rate as simSpan of sim time played every
+// wallSpan of wall time, for example 5 minutes of market per second.
+// The ratio need not be a whole number, a simSpan shorter than the
+// wallSpan plays slower than real time.
+func (pb *PlayBack) SetRatioRate(simSpan, wallSpan time.Duration) error {
+	if wallSpan <= 0 {
+		return errors.New("playBack: ratio wallSpan must be greater than 0")
+	}
+	return pb.SetSimRate(RatioRate(simSpan, wallSpan))
+}
+
+// validRate reports if rate is usable as a sim rate, finite and
+// above 0
+func validRate(rate float64) bool {
+	return rate > 0 && !math.IsInf(rate, 1)
+}
+
+// debounce holds rate for the ControlDebounce window, the last rate
+// held is queued for the controller when the window closes, behind
+// the commands queued in the window
+func (pb *PlayBack) debounce(rate float64) {
+	pb.debounceMu.Lock()
+	defer pb.debounceMu.Unlock()
+	pb.debounceRate = rate
+	if pb.debouncing {
+		return
+	}
+	pb.debouncing = true
+	wait := pb.clock().After(pb.ControlDebounce)
+	go func() {
+		<-wait
+		pb.debounceMu.Lock()
+		rate := pb.debounceRate
+		pb.debouncing = false
+		pb.debounceMu.Unlock()
+
+		if !pb.enqueue(command{kind: cmdSetRate, rate: rate}) {
+			pb.setRate(rate)
+		}
+	}()
+}
+
+// rateChange is a rate change at the wall time at, from the rate old
+type rateChange struct {
+	at  time.Time
+	old float64
+}
+
+// setRate sets the simulation rate, logging the change for the timer
+func (pb *PlayBack) setRate(rate float64) {
+	pb.rateMu.Lock()
+	pb.markRateLocked(rate)
+	pb.rateChanges = append(pb.rateChanges, rateChange{at: pb.clock().Now(), old: pb.rate})
+	pb.rate, pb.baseRate = rate, rate
+	pb.rateMu.Unlock()
+
+	pb.rateCnt.Add(1)
+	select {
+	case pb.rateSig <- struct{}{}:
+	default:
+	}
+}
+
+// rebase moves the timer's pacing base, baseSim at wall baseWall, up
+// to the latest rate change since, so the gap to the next value at
+// tsTime is paced at the old rate up to the change and the new rate
+// after. Only called by the timer.
+func (pb *PlayBack) rebase(baseSim, baseWall *time.Time, tsTime time.Time) {
+	// The changes taken here don't need to wake the next sleep
+	select {
+	case <-pb.rateSig:
+	default:
+	}
+
+	pb.rateMu.Lock()
+	changes := pb.rateChanges
+	pb.rateChanges = nil
+	pb.rateMu.Unlock()
+
+	for _, rc := range changes {
+		// Sent since, the send is the base
+		if !rc.at.After(*baseWall) {
+			continue
+		}
+		elapsed := rc.at.Sub(*baseWall) - pb.pausedBetween(*baseWall, rc.at)
+		reached := baseSim.Add(time.Duration(float64(elapsed) * rc.old))
+		if reached.After(tsTime) {
+			reached = tsTime
+		}
+		*baseSim, *baseWall = reached, rc.at
+	}
+}
+
+// scaled returns the sim duration d adjusted for the sim rate
+func (pb *PlayBack) scaled(d time.Duration) time.Duration {
+	pb.rateMu.RLock()
+	defer pb.rateMu.RUnlock()
+	return time.Duration(float64(d) / pb.rate)
+}
+
+// simDur returns the sim time from to to, less any time the
+// Calendar is closed
+func (pb *PlayBack) simDur(from, to time.Time) time.Duration {
+	d := to.Sub(from)
+	if pb.Calendar != nil {
+		d -= closedDur(pb.Calendar, from, to)
+	}
+	return d
+}
+
+// pacedDur returns the wall duration the sim time from prev to tsTime
+// plays over. Each RateTrigger crossed on the way switches the rate
+// from its At on. next indexes the first trigger not yet crossed and
+// is advanced past the ones crossed.
+func (pb *PlayBack) pacedDur(prev, tsTime time.Time, next *int) time.Duration {
+	var paced time.Duration
+	for ; *next < len(pb.RateTriggers); *next++ {
+		trig := pb.RateTriggers[*next]
+		if trig.At.After(tsTime) {
+			break
+		}
+		if trig.Rate <= 0 {
+			continue
+		}
+		if trig.At.After(prev) {
+			paced += pb.scaled(pb.simDur(prev, trig.At))
+			prev = trig.At
+		}
+		pb.setRateAt(trig.At, trig.Rate)
+	}
+	return paced + pb.scaled(pb.simDur(prev, tsTime))
+}
@@ -0,0 +1,371 @@
+package gopeat
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestRateTriggers confirms the pacing switches rate at a trigger's
+// sim time, mid gap
+func TestRateTriggers(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 4; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * 100 * time.Millisecond),
+			Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+	pb.RateTriggers = []RateTrigger{
+		{At: simStartTime.Add(150 * time.Millisecond), Rate: 2},
+	}
+
+	var sendDurs []time.Duration
+	pb.SendTs = func(ts TimeStamper) error {
+		sendDurs = append(sendDurs, time.Since(pb.WallStartTime))
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	// 1x to 150ms, the 2x rate halves the gaps after
+	expected := []time.Duration{100, 175, 225, 275}
+	if len(sendDurs) != len(expected) {
+		t.Fatalf("Got %d sends; expected %d", len(sendDurs), len(expected))
+	}
+	for i, exp := range expected {
+		d := (sendDurs[i] - exp*time.Millisecond).Seconds() * 1000
+		if math.Abs(d) > 3 {
+			t.Errorf("send %d Time = %f(ms); want less than 3(ms)", i, d)
+		}
+	}
+}
+
+// TestRateTriggersReplay confirms a replay after Reset starts back at
+// the rate set by New, not the rate of the last trigger crossed
+func TestRateTriggersReplay(t *testing.T) {
+	simStartTime := time.Now()
+	ss := pipelineSource(simStartTime, 4, 100*time.Millisecond)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), ss, 1, nil)
+	pb.RateTriggers = []RateTrigger{
+		{At: simStartTime.Add(150 * time.Millisecond), Rate: 2},
+	}
+
+	var sendDurs []time.Duration
+	pb.SendTs = func(ts TimeStamper) error {
+		sendDurs = append(sendDurs, time.Since(pb.WallStartTime))
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+	if err := pb.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	sendDurs = nil
+	pb.Play()
+	pb.Wait()
+
+	expected := []time.Duration{100, 175, 225, 275}
+	if len(sendDurs) != len(expected) {
+		t.Fatalf("Got %d sends; expected %d", len(sendDurs), len(expected))
+	}
+	for i, exp := range expected {
+		d := (sendDurs[i] - exp*time.Millisecond).Seconds() * 1000
+		if math.Abs(d) > 3 {
+			t.Errorf("send %d Time = %f(ms); want less than 3(ms)", i, d)
+		}
+	}
+	if exp := pb.DriftStats().ExpectedRunDuration; exp != 400*time.Millisecond {
+		t.Errorf("ExpectedRunDuration = %v; expected 400ms at the 1x rate", exp)
+	}
+}
+
+// TestRateTriggersSeekBack confirms a seek back before a trigger
+// plays at the rate set by New up to the trigger again
+func TestRateTriggersSeekBack(t *testing.T) {
+	simStartTime := time.Now()
+	ss := pipelineSource(simStartTime, 10, 100*time.Millisecond)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), ss, 1, nil)
+	if err := pb.SetBufferSizes(2, 2); err != nil {
+		t.Fatal(err)
+	}
+	pb.RateTriggers = []RateTrigger{
+		{At: simStartTime.Add(150 * time.Millisecond), Rate: 2},
+	}
+
+	var vals []int64
+	var sendWalls []time.Time
+	reached := make(chan struct{})
+	pb.SendTs = func(ts TimeStamper) error {
+		vals = append(vals, ts.(mockTsData).Val)
+		sendWalls = append(sendWalls, time.Now())
+		if len(vals) == 3 {
+			close(reached)
+		}
+		return nil
+	}
+
+	pb.Play()
+	<-reached
+	if err := pb.SeekTo(simStartTime.Add(100 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	pb.Wait()
+
+	i := len(vals) - 10
+	if i < 3 || vals[i] != 1 || vals[i+1] != 2 {
+		t.Fatalf("Got %v; expected 1 to 10 after the seek", vals)
+	}
+	// 1x from 100ms to the trigger at 150ms, 2x on to 200ms
+	d := (sendWalls[i+1].Sub(sendWalls[i]) - 75*time.Millisecond).Seconds() * 1000
+	if math.Abs(d) > 3 {
+		t.Errorf("Gap after the seek = %f(ms) off 75ms; want less than 3(ms)", d)
+	}
+}
+
+// TestMaxInterRecordWait confirms a huge gap is collapsed to the cap
+// and shorter gaps are left alone
+func TestMaxInterRecordWait(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(50 * time.Millisecond), Val: 1},
+		mockTsData{Tim: simStartTime.Add(5 * time.Hour), Val: 2},
+		mockTsData{Tim: simStartTime.Add(5*time.Hour + 30*time.Millisecond), Val: 3},
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(6*time.Hour), &mts, 1, nil)
+	pb.MaxInterRecordWait = 100 * time.Millisecond
+
+	var sendDurs []time.Duration
+	pb.SendTs = func(ts TimeStamper) error {
+		sendDurs = append(sendDurs, time.Since(pb.WallStartTime))
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	expected := []time.Duration{50, 150, 180}
+	if len(sendDurs) != len(expected) {
+		t.Fatalf("Got %d sends; expected %d", len(sendDurs), len(expected))
+	}
+	for i, exp := range expected {
+		d := (sendDurs[i] - exp*time.Millisecond).Seconds() * 1000
+		if math.Abs(d) > 3 {
+			t.Errorf("send %d Time = %f(ms); want less than 3(ms)", i, d)
+		}
+	}
+}
+
+// TestRatioRate confirms 5 minutes of sim time per wall second paces
+// a 15 second sim gap over 50ms
+func TestRatioRate(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 3; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * 15 * time.Second),
+			Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Hour), &mts, 1, nil)
+	if err := pb.SetRatioRate(5*time.Minute, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := pb.SetRatioRate(0, time.Minute); err != errRateTooLow {
+		t.Errorf("zero ratio err %v; expected errRateTooLow", err)
+	}
+
+	var sendDurs []time.Duration
+	pb.SendTs = func(ts TimeStamper) error {
+		sendDurs = append(sendDurs, time.Since(pb.WallStartTime))
+		return nil
+	}
+
+	pb.Play()
+	pb.Wait()
+
+	expected := []time.Duration{50, 100, 150}
+	if len(sendDurs) != len(expected) {
+		t.Fatalf("Got %d sends; expected %d", len(sendDurs), len(expected))
+	}
+	for i, exp := range expected {
+		d := (sendDurs[i] - exp*time.Millisecond).Seconds() * 1000
+		if math.Abs(d) > 3 {
+			t.Errorf("send %d Time = %f(ms); want less than 3(ms)", i, d)
+		}
+	}
+}
+
+// TestSetRateMidGap confirms a rate change partway through a gap paces
+// the rest of the gap at the new rate, speeding up or slowing down,
+// without throwing off the sends after
+func TestSetRateMidGap(t *testing.T) {
+	for _, tc := range []struct {
+		gap      time.Duration
+		from, to uint16
+	}{
+		// Half of 1s at 10x, then 100x
+		{time.Second, 10, 100},
+		// Half of 10s at 100x, then 10x
+		{10 * time.Second, 100, 10},
+	} {
+		var mts mockSliceBackedDs
+		simStartTime := time.Now()
+		for i := 1; i <= 2; i++ {
+			mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+				Tim: simStartTime.Add(time.Duration(i) * tc.gap),
+				Val: int64(i)})
+		}
+		pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), &mts, tc.from, nil)
+
+		var sendDurs []time.Duration
+		pb.SendTs = func(ts TimeStamper) error {
+			sendDurs = append(sendDurs, time.Since(pb.WallStartTime))
+			return nil
+		}
+
+		pb.Play()
+		time.Sleep(time.Until(pb.WallStartTime.Add(50 * time.Millisecond)))
+		changed := time.Since(pb.WallStartTime)
+		pb.SetRate(tc.to)
+		pb.Wait()
+
+		// The rest of the first gap, then the whole second, at the new rate
+		rest := tc.gap - changed*time.Duration(tc.from)
+		first := changed + rest/time.Duration(tc.to)
+		expected := []time.Duration{first, first + tc.gap/time.Duration(tc.to)}
+		if len(sendDurs) != len(expected) {
+			t.Fatalf("Got %d sends; expected %d", len(sendDurs), len(expected))
+		}
+		for i, exp := range expected {
+			d := (sendDurs[i] - exp).Seconds() * 1000
+			if math.Abs(d) > 3 {
+				t.Errorf("%dx to %dx send %d Time = %f(ms); want less than 3(ms)",
+					tc.from, tc.to, i, d)
+			}
+		}
+	}
+}
+
+// TestFractionalRate plays 10ms gaps at half and one and a half speed,
+// and confirms a rate of 0 is rejected
+func TestFractionalRate(t *testing.T) {
+	if _, err := NewWithRate("test", time.Now(), time.Now(),
+		&mockSliceBackedDs{}, 0, nil); err == nil {
+		t.Error("Got nil error for rate 0; expected error")
+	}
+
+	for _, tc := range []struct {
+		rate     float64
+		expected []time.Duration
+	}{
+		{0.5, []time.Duration{20000, 40000, 60000}},
+		{1.5, []time.Duration{6667, 13333, 20000}},
+	} {
+		var mts mockSliceBackedDs
+		simStartTime := time.Now()
+		for i := 1; i <= 3; i++ {
+			mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+				Tim: simStartTime.Add(time.Duration(i) * 10 * time.Millisecond),
+				Val: int64(i)})
+		}
+		pb, err := NewWithRate("test", simStartTime, simStartTime.Add(time.Minute),
+			&mts, tc.rate, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := pb.SetSimRate(-1); err != errRateTooLow {
+			t.Errorf("negative rate err %v; expected errRateTooLow", err)
+		}
+
+		var sendDurs []time.Duration
+		pb.SendTs = func(ts TimeStamper) error {
+			sendDurs = append(sendDurs, time.Since(pb.WallStartTime))
+			return nil
+		}
+		pb.Play()
+		pb.Wait()
+
+		if len(sendDurs) != len(tc.expected) {
+			t.Fatalf("Got %d sends; expected %d", len(sendDurs), len(tc.expected))
+		}
+		for i, exp := range tc.expected {
+			d := (sendDurs[i] - exp*time.Microsecond).Seconds() * 1000
+			if math.Abs(d) > 3 {
+				t.Errorf("%vx send %d Time = %f(ms); want less than 3(ms)",
+					tc.rate, i, d)
+			}
+		}
+	}
+}
+
+// TestControlDebounce sends a burst of rate changes, only the last is
+// applied once the window closes
+func TestControlDebounce(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	mts.TimeStampers = append(mts.TimeStampers,
+		mockTsData{Tim: simStartTime.Add(time.Hour), Val: 1})
+	pb, _ := New("test", simStartTime, simStartTime.Add(2*time.Hour), &mts, 1, nil)
+	pb.ControlDebounce = 20 * time.Millisecond
+
+	pb.Play()
+	applied := pb.rateCnt.Load()
+	for rate := uint16(2); rate <= 10; rate++ {
+		pb.SetRate(rate)
+	}
+	if n := pb.rateCnt.Load() - applied; n != 0 {
+		t.Errorf("Applied %d rates in the window; expected 0", n)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	pb.sync()
+	if n := pb.rateCnt.Load() - applied; n != 1 {
+		t.Errorf("Applied %d rates; expected 1", n)
+	}
+	pb.rateMu.RLock()
+	rate := pb.rate
+	pb.rateMu.RUnlock()
+	if rate != 10 {
+		t.Errorf("Rate = %v; expected 10", rate)
+	}
+	pb.Quit()
+	pb.Wait()
+}
+
+// TestControlDebounceOrder confirms a debounced rate is applied when
+// its window closes, after a Pause made within the window
+func TestControlDebounceOrder(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	mts.TimeStampers = append(mts.TimeStampers,
+		mockTsData{Tim: simStartTime.Add(time.Hour), Val: 1})
+	pb, _ := New("test", simStartTime, simStartTime.Add(2*time.Hour), &mts, 1, nil)
+	pb.ControlDebounce = 20 * time.Millisecond
+	clock := &mockClock{now: simStartTime, manual: true}
+	pb.Clock = clock
+
+	pb.Play()
+	pb.SetRate(2)
+	pb.Pause()
+	pb.sync()
+	pb.rateMu.RLock()
+	rate := pb.rate
+	pb.rateMu.RUnlock()
+	if !pb.paused || rate != 1 {
+		t.Errorf("paused %t, rate %v; expected paused at rate 1", pb.paused, rate)
+	}
+
+	clock.advance(20 * time.Millisecond)
+	for applied := false; !applied; {
+		pb.sync()
+		pb.rateMu.RLock()
+		applied = pb.rate == 2
+		pb.rateMu.RUnlock()
+	}
+	pb.Quit()
+	pb.Wait()
+}
@@ -0,0 +1,57 @@
+package gopeat
+
+import (
+	"bytes"
+	"io"
+)
+
+// RawTs wraps a TimeStamper value with the raw source bytes it was
+// parsed from. Sources in RawCapture mode provide RawTs values,
+// playback unwraps them and passes the bytes to SendTsRaw.
+type RawTs struct {
+	TimeStamper
+	Raw []byte
+}
+
+// unwrapRaw splits a RawTs into its value and raw bytes, other
+// values are returned as is with nil raw bytes
+func unwrapRaw(tsData TimeStamper) (TimeStamper, []byte) {
+	if rt, ok := tsData.(RawTs); ok {
+		return rt.TimeStamper, rt.Raw
+	}
+	return tsData, nil
+}
+
+// send passes a value to the client, along with its raw bytes when
+// SendTsRaw is set
+func (pb *PlayBack) send(tsData TimeStamper, raw []byte) error {
+	if pb.SendTsRaw != nil {
+		return pb.SendTsRaw(tsData, raw)
+	}
+	return pb.SendTs(tsData)
+}
+
+// rawTee keeps a copy of the bytes read through it until taken, so
+// the raw bytes of each record can be recovered from a parser's input
+// offset even though the parser reads ahead
+type rawTee struct {
+	r    io.Reader
+	buf  []byte
+	base int64
+}
+
+func (rt *rawTee) Read(p []byte) (int, error) {
+	n, err := rt.r.Read(p)
+	rt.buf = append(rt.buf, p[:n]...)
+	return n, err
+}
+
+// take returns a copy of the bytes from the end of the last take to
+// the input offset end, without the line terminator
+func (rt *rawTee) take(end int64) []byte {
+	n := end - rt.base
+	raw := bytes.Clone(bytes.TrimRight(rt.buf[:n], "\r\n"))
+	rt.buf = rt.buf[:copy(rt.buf, rt.buf[n:])]
+	rt.base = end
+	return raw
+}
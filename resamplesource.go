@@ -0,0 +1,85 @@
+package gopeat
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ResampledSource is a time stamped data source that coalesces a
+// source's values into one value per window, see ResampleSource
+type ResampledSource struct {
+	sourceWrap
+	window time.Duration
+	agg    func([]TimeStamper) TimeStamper
+
+	pending TimeStamper
+	vals    []TimeStamper
+	end     time.Time
+	done    bool
+}
+
+// ResampleSource returns a source that provides one value per window
+// of src's time, agg's value for the src values in the window, for
+// example a chart point per 100ms of a tick file with thousands of
+// ticks a millisecond. Windows are aligned to window boundaries, like
+// OHLCAggregator bars, and a window with no values is skipped. agg
+// gets the window's values in order, never none. Playback paces on
+// the GetTimeStamp of agg's value, which must keep the values ordered,
+// the time of the window's last value sends it as the window's values
+// are in.
+//
+// The time bracket applies to src's values before they are windowed,
+// so a start time off a window boundary leaves the first window only
+// the values from the start time on, and the end time cuts the last
+// window short. A value agg stamps outside the bracket, such as a
+// window end past the end time, is still sent. window must be
+// greater than 0.
+func ResampleSource(src TimeStampSource, window time.Duration,
+	agg func([]TimeStamper) TimeStamper) (*ResampledSource, error) {
+	if window <= 0 {
+		return nil, errors.New("playBack: resample window must be greater than 0")
+	}
+	return &ResampledSource{sourceWrap: sourceWrap{src: src}, window: window, agg: agg}, nil
+}
+
+// Next implements an iterator for the windows' values
+func (rs *ResampledSource) Next() (TimeStamper, bool) {
+	return rs.NextContext(context.Background())
+}
+
+// NextContext is Next, returning false once ctx is done, see
+// CancellableSource. A window is read until a value past its end
+// shows up, a nil value from the source is passed on as is.
+func (rs *ResampledSource) NextContext(ctx context.Context) (TimeStamper, bool) {
+	for {
+		if rs.pending == nil && !rs.done {
+			tsData, ok := rs.next(ctx)
+			if ok && tsData == nil {
+				return nil, true
+			}
+			rs.pending, rs.done = tsData, !ok
+		}
+
+		// Cut short, not a whole window
+		if ctx.Err() != nil {
+			return nil, false
+		}
+
+		// Add the value to the window, the first sets the window's end
+		if rs.pending != nil && (rs.vals == nil || rs.pending.GetTimeStamp().Before(rs.end)) {
+			if rs.vals == nil {
+				rs.end = rs.pending.GetTimeStamp().Truncate(rs.window).Add(rs.window)
+			}
+			rs.vals = append(rs.vals, rs.pending)
+			rs.pending = nil
+			continue
+		}
+		if rs.vals == nil {
+			return nil, false
+		}
+		vals := rs.vals
+		rs.vals = nil
+		return rs.agg(vals), true
+	}
+}
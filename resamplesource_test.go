@@ -0,0 +1,123 @@
+package gopeat
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestResampleSource coalesces bursts of trades into OHLC bars per
+// 100ms window, skipping the window with no trades. A window of 0 is
+// rejected
+func TestResampleSource(t *testing.T) {
+	simStartTime := time.Date(2013, 9, 3, 10, 0, 0, 0, time.UTC)
+	ms := func(n int) time.Time {
+		return simStartTime.Add(time.Duration(n) * time.Millisecond)
+	}
+
+	// Trades with prices in Val, bursts in the same millisecond
+	var mts mockSliceBackedDs
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: ms(10), Val: 100},
+		mockTsData{Tim: ms(10), Val: 105},
+		mockTsData{Tim: ms(10), Val: 99},
+		mockTsData{Tim: ms(50), Val: 102},
+		mockTsData{Tim: ms(120), Val: 101},
+		mockTsData{Tim: ms(120), Val: 104},
+		mockTsData{Tim: ms(320), Val: 103},
+	}
+	ohlc := func(trades []TimeStamper) TimeStamper {
+		last := trades[len(trades)-1].GetTimeStamp()
+		bar := Bar{Start: last.Truncate(100 * time.Millisecond), End: last,
+			Count: len(trades)}
+		for i, trade := range trades {
+			price := float64(trade.(mockTsData).Val)
+			if i == 0 {
+				bar.Open, bar.High, bar.Low = price, price, price
+			}
+			bar.High = max(bar.High, price)
+			bar.Low = min(bar.Low, price)
+			bar.Close = price
+		}
+		return bar
+	}
+	if _, err := ResampleSource(&mts, 0, ohlc); err == nil {
+		t.Error("window 0 accepted; expected error")
+	}
+	rs, err := ResampleSource(&mts, 100*time.Millisecond, ohlc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, _ := New("test", simStartTime, ms(500), rs, 1, nil)
+
+	var bars []Bar
+	pb.SendTs = func(ts TimeStamper) error {
+		bars = append(bars, ts.(Bar))
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+
+	expected := []Bar{
+		{Start: ms(0), End: ms(50), Open: 100, High: 105, Low: 99, Close: 102, Count: 4},
+		{Start: ms(100), End: ms(120), Open: 101, High: 104, Low: 101, Close: 104, Count: 2},
+		{Start: ms(300), End: ms(320), Open: 103, High: 103, Low: 103, Close: 103, Count: 1},
+	}
+	if len(bars) != len(expected) {
+		t.Fatalf("Got %d bars; expected %d", len(bars), len(expected))
+	}
+	for i, bar := range bars {
+		if bar != expected[i] {
+			t.Errorf("bar %d = %+v; expected %+v", i, bar, expected[i])
+		}
+	}
+}
+
+// TestResampleSourceNil confirms a nil value from the source is
+// passed on as is and the window it came in carries on after it
+func TestResampleSourceNil(t *testing.T) {
+	simStartTime := time.Date(2013, 9, 3, 10, 0, 0, 0, time.UTC)
+	ms := func(n int) time.Time {
+		return simStartTime.Add(time.Duration(n) * time.Millisecond)
+	}
+	var mts mockSliceBackedDs
+	mts.TimeStampers = []TimeStamper{
+		mockTsData{Tim: ms(10), Val: 1},
+		nil,
+		mockTsData{Tim: ms(20), Val: 2},
+		mockTsData{Tim: ms(120), Val: 3},
+	}
+	count := func(vals []TimeStamper) TimeStamper {
+		return mockTsData{Tim: vals[len(vals)-1].GetTimeStamp(), Val: int64(len(vals))}
+	}
+	rs, _ := ResampleSource(&mts, 100*time.Millisecond, count)
+
+	// 0 stands for a nil value
+	var counts []int64
+	for ts, ok := rs.Next(); ok; ts, ok = rs.Next() {
+		if ts == nil {
+			counts = append(counts, 0)
+			continue
+		}
+		counts = append(counts, ts.(mockTsData).Val)
+	}
+	if fmt.Sprint(counts) != "[0 2 1]" {
+		t.Errorf("Got %v; expected [0 2 1]", counts)
+	}
+}
+
+// TestResampleSourceCanceled confirms a window cut short by ctx is
+// not sent
+func TestResampleSourceCanceled(t *testing.T) {
+	simStartTime := time.Date(2013, 9, 3, 10, 0, 0, 0, time.UTC)
+	src := &mockCancellableDs{tim: simStartTime.Add(10 * time.Millisecond)}
+	first := func(vals []TimeStamper) TimeStamper { return vals[0] }
+	rs, _ := ResampleSource(src, 100*time.Millisecond, first)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if ts, ok := rs.NextContext(ctx); ts != nil || ok {
+		t.Errorf("NextContext = %v, %t; expected nil, false", ts, ok)
+	}
+}
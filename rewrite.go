@@ -0,0 +1,85 @@
+package gopeat
+
+import "time"
+
+// ReTimeStamper is implemented by TimeStamper values that can copy
+// themselves with a new timestamp. Playback's timestamp rewrites,
+// like DateShift, use it to keep the client's value type. Values that
+// don't implement it are wrapped in a RetimedTs.
+type ReTimeStamper interface {
+	TimeStamper
+	WithTimeStamp(time.Time) TimeStamper
+}
+
+// RetimedTs wraps a TimeStamper value given a new timestamp by a
+// playback timestamp rewrite, the original value is embedded
+type RetimedTs struct {
+	TimeStamper
+	Tim time.Time
+}
+
+// GetTimeStamp returns the rewritten timestamp
+func (rt RetimedTs) GetTimeStamp() time.Time {
+	return rt.Tim
+}
+
+// retime returns tsData with the new timestamp tim
+func retime(tsData TimeStamper, tim time.Time) TimeStamper {
+	switch ts := tsData.(type) {
+	case ReTimeStamper:
+		return ts.WithTimeStamp(tim)
+	case RetimedTs:
+		ts.Tim = tim
+		return ts
+	}
+	return RetimedTs{TimeStamper: tsData, Tim: tim}
+}
+
+// rewriting reports if sent values get rewritten
+func (pb *PlayBack) rewriting() bool {
+	return pb.RewriteTs != nil || !pb.DateShift.IsZero() ||
+		pb.TimestampResolution > 0 || !pb.RewriteOrigin.IsZero()
+}
+
+// rewrite applies the timestamp rewrites to a value about to be sent
+func (pb *PlayBack) rewrite(tsData TimeStamper) TimeStamper {
+	if pb.TimestampResolution > 0 {
+		tim := tsData.GetTimeStamp()
+		if trunc := tim.Truncate(pb.TimestampResolution); !trunc.Equal(tim) {
+			tsData = retime(tsData, trunc)
+		}
+	}
+	if !pb.RewriteOrigin.IsZero() {
+		tsData = retime(tsData, pb.fromOrigin(tsData.GetTimeStamp()))
+	}
+	if !pb.DateShift.IsZero() {
+		tim := tsData.GetTimeStamp()
+		tsData = retime(tsData, tim.AddDate(0, 0, shiftDays(tim, pb.DateShift)))
+	}
+	if pb.RewriteTs != nil {
+		tsData = pb.RewriteTs(tsData)
+	}
+	return tsData
+}
+
+// fromOrigin returns tim moved to the RewriteOrigin timeline, scaled
+// at the current rate from the previous value moved
+func (pb *PlayBack) fromOrigin(tim time.Time) time.Time {
+	if pb.originOut.IsZero() {
+		pb.originSrc, pb.originOut = pb.StartTime, pb.RewriteOrigin
+	}
+	pb.originOut = pb.originOut.Add(pb.scaled(tim.Sub(pb.originSrc)))
+	pb.originSrc = tim
+	return pb.originOut
+}
+
+// shiftDays returns the calendar days from tim's date to the date of
+// target. Adding them with AddDate keeps tim's time of day even when
+// a DST change falls in between.
+func shiftDays(tim time.Time, target time.Time) int {
+	y, m, d := tim.Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	y, m, d = target.In(tim.Location()).Date()
+	to := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
@@ -0,0 +1,104 @@
+package gopeat
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// SkipCounter is implemented by a TimeStampSource that counts the
+// records it skipped, like CsvTsSource's records before the start time
+type SkipCounter interface {
+	Skipped() int64
+}
+
+// RunResult bundles what a caller needs after a run, see Run.
+// Durations marshal to JSON as nanoseconds.
+type RunResult struct {
+	Completion Completion `json:"completion"`
+	Err        error      `json:"-"`
+	Error      string     `json:"error,omitempty"`
+
+	// Records sent, and records the source skipped when it is a
+	// SkipCounter
+	Records int64 `json:"records"`
+	Skipped int64 `json:"skipped"`
+
+	// WallRunDur is the wall time the run took, SimSpan the sim time
+	// from the first to the last value sent
+	WallRunDur time.Duration `json:"wallRunDur"`
+	SimSpan    time.Duration `json:"simSpan"`
+
+	// AchievedRate is SimSpan over the wall time between the first and
+	// last sends, 0 with fewer than two sends
+	AchievedRate float64 `json:"achievedRate"`
+
+	// Percentiles of the absolute send drift, of the sample kept per
+	// TimingSampleRate, and the largest of every send
+	DriftP50 time.Duration `json:"driftP50"`
+	DriftP90 time.Duration `json:"driftP90"`
+	DriftP99 time.Duration `json:"driftP99"`
+	DriftMax time.Duration `json:"driftMax"`
+
+	Contention ContentionStats `json:"contention"`
+}
+
+// Run plays back and blocks until the run ends, then returns the
+// run's RunResult
+func (pb *PlayBack) Run() RunResult {
+	pb.Play()
+	pb.Wait()
+	return pb.result()
+}
+
+// result collects the RunResult of the last run
+func (pb *PlayBack) result() RunResult {
+	res := RunResult{
+		Completion: pb.Completion(),
+		Err:        pb.Err(),
+		WallRunDur: pb.WallRunDur,
+		Contention: pb.ContentionStats(),
+	}
+	if res.Err != nil {
+		res.Error = res.Err.Error()
+	}
+	if sc, ok := pb.TsDataSource.(SkipCounter); ok {
+		res.Skipped = sc.Skipped()
+	}
+
+	tt := pb.timingTotals
+	res.Records = tt.count
+	if tt.count == 0 {
+		return res
+	}
+
+	first, last := tt.first, tt.last
+	res.SimSpan = last.TsTime.Sub(first.TsTime)
+	if wall := last.ActualWall.Sub(first.ActualWall); wall > 0 {
+		res.AchievedRate = float64(res.SimSpan) / float64(wall)
+	}
+
+	timings := pb.Timings()
+	drifts := make([]time.Duration, len(timings))
+	for i, rt := range timings {
+		drifts[i] = rt.DriftDur
+		if drifts[i] < 0 {
+			drifts[i] = -drifts[i]
+		}
+	}
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i] < drifts[j] })
+	res.DriftP50 = percentile(drifts, 0.50)
+	res.DriftP90 = percentile(drifts, 0.90)
+	res.DriftP99 = percentile(drifts, 0.99)
+	res.DriftMax = tt.maxDrift
+	return res
+}
+
+// percentile returns the nearest rank p percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
@@ -0,0 +1,99 @@
+package gopeat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRun confirms a completed Run returns a populated RunResult that
+// marshals to JSON
+func TestRun(t *testing.T) {
+	start := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	var sb strings.Builder
+	sb.WriteString("tim, amt\n")
+	for i := -2; i < 5; i++ {
+		fmt.Fprintf(&sb, "%s, %d\n",
+			start.Add(time.Duration(i+1)*20*time.Millisecond).Format(time.RFC3339Nano), i)
+	}
+	tsSource := &CsvTsSource{
+		CsvStream: strings.NewReader(sb.String()),
+		CsvTsConv: func(csv []string) (TimeStamper, error) {
+			tim, err := time.Parse(time.RFC3339Nano, csv[0])
+			return mockTsData{Tim: tim}, err
+		},
+	}
+	pb, _ := New("test", start, start.Add(time.Second), tsSource, 2, nil)
+
+	res := pb.Run()
+
+	if res.Completion != CompletedOK || res.Err != nil || res.Error != "" {
+		t.Errorf("Completion %d, Err %v; expected CompletedOK", res.Completion, res.Err)
+	}
+	// The record before the start is skipped
+	if res.Records != 6 || res.Skipped != 1 {
+		t.Errorf("Records %d, Skipped %d; expected 6, 1", res.Records, res.Skipped)
+	}
+	if res.SimSpan != 100*time.Millisecond {
+		t.Errorf("SimSpan %v; expected 100ms", res.SimSpan)
+	}
+	if res.AchievedRate < 1.8 || res.AchievedRate > 2.2 {
+		t.Errorf("AchievedRate %f; expected about 2", res.AchievedRate)
+	}
+	if res.WallRunDur <= 0 {
+		t.Errorf("WallRunDur %v; expected above 0", res.WallRunDur)
+	}
+	if !(res.DriftP50 <= res.DriftP90 && res.DriftP90 <= res.DriftP99 &&
+		res.DriftP99 <= res.DriftMax) {
+		t.Errorf("drift percentiles out of order: %v %v %v %v",
+			res.DriftP50, res.DriftP90, res.DriftP99, res.DriftMax)
+	}
+	if res.Contention.TimerReads == 0 {
+		t.Error("Contention TimerReads 0; expected reads")
+	}
+
+	js, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back RunResult
+	if err := json.Unmarshal(js, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Records != res.Records || back.DriftMax != res.DriftMax {
+		t.Errorf("JSON round trip %+v; expected %+v", back, res)
+	}
+}
+
+// TestDone confirms Done tells a run that sent all its data from one
+// that was quit
+func TestDone(t *testing.T) {
+	simStartTime := time.Now()
+	ss := pipelineSource(simStartTime, 5, 10*time.Millisecond)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), ss, 1, nil)
+	if pb.Done() != nil {
+		t.Error("Done not nil before Play")
+	}
+
+	pb.Play()
+	res := <-pb.Done()
+	if res.Completion != CompletedOK || res.Records != 5 || res.WallRunDur <= 0 {
+		t.Errorf("Completion, Records, WallRunDur = %v, %d, %v; expected CompletedOK, 5, above 0",
+			res.Completion, res.Records, res.WallRunDur)
+	}
+	if _, ok := <-pb.Done(); ok {
+		t.Error("Done not closed after the result")
+	}
+	pb.Wait()
+
+	if err := pb.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	pb.Play()
+	pb.Quit()
+	if res := <-pb.Done(); res.Completion != CompletedQuit {
+		t.Errorf("Completion = %v; expected CompletedQuit", res.Completion)
+	}
+}
@@ -0,0 +1,111 @@
+package gopeat
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotSeekable is the SeekTo error for a source that doesn't
+// implement Seekable
+var ErrNotSeekable = errors.New("playBack: source not seekable")
+
+// errSeekNotRunning is the SeekTo error when there is no run to seek,
+// or the run has read its source to the end
+var errSeekNotRunning = errors.New("playBack: not loading, can't seek")
+
+// Seekable is implemented by a source that can move to the first
+// value at or after t, the next call to Next returns it. t is within
+// the source's time bracket.
+type Seekable interface {
+	SeekTo(t time.Time) error
+}
+
+// seekReq is a SeekTo for the loader, err is set and done closed once
+// the source has moved
+type seekReq struct {
+	at   time.Time
+	err  error
+	done chan struct{}
+}
+
+// seekMark heads the data loaded after a seek, the timer drops the
+// data loaded before it and paces on from at
+type seekMark struct {
+	at  time.Time
+	gen int64
+}
+
+// GetTimeStamp returns the sim time seeked to
+func (sm seekMark) GetTimeStamp() time.Time {
+	return sm.at
+}
+
+// SeekTo jumps the running playback to the first value at or after t,
+// which must be within the start and end times. The data loaded ahead
+// is dropped and pacing starts over from t, so the value at t goes out
+// right away and the ones after it at the rate from there, the time
+// skipped isn't slept. Seeking back plays the values from t again.
+// A paused playback stays paused and Resume carries on from t.
+//
+// The source must implement Seekable. The data loaded is dropped by
+// the loader, which has to be reading the source, so SeekTo fails once
+// the source is read to the end even while the last of it plays.
+func (pb *PlayBack) SeekTo(t time.Time) error {
+	if _, ok := pb.TsDataSource.(Seekable); !ok {
+		return ErrNotSeekable
+	}
+	if pb.outOfBracket(t) {
+		return fmt.Errorf("playBack: seek to %v not in %v to %v", t,
+			pb.StartTime, pb.EndTime)
+	}
+
+	pb.ctlMu.Lock()
+	if !pb.running {
+		pb.ctlMu.Unlock()
+		return errSeekNotRunning
+	}
+	seekReqs, quitChan, loaderDone := pb.seekReqs, pb.quitChan, pb.loaderDone
+	pb.ctlMu.Unlock()
+
+	req := &seekReq{at: t, done: make(chan struct{})}
+	select {
+	case seekReqs <- req:
+	case <-quitChan:
+		return errSeekNotRunning
+	case <-loaderDone:
+		return errSeekNotRunning
+	}
+	<-req.done
+	return req.err
+}
+
+// seek moves the source for req and drops the data loaded ahead, then
+// sends the timer the mark to drop its data up to. Returns false if
+// quit. Only called by the loader
+func (pb *PlayBack) seek(req *seekReq) bool {
+	req.err = pb.TsDataSource.(Seekable).SeekTo(req.at)
+	if req.err != nil {
+		close(req.done)
+		return true
+	}
+	pb.primed, pb.primedDone = nil, false
+	pb.bucket, pb.bucketNext, pb.bucketHeld = nil, nil, false
+	pb.seeked = true
+
+	// Only the loader sends, the drained chan has room for the mark
+	for drained := false; !drained; {
+		select {
+		case <-pb.tsDataChan:
+		default:
+			drained = true
+		}
+	}
+	gen := pb.seekGen.Add(1)
+	select {
+	case pb.seekSig <- struct{}{}:
+	default:
+	}
+	close(req.done)
+	return pb.sendTsDataBuf([]TimeStamper{seekMark{at: req.at, gen: gen}})
+}
@@ -0,0 +1,102 @@
+package gopeat
+
+import (
+	"testing"
+	"time"
+)
+
+// seekRun plays 40 values 10ms apart in small buffers, seeks to the
+// value at seekVal once at values are sent, and returns the values
+// sent and the wall time of the seek
+func seekRun(t *testing.T, at, seekVal int64) ([]int64, *PlayBack, time.Time) {
+	simStartTime := time.Now()
+	ss := pipelineSource(simStartTime, 40, 10*time.Millisecond)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), ss, 1, nil)
+	if err := pb.SetBufferSizes(2, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	var vals []int64
+	reached := make(chan struct{})
+	pb.SendTs = func(ts TimeStamper) error {
+		vals = append(vals, ts.(mockTsData).Val)
+		if len(vals) == int(at) {
+			close(reached)
+		}
+		return nil
+	}
+
+	pb.Play()
+	<-reached
+	seekWall := time.Now()
+	if err := pb.SeekTo(simStartTime.Add(time.Duration(seekVal) * 10 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	pb.Wait()
+	return vals, pb, seekWall
+}
+
+// checkSeeked confirms vals run up to at, maybe one more, then from
+// seekVal to the end in order
+func checkSeeked(t *testing.T, vals []int64, at, seekVal int64) int {
+	t.Helper()
+	i := 0
+	for ; i < len(vals) && vals[i] == int64(i+1); i++ {
+	}
+	if i < int(at) || i > int(at)+1 {
+		t.Fatalf("Got %v; expected 1 to %d before the seek", vals, at)
+	}
+	seekIdx := i
+	for val := seekVal; val <= 40; val++ {
+		if i >= len(vals) || vals[i] != val {
+			t.Fatalf("Got %v; expected %d to 40 after the seek", vals, seekVal)
+		}
+		i++
+	}
+	if i != len(vals) {
+		t.Fatalf("Got %v; expected 40 last", vals)
+	}
+	return seekIdx
+}
+
+// TestSeekTo seeks ahead, the value seeked to goes out right away
+// and the rest are paced from it
+func TestSeekTo(t *testing.T) {
+	vals, pb, seekWall := seekRun(t, 3, 30)
+	seekIdx := checkSeeked(t, vals, 3, 30)
+
+	timings := pb.Timings()
+	if d := timings[seekIdx].ActualWall.Sub(seekWall); d > 10*time.Millisecond {
+		t.Errorf("Value seeked to sent %v after the seek; expected right away", d)
+	}
+	d := timings[len(timings)-1].ActualWall.Sub(timings[seekIdx].ActualWall)
+	if d < 95*time.Millisecond || d > 120*time.Millisecond {
+		t.Errorf("Values after the seek took %v; expected 100ms", d)
+	}
+}
+
+// TestSeekBack seeks back, the values from there play again
+func TestSeekBack(t *testing.T) {
+	vals, _, _ := seekRun(t, 10, 2)
+	checkSeeked(t, vals, 10, 2)
+}
+
+// TestSeekToErrors confirms a seek needs a seekable source, a time in
+// the bracket and a run
+func TestSeekToErrors(t *testing.T) {
+	simStartTime := time.Now()
+	var mts mockSliceBackedDs
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), &mts, 1, nil)
+	if err := pb.SeekTo(simStartTime); err != ErrNotSeekable {
+		t.Errorf("SeekTo err = %v; expected ErrNotSeekable", err)
+	}
+
+	ss := pipelineSource(simStartTime, 5, time.Millisecond)
+	pb, _ = New("test", simStartTime, simStartTime.Add(time.Minute), ss, 1, nil)
+	if err := pb.SeekTo(simStartTime.Add(time.Hour)); err == nil {
+		t.Error("SeekTo after the end time; expected error")
+	}
+	if err := pb.SeekTo(simStartTime); err == nil {
+		t.Error("SeekTo before Play; expected error")
+	}
+}
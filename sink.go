@@ -0,0 +1,233 @@
+package gopeat
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// Overflow is what a Sink does with a value when its buffer is full
+type Overflow int
+
+// Sink overflow policies, only OverflowBlock holds up playback
+const (
+	// OverflowDropNewest drops the value being sent
+	OverflowDropNewest Overflow = iota
+
+	// OverflowDropOldest drops the oldest buffered value to make room
+	OverflowDropOldest
+
+	// OverflowBlock waits for room, holding up SendTs, the other
+	// sinks and the playback clock until the sink catches up. Commands
+	// are still taken while waiting.
+	OverflowBlock
+)
+
+// SinkOptions configures a Sink. Buffer is the count of values the
+// sink holds while its callback catches up, less than 1 is 1.
+type SinkOptions struct {
+	Buffer   int
+	Overflow Overflow
+}
+
+// Sink is an extra output stream fed every value sent, see AddSink.
+// A Sink calls its callback on its own goroutine, so a slow sink
+// drops values per its Overflow policy instead of slowing SendTs,
+// the other sinks or the playback clock. A subscription is a Sink
+// without a callback, see Subscribe.
+type Sink struct {
+	send OnTsDataReady
+	opts SinkOptions
+	ch   chan TimeStamper
+
+	sent    atomic.Int64
+	dropped atomic.Int64
+}
+
+// Sent returns the count of values the sink's callback was called
+// with in the current or last run
+func (sk *Sink) Sent() int64 {
+	return sk.sent.Load()
+}
+
+// Dropped returns the count of values the sink dropped on overflow
+// in the current or last run
+func (sk *Sink) Dropped() int64 {
+	return sk.dropped.Load()
+}
+
+// AddSink registers send as an output stream fed every value sent,
+// after SendTs. At the end of a run Wait returns once each sink has
+// called send with its buffered values, a Quit discards them. Sinks
+// hold values past the send, don't combine them with
+// ReleaseAfterSend.
+func (pb *PlayBack) AddSink(send OnTsDataReady, opts SinkOptions) (*Sink, error) {
+	if send == nil {
+		return nil, errors.New("playBack: sink send required")
+	}
+	if opts.Buffer < 1 {
+		opts.Buffer = 1
+	}
+
+	pb.ctlMu.Lock()
+	defer pb.ctlMu.Unlock()
+	if pb.running {
+		return nil, errors.New("playBack: sinks can't be added while running")
+	}
+	sk := &Sink{send: send, opts: opts}
+	pb.sinks = append(pb.sinks, sk)
+	return sk, nil
+}
+
+// Subscribe returns a chan that receives every value sent in the next
+// run, after SendTs, and closes at the end of the run, so subscribe
+// again for each run. Values the subscriber hasn't received are
+// buffered per opts, and a full buffer is handled per its Overflow
+// policy. Values still buffered at the end of the run, even a quit
+// one, stay for the subscriber to receive. Subscribers hold values
+// past the send, don't combine them with ReleaseAfterSend.
+func (pb *PlayBack) Subscribe(opts SinkOptions) (<-chan TimeStamper, error) {
+	if opts.Buffer < 1 {
+		opts.Buffer = 1
+	}
+
+	pb.ctlMu.Lock()
+	defer pb.ctlMu.Unlock()
+	if pb.running {
+		return nil, errors.New("playBack: can't subscribe while running")
+	}
+	sk := &Sink{opts: opts, ch: make(chan TimeStamper, opts.Buffer)}
+	pb.sinks = append(pb.sinks, sk)
+	return sk.ch, nil
+}
+
+// Output returns a chan that receives every value sent, after SendTs
+// and the sinks, as an alternative to a callback, for example
+// for v := range pb.Output(). The chan is unbuffered and each send
+// waits on the receiver, so a slow receiver holds up the playback
+// clock, commands are still taken while waiting. The chan closes at
+// the end of the run, even a quit one.
+//
+// Output called while running returns the run's chan, the values sent
+// before the call aren't on it. Called while not running it returns
+// the chan for the next run, so call it before Play to receive every
+// value of a run, a run that has ended won't send on it.
+func (pb *PlayBack) Output() <-chan TimeStamper {
+	pb.ctlMu.Lock()
+	defer pb.ctlMu.Unlock()
+	if out := pb.output.Load(); out != nil {
+		return *out
+	}
+	out := make(chan TimeStamper)
+	pb.output.Store(&out)
+	return out
+}
+
+// startSinks starts each sink's goroutine for a run, the returned
+// func ends them once their buffers are sent and closes the
+// subscriptions and the Output chan
+func (pb *PlayBack) startSinks() func() {
+	pb.ctlMu.Lock()
+	sinks := pb.sinks
+	pb.ctlMu.Unlock()
+
+	quit := pb.quitChan
+	for _, sk := range sinks {
+		sk.sent.Store(0)
+		sk.dropped.Store(0)
+		if sk.send == nil {
+			// Subscribed, the subscriber receives from ch
+			continue
+		}
+		sk.ch = make(chan TimeStamper, sk.opts.Buffer)
+		pb.life.spawn(stageSinks, func() { sk.run(sk.ch, quit) })
+	}
+	return func() {
+		for _, sk := range sinks {
+			close(sk.ch)
+		}
+
+		// Subscriptions and Output last the run
+		pb.ctlMu.Lock()
+		if out := pb.output.Swap(nil); out != nil {
+			close(*out)
+		}
+		kept := pb.sinks[:0]
+		for _, sk := range pb.sinks {
+			if sk.send != nil {
+				kept = append(kept, sk)
+			}
+		}
+		pb.sinks = kept
+		pb.ctlMu.Unlock()
+	}
+}
+
+// fanOut hands tsData to each sink then Output, blocking only on an
+// OverflowBlock sink and Output. Returns false if quit while blocked
+func (pb *PlayBack) fanOut(tsData TimeStamper) bool {
+	for _, sk := range pb.sinks {
+		if !sk.offer(tsData) && !pb.offerWait(sk.ch, tsData) {
+			return false
+		}
+	}
+	if out := pb.output.Load(); out != nil {
+		return pb.offerWait(*out, tsData)
+	}
+	return true
+}
+
+// offerWait waits for ch to take tsData, applying the commands queued
+// meanwhile. Returns false if quit first
+func (pb *PlayBack) offerWait(ch chan<- TimeStamper, tsData TimeStamper) bool {
+	for {
+		select {
+		case ch <- tsData:
+			return true
+		case c := <-pb.cmdChan:
+			pb.apply(c)
+		case <-pb.quitChan:
+			return false
+		}
+	}
+}
+
+// offer buffers tsData, applying the overflow policy when full.
+// Returns false if an OverflowBlock sink is full
+func (sk *Sink) offer(tsData TimeStamper) bool {
+	select {
+	case sk.ch <- tsData:
+		return true
+	default:
+	}
+	if sk.opts.Overflow == OverflowBlock {
+		return false
+	}
+	if sk.opts.Overflow == OverflowDropOldest {
+		// The sink may have taken the oldest already
+		select {
+		case <-sk.ch:
+			sk.dropped.Add(1)
+		default:
+		}
+		sk.ch <- tsData
+		return true
+	}
+	sk.dropped.Add(1)
+	return true
+}
+
+// run calls send with each buffered value until ch closes or quit
+func (sk *Sink) run(ch <-chan TimeStamper, quit <-chan struct{}) {
+	for {
+		select {
+		case tsData, ok := <-ch:
+			if !ok {
+				return
+			}
+			sk.send(tsData)
+			sk.sent.Add(1)
+		case <-quit:
+			return
+		}
+	}
+}
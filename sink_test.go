@@ -0,0 +1,185 @@
+package gopeat
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSinks confirms a slow sink drops values on overflow while a
+// fast sink and the playback pacing are unaffected
+func TestSinks(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 20; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * 5 * time.Millisecond),
+			Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+
+	var fastVals []int64
+	fast, err := pb.AddSink(func(ts TimeStamper) error {
+		fastVals = append(fastVals, ts.(mockTsData).Val)
+		return nil
+	}, SinkOptions{Buffer: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slowVals []int64
+	slow, _ := pb.AddSink(func(ts TimeStamper) error {
+		slowVals = append(slowVals, ts.(mockTsData).Val)
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	}, SinkOptions{Buffer: 2, Overflow: OverflowDropOldest})
+
+	pb.Play()
+	pb.Wait()
+
+	// The slow sink would take 600ms, the run paces at 100ms
+	timings := pb.Timings()
+	if wall := timings[len(timings)-1].ActualWall.Sub(pb.WallStartTime); wall > 150*time.Millisecond {
+		t.Errorf("sends took %v; expected about 100ms", wall)
+	}
+	if len(fastVals) != 20 || fast.Sent() != 20 || fast.Dropped() != 0 {
+		t.Errorf("fast got %d, dropped %d; expected all 20", len(fastVals), fast.Dropped())
+	}
+	for i, v := range fastVals {
+		if v != int64(i+1) {
+			t.Fatalf("fast got %v; expected values in order", fastVals)
+		}
+	}
+	if slow.Dropped() == 0 || slow.Sent()+slow.Dropped() != 20 {
+		t.Errorf("slow sent %d, dropped %d; expected drops totaling 20",
+			slow.Sent(), slow.Dropped())
+	}
+	// Dropping the oldest keeps the latest value
+	if slowVals[len(slowVals)-1] != 20 {
+		t.Errorf("slow last value %d; expected 20", slowVals[len(slowVals)-1])
+	}
+}
+
+// TestSubscribe confirms two subscribers each receive every value in
+// order and their chans close with the run, a blocking subscriber
+// holds up the run until it catches up
+func TestSubscribe(t *testing.T) {
+	simStartTime := time.Now()
+	ss := pipelineSource(simStartTime, 20, 2*time.Millisecond)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), ss, 1, nil)
+
+	slowSub, err := pb.Subscribe(SinkOptions{Buffer: 1, Overflow: OverflowBlock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fastSub, _ := pb.Subscribe(SinkOptions{Buffer: 32})
+
+	var wg sync.WaitGroup
+	received := make([][]int64, 2)
+	for i, sub := range []<-chan TimeStamper{slowSub, fastSub} {
+		wg.Add(1)
+		go func(i int, sub <-chan TimeStamper) {
+			defer wg.Done()
+			for ts := range sub {
+				received[i] = append(received[i], ts.(mockTsData).Val)
+				if i == 0 {
+					time.Sleep(5 * time.Millisecond)
+				}
+			}
+		}(i, sub)
+	}
+	pb.Play()
+	pb.Wait()
+	wg.Wait()
+
+	var all []int64
+	for i := int64(1); i <= 20; i++ {
+		all = append(all, i)
+	}
+	expected := fmt.Sprint(all)
+	for i, vals := range received {
+		if fmt.Sprint(vals) != expected {
+			t.Errorf("Subscriber %d got %v; expected %s", i, vals, expected)
+		}
+	}
+
+	// The slow subscriber takes about 100ms, the run paces at 40ms
+	if pb.WallRunDur < 80*time.Millisecond {
+		t.Errorf("WallRunDur = %v; expected held up to about 100ms", pb.WallRunDur)
+	}
+	if len(pb.sinks) != 0 {
+		t.Errorf("%d subscriptions left after the run; expected 0", len(pb.sinks))
+	}
+}
+
+// TestSubscribeQuit confirms Quit ends a run held up by a subscriber
+// that stopped receiving
+func TestSubscribeQuit(t *testing.T) {
+	simStartTime := time.Now()
+	ss := pipelineSource(simStartTime, 20, time.Millisecond)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), ss, 1, nil)
+	sub, _ := pb.Subscribe(SinkOptions{Buffer: 1, Overflow: OverflowBlock})
+
+	pb.Play()
+	<-sub
+	pb.Quit()
+
+	waited := make(chan struct{})
+	go func() {
+		pb.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("Wait blocked")
+	}
+	if pb.Completion() != CompletedQuit {
+		t.Errorf("Completion = %d; expected CompletedQuit", pb.Completion())
+	}
+}
+
+// TestOutput ranges over Output for every value, each after SendTs,
+// and a quit run closes the chan
+func TestOutput(t *testing.T) {
+	simStartTime := time.Now()
+	ss := pipelineSource(simStartTime, 20, time.Millisecond)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), ss, 1, nil)
+	var sent atomic.Int64
+	pb.SendTs = func(ts TimeStamper) error {
+		sent.Add(1)
+		return nil
+	}
+
+	out := pb.Output()
+	pb.Play()
+	var vals []int64
+	for ts := range out {
+		vals = append(vals, ts.(mockTsData).Val)
+		if sent.Load() < int64(len(vals)) {
+			t.Errorf("Value %d on Output ahead of SendTs", len(vals))
+		}
+	}
+	pb.Wait()
+	if len(vals) != 20 || vals[0] != 1 || vals[19] != 20 {
+		t.Errorf("Output got %v; expected 1 to 20", vals)
+	}
+
+	// A run quit while the receiver holds it up
+	pb, _ = New("test", simStartTime, simStartTime.Add(time.Second),
+		pipelineSource(simStartTime, 20, time.Millisecond), 1, nil)
+	out = pb.Output()
+	pb.Play()
+	<-out
+	pb.Quit()
+	n := 0
+	for range out {
+		n++
+	}
+	pb.Wait()
+	if n > 1 || pb.Completion() != CompletedQuit {
+		t.Errorf("Got %d more values, Completion %d; expected at most 1, CompletedQuit",
+			n, pb.Completion())
+	}
+}
@@ -0,0 +1,154 @@
+package gopeat
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrUnsorted is the SliceTsSource error when RequireSorted is set
+// and a value is timestamped before the value ahead of it
+var ErrUnsorted = errors.New("sliceTsSource: values not sorted by timestamp")
+
+// SliceTsSource implements a time stamped data source for values
+// already in memory, which must be sorted by timestamp. Values before
+// the start time are skipped and the first value after the end time
+// ends the source.
+//
+// Unsorted values play with garbage timing, RequireSorted checks the
+// whole slice on the first Next and stops with an error wrapping
+// ErrUnsorted, reported by Err, instead of playing any of it. Only
+// the order is checked, the whole slice has to be sorted, values
+// outside the bracket included, and those are still skipped, not an
+// error.
+type SliceTsSource struct {
+	Values        []TimeStamper
+	RequireSorted bool
+
+	idx       int
+	checked   bool
+	done      bool
+	err       error
+	startTime time.Time
+	endTime   time.Time
+}
+
+// Next implements an iterator for the slice values in the bracket.
+// Once Next returns false it keeps returning false.
+func (ss *SliceTsSource) Next() (TimeStamper, bool) {
+	if ss.done {
+		return nil, false
+	}
+	if !ss.checked {
+		ss.checked = true
+		if ss.RequireSorted {
+			ss.err = ss.checkSorted()
+		}
+	}
+	for ss.err == nil && ss.idx < len(ss.Values) {
+		ts := ss.Values[ss.idx]
+		ss.idx++
+		tim := ts.GetTimeStamp()
+		if tim.Before(ss.startTime) {
+			continue
+		}
+		if !ss.endTime.IsZero() && tim.After(ss.endTime) {
+			break
+		}
+		return ts, true
+	}
+	ss.done = true
+	return nil, false
+}
+
+// checkSorted returns an ErrUnsorted error for the first value
+// timestamped before the one ahead of it
+func (ss *SliceTsSource) checkSorted() error {
+	for i := 1; i < len(ss.Values); i++ {
+		prev := ss.Values[i-1].GetTimeStamp()
+		tim := ss.Values[i].GetTimeStamp()
+		if tim.Before(prev) {
+			return fmt.Errorf("%w: value %d at %v before %v", ErrUnsorted,
+				i, tim, prev)
+		}
+	}
+	return nil
+}
+
+// Err returns the reason Next stopped, nil at the end of the values,
+// see ErrSource
+func (ss *SliceTsSource) Err() error {
+	return ss.err
+}
+
+// TimeRange returns the timestamps of the first and last values, see
+// TimeRanger
+func (ss *SliceTsSource) TimeRange() (first, last time.Time, ok bool) {
+	if len(ss.Values) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	return ss.Values[0].GetTimeStamp(),
+		ss.Values[len(ss.Values)-1].GetTimeStamp(), true
+}
+
+// Reset goes back to the first value, see Resettable
+func (ss *SliceTsSource) Reset() error {
+	ss.idx, ss.checked, ss.done, ss.err = 0, false, false, nil
+	return nil
+}
+
+// SeekTo moves to the first value at or after t, see Seekable
+func (ss *SliceTsSource) SeekTo(t time.Time) error {
+	ss.idx = sort.Search(len(ss.Values), func(i int) bool {
+		return !ss.Values[i].GetTimeStamp().Before(t)
+	})
+	ss.done = ss.err != nil
+	return nil
+}
+
+// SetStartTime sets min timpstamp for data provided
+func (ss *SliceTsSource) SetStartTime(startTime time.Time) {
+	ss.startTime = startTime
+}
+
+// SetEndTime sets max timpstamp for data provided
+func (ss *SliceTsSource) SetEndTime(endTime time.Time) {
+	ss.endTime = endTime
+}
+
+// NewFromSlice allocates a new Playback of in memory data, wrapped in
+// a SliceTsSource, for tests, demos and small datasets. data must be
+// sorted by timestamp, the values outside start to end are skipped.
+// See New.
+func NewFromSlice(symbol string,
+	startTime time.Time, endTime time.Time,
+	data []TimeStamper,
+	pbRate uint16,
+	cb OnTsDataReady) (*PlayBack, error) {
+	return New(symbol, startTime, endTime, &SliceTsSource{Values: data}, pbRate, cb)
+}
+
+// Count returns the count of values in the bracket without reading
+// them, see Counter
+func (ss *SliceTsSource) Count() (records int64, span time.Duration, err error) {
+	if ss.RequireSorted {
+		if err := ss.checkSorted(); err != nil {
+			return 0, 0, err
+		}
+	}
+	lo := sort.Search(len(ss.Values), func(i int) bool {
+		return !ss.Values[i].GetTimeStamp().Before(ss.startTime)
+	})
+	hi := len(ss.Values)
+	if !ss.endTime.IsZero() {
+		hi = sort.Search(len(ss.Values), func(i int) bool {
+			return ss.Values[i].GetTimeStamp().After(ss.endTime)
+		})
+	}
+	if hi <= lo {
+		return 0, 0, nil
+	}
+	return int64(hi - lo),
+		ss.Values[hi-1].GetTimeStamp().Sub(ss.Values[lo].GetTimeStamp()), nil
+}
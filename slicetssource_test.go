@@ -0,0 +1,103 @@
+package gopeat
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSliceTsSourceBracket confirms values outside the bracket are
+// skipped, not an error with RequireSorted
+func TestSliceTsSourceBracket(t *testing.T) {
+	base := time.Date(2013, 9, 3, 9, 0, 0, 0, time.UTC)
+	ss := &SliceTsSource{RequireSorted: true}
+	for i := 1; i <= 5; i++ {
+		ss.Values = append(ss.Values, mockTsData{
+			Tim: base.Add(time.Duration(i) * time.Second), Val: int64(i)})
+	}
+	ss.SetStartTime(base.Add(2 * time.Second))
+	ss.SetEndTime(base.Add(4 * time.Second))
+
+	var vals []int64
+	for ts, ok := ss.Next(); ok; ts, ok = ss.Next() {
+		vals = append(vals, ts.(mockTsData).Val)
+	}
+	if len(vals) != 3 || vals[0] != 2 || vals[2] != 4 {
+		t.Errorf("Got %v; expected [2 3 4]", vals)
+	}
+	if ss.Err() != nil {
+		t.Errorf("Err = %v; expected nil", ss.Err())
+	}
+}
+
+// TestRequireSorted plays unsorted values, the run fails before
+// sending anything, also when the unsorted values are past the end
+func TestRequireSorted(t *testing.T) {
+	simStartTime := time.Now()
+	ss := &SliceTsSource{RequireSorted: true, Values: []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(10 * time.Millisecond), Val: 1},
+		mockTsData{Tim: simStartTime.Add(30 * time.Millisecond), Val: 2},
+		mockTsData{Tim: simStartTime.Add(20 * time.Millisecond), Val: 3},
+	}}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), ss, 1, nil)
+
+	sent := 0
+	pb.SendTs = func(ts TimeStamper) error {
+		sent++
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+
+	if !errors.Is(pb.Err(), ErrUnsorted) {
+		t.Errorf("Err = %v; expected ErrUnsorted", pb.Err())
+	}
+	if pb.Completion() != CompletedError {
+		t.Errorf("Completion = %v; expected CompletedError", pb.Completion())
+	}
+	if sent != 0 {
+		t.Errorf("Sent %d; expected 0", sent)
+	}
+
+	// The whole slice is checked, not just the bracket
+	ss = &SliceTsSource{RequireSorted: true, Values: []TimeStamper{
+		mockTsData{Tim: simStartTime.Add(10 * time.Millisecond), Val: 1},
+		mockTsData{Tim: simStartTime.Add(2 * time.Minute), Val: 2},
+		mockTsData{Tim: simStartTime.Add(time.Minute + time.Second), Val: 3},
+	}}
+	ss.SetStartTime(simStartTime)
+	ss.SetEndTime(simStartTime.Add(time.Minute))
+	if _, ok := ss.Next(); ok || !errors.Is(ss.Err(), ErrUnsorted) {
+		t.Errorf("Next ok %t, Err = %v; expected ErrUnsorted", ok, ss.Err())
+	}
+}
+
+// TestNewFromSlice plays the slice values in the bracket
+func TestNewFromSlice(t *testing.T) {
+	simStartTime := time.Now()
+	var data []TimeStamper
+	for i := 1; i <= 5; i++ {
+		data = append(data, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * 10 * time.Millisecond),
+			Val: int64(i)})
+	}
+
+	var vals []int64
+	pb, err := NewFromSlice("test", simStartTime.Add(15*time.Millisecond),
+		simStartTime.Add(40*time.Millisecond), data, 1, func(ts TimeStamper) error {
+			vals = append(vals, ts.(mockTsData).Val)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb.Play()
+	pb.Wait()
+
+	if len(vals) != 3 || vals[0] != 2 || vals[2] != 4 {
+		t.Errorf("Got %v; expected [2 3 4]", vals)
+	}
+	if pb.Completion() != CompletedOK {
+		t.Errorf("Completion = %d; expected CompletedOK", pb.Completion())
+	}
+}
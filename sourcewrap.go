@@ -0,0 +1,75 @@
+package gopeat
+
+import (
+	"context"
+	"time"
+)
+
+// sourceWrap is a source read by a source wrapping it, like
+// FilterSource. It forwards TimeBracket, Err and cancellation to the
+// source. Playback leaves bracketing to the wrapper, so the values of
+// a source that doesn't implement TimeBracket are bracketed here.
+type sourceWrap struct {
+	src       TimeStampSource
+	startTime time.Time
+	endTime   time.Time
+}
+
+// next returns the source's next value in the bracket, a
+// CancellableSource is read with ctx
+func (sw *sourceWrap) next(ctx context.Context) (TimeStamper, bool) {
+	_, bracketed := sw.src.(TimeBracket)
+	return bracketedNext(ctx, sw.src, !bracketed, sw.startTime, sw.endTime)
+}
+
+// bracketedNext returns src's next value, a CancellableSource is read
+// with ctx. With bracket set the values before startTime are skipped
+// and a value after endTime ends the source, a zero endTime is open.
+func bracketedNext(ctx context.Context, src TimeStampSource, bracket bool,
+	startTime, endTime time.Time) (TimeStamper, bool) {
+	cs, cancellable := src.(CancellableSource)
+	for {
+		var tsData TimeStamper
+		var ok bool
+		if cancellable {
+			tsData, ok = cs.NextContext(ctx)
+		} else {
+			tsData, ok = src.Next()
+		}
+		if !ok || !bracket || tsData == nil {
+			return tsData, ok
+		}
+		tim := tsData.GetTimeStamp()
+		if tim.Before(startTime) {
+			continue
+		}
+		if !endTime.IsZero() && tim.After(endTime) {
+			return nil, false
+		}
+		return tsData, true
+	}
+}
+
+// Err returns the source's error, see ErrSource
+func (sw *sourceWrap) Err() error {
+	if es, ok := sw.src.(ErrSource); ok {
+		return es.Err()
+	}
+	return nil
+}
+
+// SetStartTime sets min timpstamp for data provided
+func (sw *sourceWrap) SetStartTime(startTime time.Time) {
+	sw.startTime = startTime
+	if tb, ok := sw.src.(TimeBracket); ok {
+		tb.SetStartTime(startTime)
+	}
+}
+
+// SetEndTime sets max timpstamp for data provided
+func (sw *sourceWrap) SetEndTime(endTime time.Time) {
+	sw.endTime = endTime
+	if tb, ok := sw.src.(TimeBracket); ok {
+		tb.SetEndTime(endTime)
+	}
+}
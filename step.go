@@ -0,0 +1,230 @@
+package gopeat
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrStepBackLimit is the StepBack error once the values cached for
+// stepping back run out
+var ErrStepBackLimit = errors.New("playBack: no earlier value cached to step back to")
+
+// errNotPaused is the StepBack error when the run isn't paused
+var errNotPaused = errors.New("playBack: not paused")
+
+// PreviewTs wraps a value sent by Step with PreviewSteps set, so the
+// client can tell a previewed value from an emitted one
+type PreviewTs struct {
+	TimeStamper
+}
+
+// ReplayTs wraps a value sent again by StepBack
+type ReplayTs struct {
+	TimeStamper
+}
+
+// emittedTs is a sent value cached for StepBack, with its raw bytes
+// and the source timestamp to seek to
+type emittedTs struct {
+	ts  TimeStamper
+	raw []byte
+	at  time.Time
+}
+
+// stepReq is a Step or Peek for the timer, which sets ts to the value
+// stepped or peeked. done closes once ts is peeked or sent.
+type stepReq struct {
+	peek    bool
+	preview bool
+	ts      TimeStamper
+
+	// Set by a StepBack past the cache, the source time to seek to
+	seekTo time.Time
+
+	err  error
+	done chan struct{}
+}
+
+// Step sends the next value right away while paused, the playback
+// stays paused. The value is counted and timed like any other send,
+// unless PreviewSteps is set. Pacing after Resume continues from the
+// stepped value. ok is false when not paused or nothing is left to
+// send. Step waits on the value when it isn't loaded yet.
+func (pb *PlayBack) Step() (TimeStamper, bool) {
+	return pb.requestStep(false)
+}
+
+// Peek returns the value the paused playback sends next, without
+// sending it. ok is false when not paused or nothing is left to send.
+func (pb *PlayBack) Peek() (TimeStamper, bool) {
+	return pb.requestStep(true)
+}
+
+// StepMode pauses the running playback for stepping through it one
+// value at a time, each Step sends the next value to SendTs and
+// returns once it's sent. Quit ends the run as usual and Resume goes
+// back to pacing from the last value stepped. StepMode returns once
+// the playback is paused, a no-op when not running.
+func (pb *PlayBack) StepMode() {
+	if pb.enqueue(command{kind: cmdStepMode}) {
+		pb.sync()
+	}
+}
+
+// IsStepping reports if the playback is in StepMode
+func (pb *PlayBack) IsStepping() bool {
+	pb.ctlMu.Lock()
+	defer pb.ctlMu.Unlock()
+	return pb.stepping
+}
+
+// requestStep hands a Step or Peek to the timer and waits on it
+func (pb *PlayBack) requestStep(peek bool) (TimeStamper, bool) {
+	pb.ctlMu.Lock()
+	if !pb.paused || !pb.running {
+		pb.ctlMu.Unlock()
+		return nil, false
+	}
+	stepReqs, quitChan := pb.stepReqs, pb.quitChan
+	timerDone, exited := pb.timerDone, pb.exited
+	pb.ctlMu.Unlock()
+
+	req := &stepReq{peek: peek, preview: pb.PreviewSteps,
+		done: make(chan struct{})}
+	select {
+	case stepReqs <- req:
+	case <-quitChan:
+		return nil, false
+	case <-timerDone:
+		return nil, false
+	}
+	select {
+	case <-req.done:
+		return req.ts, true
+	case <-exited:
+		return nil, false
+	}
+}
+
+// stepTimer is the dataTimer pacing state a Step moves on
+type stepTimer struct {
+	recCnt   *int64
+	prevWall *time.Time
+	prevSim  *time.Time
+	baseSim  *time.Time
+}
+
+// answerPeek answers a Peek with tsData, the value the timer holds
+func answerPeek(req *stepReq, tsData TimeStamper) {
+	req.ts = tsData
+	close(req.done)
+}
+
+// stepSend sends tsData to the controller unpaced for a Step, the
+// timer then moves on to the next value. Returns false if quit
+func (pb *PlayBack) stepSend(req *stepReq, tsData TimeStamper, st stepTimer) bool {
+	req.ts = tsData
+	select {
+	case pb.stepTs <- req:
+	case <-pb.quitChan:
+		return false
+	}
+	wallSendTime := pb.clock().Now()
+
+	// A preview isn't a record of the run
+	if req.preview {
+		*st.recCnt--
+	} else {
+		pb.recordTiming(RunTiming{
+			TsTime:     tsData.GetTimeStamp(),
+			RecNum:     *st.recCnt,
+			TargetWall: wallSendTime,
+			ActualWall: wallSendTime,
+		})
+	}
+
+	// The next send is paced from the stepped value
+	tsTime := pb.pacingTime(tsData, *st.prevSim)
+	*st.prevWall = wallSendTime
+	*st.prevSim, *st.baseSim = tsTime, tsTime
+	return true
+}
+
+// StepBack sends the value before the last one sent again while
+// paused, wrapped in a ReplayTs, and returns it. Each StepBack goes
+// back one more value, up to StepBackDepth values. The next value sent
+// by the run, by Step or Resume, is the one after the last sent as
+// before, and it starts the stepping back over.
+//
+// Past the cached values a Seekable source is seeked back to the
+// oldest of them, the one the last StepBack replayed, which is
+// returned. Step and Resume then replay the source from it, and
+// StepBack has nothing to go back to until values are sent again.
+// A source that isn't Seekable can't go back past the cache,
+// ErrStepBackLimit is returned.
+func (pb *PlayBack) StepBack() (TimeStamper, error) {
+	pb.ctlMu.Lock()
+	exited := pb.exited
+	pb.ctlMu.Unlock()
+
+	req := &stepReq{done: make(chan struct{})}
+	if !pb.enqueue(command{kind: cmdStepBack, step: req, done: req.done}) {
+		return nil, errNotPaused
+	}
+	select {
+	case <-req.done:
+	case <-exited:
+		return nil, errNotPaused
+	}
+	if req.err != nil || req.seekTo.IsZero() {
+		return req.ts, req.err
+	}
+
+	// Seeked by the loader, not the controller, which must keep
+	// handling commands while the source moves
+	if err := pb.SeekTo(req.seekTo); err != nil {
+		return nil, err
+	}
+	return req.ts, nil
+}
+
+// cacheEmitted caches a sent value for StepBack, keeping the last
+// sent and StepBackDepth before it, at is its source timestamp.
+// Values released to a TimeStamperPool are reused by the source, so
+// none are cached with ReleaseAfterSend. Only called by the controller
+func (pb *PlayBack) cacheEmitted(tsData TimeStamper, at time.Time, raw []byte) {
+	if pb.StepBackDepth <= 0 || pb.ReleaseAfterSend {
+		return
+	}
+	pb.emitted = append(pb.emitted, emittedTs{ts: tsData, raw: raw, at: at})
+	if len(pb.emitted) > pb.StepBackDepth+1 {
+		pb.emitted = pb.emitted[1:]
+	}
+	pb.backCnt = 0
+}
+
+// stepBack replays the next cached value back for req. Only called by
+// the controller
+func (pb *PlayBack) stepBack(req *stepReq) {
+	if !pb.paused {
+		req.err = errNotPaused
+		return
+	}
+	i := len(pb.emitted) - 2 - pb.backCnt
+	if i < 0 {
+		_, seekable := pb.TsDataSource.(Seekable)
+		if !seekable || len(pb.emitted) == 0 {
+			req.err = ErrStepBackLimit
+			return
+		}
+
+		// Rewind to the oldest cached value, the values cached are
+		// sent again by the run
+		req.ts, req.seekTo = pb.emitted[0].ts, pb.emitted[0].at
+		pb.emitted, pb.backCnt = nil, 0
+		return
+	}
+	pb.backCnt++
+	req.ts = pb.emitted[i].ts
+	pb.send(ReplayTs{TimeStamper: req.ts}, pb.emitted[i].raw)
+}
@@ -0,0 +1,286 @@
+package gopeat
+
+import (
+	"testing"
+	"time"
+)
+
+// stepRun plays 6 values 10ms apart caching 3 for StepBack, pausing
+// after the pauseAt send to run steps, and returns the values sent with previews negated and
+// replays offset by 100
+func stepRun(preview bool, pauseAt int64, steps func(pb *PlayBack)) ([]int64, *PlayBack) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 6; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * 10 * time.Millisecond),
+			Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), &mts, 1, nil)
+	pb.PreviewSteps = preview
+	pb.StepBackDepth = 3
+
+	var vals []int64
+	paused := make(chan struct{})
+	pb.SendTs = func(ts TimeStamper) error {
+		switch ts := ts.(type) {
+		case PreviewTs:
+			vals = append(vals, -ts.TimeStamper.(mockTsData).Val)
+		case ReplayTs:
+			vals = append(vals, 100+ts.TimeStamper.(mockTsData).Val)
+		case mockTsData:
+			vals = append(vals, ts.Val)
+			if ts.Val == pauseAt {
+				pb.Pause()
+				close(paused)
+			}
+		}
+		return nil
+	}
+
+	pb.Play()
+	<-paused
+	pb.sync()
+	steps(pb)
+	pb.Resume()
+	pb.Wait()
+	return vals, pb
+}
+
+// TestPeekStep peeks and steps while paused, the stepped value is a
+// record of the run and the rest play out after it
+func TestPeekStep(t *testing.T) {
+	vals, pb := stepRun(false, 2, func(pb *PlayBack) {
+		if ts, ok := pb.Peek(); !ok || ts.(mockTsData).Val != 3 {
+			t.Errorf("Peek = %v, %v; expected 3", ts, ok)
+		}
+		if ts, ok := pb.Step(); !ok || ts.(mockTsData).Val != 3 {
+			t.Errorf("Step = %v, %v; expected 3", ts, ok)
+		}
+		if ts, ok := pb.Peek(); !ok || ts.(mockTsData).Val != 4 {
+			t.Errorf("Peek = %v, %v; expected 4", ts, ok)
+		}
+	})
+
+	expected := []int64{1, 2, 3, 4, 5, 6}
+	if len(vals) != len(expected) {
+		t.Fatalf("Got %v; expected %v", vals, expected)
+	}
+	for i := range expected {
+		if vals[i] != expected[i] {
+			t.Fatalf("Got %v; expected %v", vals, expected)
+		}
+	}
+	if n := len(pb.Timings()); n != 6 {
+		t.Errorf("Timings = %d; expected 6", n)
+	}
+	if _, ok := pb.Step(); ok {
+		t.Error("Step ok after the run; expected not ok")
+	}
+}
+
+// TestPreviewSteps steps in preview mode, the previews are sent
+// wrapped and the official record count and timings are unchanged
+func TestPreviewSteps(t *testing.T) {
+	vals, pb := stepRun(true, 2, func(pb *PlayBack) {
+		for _, exp := range []int64{3, 4} {
+			if ts, ok := pb.Step(); !ok || ts.(mockTsData).Val != exp {
+				t.Errorf("Step = %v, %v; expected %d", ts, ok, exp)
+			}
+		}
+	})
+
+	expected := []int64{1, 2, -3, -4, 5, 6}
+	if len(vals) != len(expected) {
+		t.Fatalf("Got %v; expected %v", vals, expected)
+	}
+	for i := range expected {
+		if vals[i] != expected[i] {
+			t.Fatalf("Got %v; expected %v", vals, expected)
+		}
+	}
+
+	timings := pb.Timings()
+	if len(timings) != 4 {
+		t.Fatalf("Timings = %d; expected 4", len(timings))
+	}
+	for i, rt := range timings {
+		if rt.RecNum != int64(i+1) {
+			t.Errorf("Timing %d RecNum = %d; expected %d", i, rt.RecNum, i+1)
+		}
+	}
+}
+
+// TestStepBack steps back through the cached values in reverse order
+// until the cache runs out, then the run carries on
+func TestStepBack(t *testing.T) {
+	vals, pb := stepRun(false, 5, func(pb *PlayBack) {
+		for _, exp := range []int64{4, 3, 2} {
+			if ts, err := pb.StepBack(); err != nil || ts.(mockTsData).Val != exp {
+				t.Errorf("StepBack = %v, %v; expected %d", ts, err, exp)
+			}
+		}
+		if _, err := pb.StepBack(); err != ErrStepBackLimit {
+			t.Errorf("StepBack err = %v; expected ErrStepBackLimit", err)
+		}
+	})
+
+	expected := []int64{1, 2, 3, 4, 5, 104, 103, 102, 6}
+	if len(vals) != len(expected) {
+		t.Fatalf("Got %v; expected %v", vals, expected)
+	}
+	for i := range expected {
+		if vals[i] != expected[i] {
+			t.Fatalf("Got %v; expected %v", vals, expected)
+		}
+	}
+	if _, err := pb.StepBack(); err == nil {
+		t.Error("StepBack after the run; expected error")
+	}
+}
+
+// stepModeRun plays 6 values 50ms apart, calls steps in StepMode
+// once value 2 is sent, and returns the values sent
+func stepModeRun(t *testing.T, steps func(pb *PlayBack)) ([]int64, *PlayBack) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 6; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * 50 * time.Millisecond),
+			Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), &mts, 1, nil)
+
+	var vals []int64
+	second := make(chan struct{})
+	pb.SendTs = func(ts TimeStamper) error {
+		vals = append(vals, ts.(mockTsData).Val)
+		if ts.(mockTsData).Val == 2 {
+			close(second)
+		}
+		return nil
+	}
+
+	pb.Play()
+	<-second
+	pb.StepMode()
+	if !pb.IsStepping() {
+		t.Error("IsStepping false in StepMode")
+	}
+	steps(pb)
+	pb.Wait()
+	return vals, pb
+}
+
+// TestStepMode steps two values one at a time, then Resume paces the
+// rest from the last one stepped
+func TestStepMode(t *testing.T) {
+	var stepWall time.Time
+	vals, pb := stepModeRun(t, func(pb *PlayBack) {
+		for _, exp := range []int64{3, 4} {
+			if ts, ok := pb.Step(); !ok || ts.(mockTsData).Val != exp {
+				t.Errorf("Step = %v, %v; expected %d", ts, ok, exp)
+			}
+		}
+		stepWall = time.Now()
+		pb.Resume()
+		pb.sync()
+		if pb.IsStepping() {
+			t.Error("IsStepping true after Resume")
+		}
+	})
+
+	expected := []int64{1, 2, 3, 4, 5, 6}
+	if len(vals) != len(expected) {
+		t.Fatalf("Got %v; expected %v", vals, expected)
+	}
+	for i := range expected {
+		if vals[i] != expected[i] {
+			t.Fatalf("Got %v; expected %v", vals, expected)
+		}
+	}
+
+	// Value 5 is paced 50ms after the last step, not sent right away
+	timings := pb.Timings()
+	if gap := timings[4].ActualWall.Sub(stepWall); gap < 40*time.Millisecond {
+		t.Errorf("Value 5 sent %v after the last step; expected about 50ms", gap)
+	}
+}
+
+// TestStepModeQuit quits while stepping, the run ends without
+// sending anything more
+func TestStepModeQuit(t *testing.T) {
+	vals, pb := stepModeRun(t, func(pb *PlayBack) {
+		if ts, ok := pb.Step(); !ok || ts.(mockTsData).Val != 3 {
+			t.Errorf("Step = %v, %v; expected 3", ts, ok)
+		}
+		pb.Quit()
+	})
+
+	if len(vals) != 3 {
+		t.Errorf("Got %v; expected [1 2 3]", vals)
+	}
+	if pb.Completion() != CompletedQuit {
+		t.Errorf("Completion = %v; expected CompletedQuit", pb.Completion())
+	}
+	if pb.IsStepping() {
+		t.Error("IsStepping true after the run")
+	}
+}
+
+// TestStepBackSeek steps back past the cache of a Seekable source,
+// the run is rewound to the oldest cached value and replays from it
+func TestStepBackSeek(t *testing.T) {
+	simStartTime := time.Now()
+	src := pipelineSource(simStartTime, 200, 10*time.Millisecond)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Minute), src, 1, nil)
+	pb.StepBackDepth = 3
+	if err := pb.SetBufferSizes(1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	var vals []int64
+	paused := make(chan struct{})
+	pb.SendTs = func(ts TimeStamper) error {
+		switch ts := ts.(type) {
+		case ReplayTs:
+			vals = append(vals, 100+ts.TimeStamper.(mockTsData).Val)
+		case mockTsData:
+			vals = append(vals, ts.Val)
+			if ts.Val == 5 {
+				pb.Pause()
+				close(paused)
+			}
+		}
+		return nil
+	}
+	pb.Play()
+	<-paused
+	pb.sync()
+
+	for _, exp := range []int64{4, 3, 2} {
+		if ts, err := pb.StepBack(); err != nil || ts.(mockTsData).Val != exp {
+			t.Errorf("StepBack = %v, %v; expected %d", ts, err, exp)
+		}
+	}
+	if ts, err := pb.StepBack(); err != nil || ts.(mockTsData).Val != 2 {
+		t.Errorf("StepBack past the cache = %v, %v; expected 2", ts, err)
+	}
+	for _, exp := range []int64{2, 3} {
+		if ts, ok := pb.Step(); !ok || ts.(mockTsData).Val != exp {
+			t.Errorf("Step = %v, %v; expected %d", ts, ok, exp)
+		}
+	}
+	pb.Quit()
+	pb.Wait()
+
+	expected := []int64{1, 2, 3, 4, 5, 104, 103, 102, 2, 3}
+	if len(vals) != len(expected) {
+		t.Fatalf("Got %v; expected %v", vals, expected)
+	}
+	for i := range expected {
+		if vals[i] != expected[i] {
+			t.Fatalf("Got %v; expected %v", vals, expected)
+		}
+	}
+}
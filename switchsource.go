@@ -0,0 +1,71 @@
+package gopeat
+
+import (
+	"context"
+	"time"
+)
+
+// SwitchedSource is a time stamped data source that plays one source
+// up to a timestamp and another from it on, see SwitchSource
+type SwitchedSource struct {
+	at       time.Time
+	a, b     sourceWrap
+	switched bool
+}
+
+// SwitchSource returns a source that provides a's records before at
+// and b's records at or after at, as one stream. It's a hard switch
+// for A/B comparing two versions of the data, not a merge. b is
+// seeked to at by moving its start time up, b's records before at
+// are skipped for sources that don't bracket exactly.
+func SwitchSource(at time.Time, a, b TimeStampSource) *SwitchedSource {
+	return &SwitchedSource{at: at, a: sourceWrap{src: a}, b: sourceWrap{src: b}}
+}
+
+// Next implements an iterator for a's records then b's
+func (ss *SwitchedSource) Next() (TimeStamper, bool) {
+	return ss.NextContext(context.Background())
+}
+
+// NextContext is Next, returning false once ctx is done, see
+// CancellableSource
+func (ss *SwitchedSource) NextContext(ctx context.Context) (TimeStamper, bool) {
+	if !ss.switched {
+		tsData, ok := ss.a.next(ctx)
+		if ok && (tsData == nil || tsData.GetTimeStamp().Before(ss.at)) {
+			return tsData, true
+		}
+		ss.switched = true
+	}
+	for ctx.Err() == nil {
+		tsData, ok := ss.b.next(ctx)
+		if !ok || tsData == nil || !tsData.GetTimeStamp().Before(ss.at) {
+			return tsData, ok
+		}
+	}
+	return nil, false
+}
+
+// Err returns the error of the source that stopped, see ErrSource
+func (ss *SwitchedSource) Err() error {
+	if err := ss.a.Err(); err != nil {
+		return err
+	}
+	return ss.b.Err()
+}
+
+// SetStartTime sets min timpstamp for data provided, b starts at the
+// switch time when that's later
+func (ss *SwitchedSource) SetStartTime(startTime time.Time) {
+	ss.a.SetStartTime(startTime)
+	if startTime.Before(ss.at) {
+		startTime = ss.at
+	}
+	ss.b.SetStartTime(startTime)
+}
+
+// SetEndTime sets max timpstamp for data provided
+func (ss *SwitchedSource) SetEndTime(endTime time.Time) {
+	ss.a.SetEndTime(endTime)
+	ss.b.SetEndTime(endTime)
+}
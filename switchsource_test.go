@@ -0,0 +1,102 @@
+package gopeat
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// mockBracketedDs is a slice backed source that honors its start
+// time, recording what it was set to
+type mockBracketedDs struct {
+	mockSliceBackedDs
+	startTime time.Time
+}
+
+func (st *mockBracketedDs) Next() (TimeStamper, bool) {
+	for {
+		ts, ok := st.mockSliceBackedDs.Next()
+		if !ok || !ts.GetTimeStamp().Before(st.startTime) {
+			return ts, ok
+		}
+	}
+}
+
+func (st *mockBracketedDs) SetStartTime(startTime time.Time) {
+	st.startTime = startTime
+}
+
+// TestSwitchSource confirms a's records play before the switch time
+// and b's at and after it
+func TestSwitchSource(t *testing.T) {
+	base := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	recs := func(version int64) []TimeStamper {
+		var ts []TimeStamper
+		for sec := 0; sec < 5; sec++ {
+			ts = append(ts, mockTsData{
+				Tim: base.Add(time.Duration(sec) * time.Second),
+				Val: version*10 + int64(sec)})
+		}
+		return ts
+	}
+	a := &mockSliceBackedDs{TimeStampers: recs(1)}
+	b := &mockBracketedDs{mockSliceBackedDs: mockSliceBackedDs{TimeStampers: recs(2)}}
+
+	at := base.Add(2 * time.Second)
+	ss := SwitchSource(at, a, b)
+	ss.SetStartTime(base)
+	ss.SetEndTime(base.Add(time.Minute))
+
+	if !b.startTime.Equal(at) {
+		t.Errorf("b start %v; expected the switch time %v", b.startTime, at)
+	}
+
+	var vals []int64
+	for ts, ok := ss.Next(); ok; ts, ok = ss.Next() {
+		vals = append(vals, ts.(mockTsData).Val)
+	}
+	expected := []int64{10, 11, 22, 23, 24}
+	if len(vals) != len(expected) {
+		t.Fatalf("Got %v; expected %v", vals, expected)
+	}
+	for i := range expected {
+		if vals[i] != expected[i] {
+			t.Fatalf("Got %v; expected %v", vals, expected)
+		}
+	}
+}
+
+// TestSwitchSourceBracket confirms the records of sources that don't
+// bracket themselves are kept to the playback bracket, and a nil
+// record is passed on for playback to report
+func TestSwitchSourceBracket(t *testing.T) {
+	simStartTime := time.Now()
+	rec := func(d time.Duration, val int64) TimeStamper {
+		return mockTsData{Tim: simStartTime.Add(d), Val: val}
+	}
+	a := &mockNextOnlyDs{TimeStampers: []TimeStamper{
+		rec(-time.Hour, 1), rec(time.Millisecond, 2), rec(3*time.Millisecond, 3),
+	}}
+	b := &mockNextOnlyDs{TimeStampers: []TimeStamper{
+		rec(time.Millisecond, 20), rec(2*time.Millisecond, 21), rec(2*time.Hour, 22),
+	}}
+	ss := SwitchSource(simStartTime.Add(2*time.Millisecond), a, b)
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Hour), ss, 1, nil)
+
+	var vals []int64
+	pb.SendTs = func(ts TimeStamper) error {
+		vals = append(vals, ts.(mockTsData).Val)
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+	if fmt.Sprint(vals) != "[2 21]" {
+		t.Errorf("Got %v; expected [2 21]", vals)
+	}
+
+	a = &mockNextOnlyDs{TimeStampers: []TimeStamper{nil}}
+	ss = SwitchSource(simStartTime, a, b)
+	if ts, ok := ss.Next(); ts != nil || !ok {
+		t.Errorf("Next = %v, %t; expected nil, true", ts, ok)
+	}
+}
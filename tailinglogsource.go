@@ -0,0 +1,167 @@
+package gopeat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// LineToTs converts a log line, without its newline, to a TimeStamper
+// value. The line's bytes are reused after the call returns.
+type LineToTs func([]byte) (TimeStamper, error)
+
+// defaultPollEvery is how often a TailingLogSource checks for appends
+const defaultPollEvery = 100 * time.Millisecond
+
+// TailingLogSource implements a time stamped data source for a
+// growing, timestamp sorted, log file. The existing lines in the
+// bracket are replayed, then, while the end time is in the future,
+// the file is followed and appended lines are provided as they're
+// written. Stop ends the follow, a Next waiting on appends returns
+// right away. It's a CancellableSource, Quit ends the follow too.
+type TailingLogSource struct {
+	Path       string
+	LineTsConv LineToTs
+
+	// PollEvery is how often the file is checked for appends,
+	// 100ms when zero
+	PollEvery time.Duration
+
+	// RawCapture provides each value as a RawTs holding the log line
+	// it was parsed from, for PlayBack.SendTsRaw
+	RawCapture bool
+
+	file      *os.File
+	reader    *bufio.Reader
+	partial   []byte
+	startTime time.Time
+	endTime   time.Time
+	err       error
+	done      bool
+	initOnce  sync.Once
+	stopOnce  sync.Once
+	stop      chan struct{}
+}
+
+// Next implements an iterator for the lines of the log, blocks
+// waiting on appends while following
+func (st *TailingLogSource) Next() (TimeStamper, bool) {
+	return st.NextContext(context.Background())
+}
+
+// NextContext is Next, ending the follow once ctx is done, see
+// CancellableSource
+func (st *TailingLogSource) NextContext(ctx context.Context) (TimeStamper, bool) {
+	if st.done {
+		return nil, false
+	}
+	if st.file == nil {
+		st.file, st.err = os.Open(st.Path)
+		if st.err != nil {
+			st.done = true
+			return nil, false
+		}
+		st.reader = bufio.NewReader(st.file)
+	}
+	stop := st.stopChan()
+	for {
+		line, err := st.reader.ReadBytes('\n')
+		st.partial = append(st.partial, line...)
+		if err == io.EOF {
+			// Wait on the rest of the line, or the next one
+			if !st.follow(ctx, stop) {
+				break
+			}
+			continue
+		} else if err != nil {
+			st.err = err
+			break
+		}
+
+		line = bytes.TrimRight(st.partial, "\r\n")
+		st.partial = st.partial[:0]
+		if len(line) == 0 {
+			continue
+		}
+		ts, err := st.LineTsConv(line)
+		if err != nil {
+			st.err = err
+			break
+		}
+		if ts.GetTimeStamp().Before(st.startTime) {
+			continue
+		}
+		if ts.GetTimeStamp().After(st.endTime) {
+			break
+		}
+		if st.RawCapture {
+			return RawTs{TimeStamper: ts, Raw: bytes.Clone(line)}, true
+		}
+		return ts, true
+	}
+	st.file.Close()
+	st.done = true
+	return nil, false
+}
+
+// follow waits a poll interval for appends, returns false when done
+// following, stopped, canceled or the end time has passed
+func (st *TailingLogSource) follow(ctx context.Context, stop <-chan struct{}) bool {
+	if !time.Now().Before(st.endTime) {
+		return false
+	}
+	poll := st.PollEvery
+	if poll <= 0 {
+		poll = defaultPollEvery
+	}
+	timer := time.NewTimer(poll)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// stopChan returns the chan closed by Stop
+func (st *TailingLogSource) stopChan() chan struct{} {
+	st.initOnce.Do(func() { st.stop = make(chan struct{}) })
+	return st.stop
+}
+
+// Stop ends following the log, safe to call from any goroutine and
+// more than once
+func (st *TailingLogSource) Stop() {
+	stop := st.stopChan()
+	st.stopOnce.Do(func() { close(stop) })
+}
+
+// Err returns the open, read or conversion error that stopped Next,
+// nil if Next stopped at the end time, end of data or Stop
+func (st *TailingLogSource) Err() error {
+	return st.err
+}
+
+// SetStartTime sets min timpstamp for data provided
+func (st *TailingLogSource) SetStartTime(startTime time.Time) {
+	st.startTime = startTime
+}
+
+// SetEndTime sets max timpstamp for data provided, a future end time
+// follows the log
+func (st *TailingLogSource) SetEndTime(endTime time.Time) {
+	st.endTime = endTime
+}
+
+// Count returns ErrNotCountable, the log is yet to be written, see
+// Counter
+func (st *TailingLogSource) Count() (int64, time.Duration, error) {
+	return 0, 0, ErrNotCountable
+}
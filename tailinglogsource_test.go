@@ -0,0 +1,84 @@
+package gopeat
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTailingLogSource confirms the existing lines in the bracket are
+// replayed, then appended lines are picked up until Stop
+func TestTailingLogSource(t *testing.T) {
+	start := time.Now().Add(-time.Minute)
+	line := func(offset time.Duration, val int) string {
+		return start.Add(offset).Format(time.RFC3339Nano) + " " +
+			strconv.Itoa(val) + "\n"
+	}
+	path := filepath.Join(t.TempDir(), "trades.log")
+	existing := line(-time.Second, 0) + line(time.Second, 1) + line(2*time.Second, 2)
+	if err := os.WriteFile(path, []byte(existing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	st := &TailingLogSource{
+		Path:      path,
+		PollEvery: 10 * time.Millisecond,
+		LineTsConv: func(line []byte) (TimeStamper, error) {
+			var ts mockTsData
+			tim, val, _ := strings.Cut(string(line), " ")
+			var err error
+			if ts.Tim, err = time.Parse(time.RFC3339Nano, tim); err != nil {
+				return nil, err
+			}
+			ts.Val, err = strconv.ParseInt(val, 10, 64)
+			return ts, err
+		},
+	}
+	st.SetStartTime(start)
+	st.SetEndTime(start.Add(time.Hour))
+
+	next := func(expected int64) {
+		t.Helper()
+		ts, ok := st.Next()
+		if !ok {
+			t.Fatalf("Next stopped, err %v; expected %d", st.Err(), expected)
+		}
+		if val := ts.(mockTsData).Val; val != expected {
+			t.Fatalf("Next = %d; expected %d", val, expected)
+		}
+	}
+
+	// Existing lines, the first is before start
+	next(1)
+	next(2)
+
+	// Append a line in two writes, the follow waits out the partial line
+	go func() {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		appended := line(3*time.Second, 3)
+		time.Sleep(30 * time.Millisecond)
+		f.WriteString(appended[:10])
+		time.Sleep(30 * time.Millisecond)
+		f.WriteString(appended[10:])
+	}()
+	next(3)
+
+	// Stop ends a Next waiting on appends
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		st.Stop()
+	}()
+	if ts, ok := st.Next(); ok {
+		t.Errorf("Next = %v after Stop; expected done", ts)
+	}
+	if st.Err() != nil {
+		t.Errorf("Err = %v; expected nil", st.Err())
+	}
+}
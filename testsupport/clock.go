@@ -0,0 +1,97 @@
+package testsupport
+
+import (
+	"sync"
+	"time"
+
+	"github.com/michelpmcdonald/go-peat"
+)
+
+// FakeClock is a gopeat.Clock that only moves when told to, so
+// pacing can be tested without real sleeps. Advance moves it by
+// hand, with AutoAdvance set each sleep moves it to the sleep's end
+// at once, playing hours of data in milliseconds.
+type FakeClock struct {
+	// AutoAdvance, when set, moves the clock to the end of each
+	// sleep as soon as it's slept on. Set before the run.
+	AutoAdvance bool
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+// fakeWaiter is a sleep on a FakeClock, c gets the time once it's at
+type fakeWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+var _ gopeat.Clock = (*FakeClock)(nil)
+
+// NewFakeClock returns a FakeClock stopped at now
+func NewFakeClock(now time.Time) *FakeClock {
+	fc := &FakeClock{now: now}
+	fc.cond = sync.NewCond(&fc.mu)
+	return fc
+}
+
+// Now returns the fake time
+func (fc *FakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+// After returns a chan that gets the fake time once the clock has
+// advanced d
+func (fc *FakeClock) After(d time.Duration) <-chan time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	c := make(chan time.Time, 1)
+	at := fc.now.Add(d)
+	if fc.AutoAdvance && at.After(fc.now) {
+		fc.now = at
+	}
+	if !at.After(fc.now) {
+		c <- fc.now
+		return c
+	}
+	fc.waiters = append(fc.waiters, fakeWaiter{at: at, c: c})
+	fc.cond.Broadcast()
+	return c
+}
+
+// Advance moves the clock on d, firing the sleeps that end by then
+func (fc *FakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.now = fc.now.Add(d)
+	waiting := fc.waiters[:0]
+	for _, w := range fc.waiters {
+		if w.at.After(fc.now) {
+			waiting = append(waiting, w)
+			continue
+		}
+		w.c <- fc.now
+	}
+	fc.waiters = waiting
+}
+
+// Waiters returns the number of sleeps not yet over
+func (fc *FakeClock) Waiters() int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return len(fc.waiters)
+}
+
+// BlockUntil blocks until at least n sleeps are waiting on the
+// clock, so a test can Advance once the code under test is asleep
+func (fc *FakeClock) BlockUntil(n int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for len(fc.waiters) < n {
+		fc.cond.Wait()
+	}
+}
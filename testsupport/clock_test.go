@@ -0,0 +1,77 @@
+package testsupport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/michelpmcdonald/go-peat"
+)
+
+// TestFakeClock confirms sleeps end only once the clock is advanced
+// past them
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	fc := NewFakeClock(start)
+	short, long := fc.After(time.Second), fc.After(time.Minute)
+	fc.BlockUntil(2)
+
+	fc.Advance(30 * time.Second)
+	select {
+	case now := <-short:
+		if !now.Equal(start.Add(30 * time.Second)) {
+			t.Errorf("short fired at %v; expected start + 30s", now)
+		}
+	default:
+		t.Error("short sleep not over after 30s")
+	}
+	select {
+	case <-long:
+		t.Error("long sleep over after 30s")
+	default:
+	}
+	if n := fc.Waiters(); n != 1 {
+		t.Errorf("Waiters = %d; expected 1", n)
+	}
+
+	fc.Advance(30 * time.Second)
+	if now := <-long; !now.Equal(start.Add(time.Minute)) {
+		t.Errorf("long fired at %v; expected start + 1m", now)
+	}
+	if c := fc.After(0); len(c) != 1 {
+		t.Error("zero sleep not over at once")
+	}
+}
+
+// TestFakeClockPlayback confirms an auto advancing clock plays a day
+// of data without real sleeps, on schedule
+func TestFakeClockPlayback(t *testing.T) {
+	start := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	src := NewSyntheticSource(start.Add(time.Hour), time.Hour, 24)
+	var sent int
+	pb, err := gopeat.New("test", start, start.Add(24*time.Hour), src, 1,
+		func(ts gopeat.TimeStamper) error {
+			sent++
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := NewFakeClock(start)
+	fc.AutoAdvance = true
+	pb.Clock = fc
+
+	wallStart := time.Now()
+	if res := pb.Run(); res.Completion != gopeat.CompletedOK || sent != 24 {
+		t.Errorf("Completion %d, %d sends; expected CompletedOK, 24",
+			res.Completion, sent)
+	}
+	if wallDur := time.Since(wallStart); wallDur > 5*time.Second {
+		t.Errorf("run took %v; expected no real sleeps", wallDur)
+	}
+	if now := fc.Now(); !now.Equal(start.Add(24 * time.Hour)) {
+		t.Errorf("clock at %v; expected start + 24h", now)
+	}
+	if ds := pb.DriftStats(); ds.MaxDriftMs != 0 {
+		t.Errorf("MaxDriftMs %f; expected 0", ds.MaxDriftMs)
+	}
+}
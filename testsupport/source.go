@@ -0,0 +1,184 @@
+// Package testsupport provides mock time stamped data sources for
+// unit testing code built on gopeat, mirroring the mocks gopeat's
+// own tests use.
+package testsupport
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/michelpmcdonald/go-peat"
+)
+
+// Record is a minimal TimeStamper value
+type Record struct {
+	Tim time.Time
+	Val int64
+}
+
+// GetTimeStamp returns the record time
+func (r Record) GetTimeStamp() time.Time {
+	return r.Tim
+}
+
+// SliceSource provides the values of a slice, which must be sorted
+// by timestamp. Values outside the playback time bracket are skipped.
+type SliceSource struct {
+	values    []gopeat.TimeStamper
+	idx       int
+	startTime time.Time
+	endTime   time.Time
+}
+
+// NewSliceSource returns a source providing values in order
+func NewSliceSource(values ...gopeat.TimeStamper) *SliceSource {
+	return &SliceSource{values: values}
+}
+
+// Next implements an iterator for the slice values in the bracket
+func (ss *SliceSource) Next() (gopeat.TimeStamper, bool) {
+	for ss.idx < len(ss.values) {
+		ts := ss.values[ss.idx]
+		ss.idx++
+		if inBracket(ts.GetTimeStamp(), ss.startTime, ss.endTime) {
+			return ts, true
+		}
+	}
+	return nil, false
+}
+
+// Err always returns nil, a slice can't fail
+func (ss *SliceSource) Err() error {
+	return nil
+}
+
+// TimeRange returns the timestamps of the first and last values, see
+// gopeat.TimeRanger
+func (ss *SliceSource) TimeRange() (first, last time.Time, ok bool) {
+	if len(ss.values) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	return ss.values[0].GetTimeStamp(),
+		ss.values[len(ss.values)-1].GetTimeStamp(), true
+}
+
+// SetStartTime sets min timpstamp for data provided
+func (ss *SliceSource) SetStartTime(startTime time.Time) {
+	ss.startTime = startTime
+}
+
+// SetEndTime sets max timpstamp for data provided
+func (ss *SliceSource) SetEndTime(endTime time.Time) {
+	ss.endTime = endTime
+}
+
+// SyntheticSource generates Count Records, Val 1 to Count, Every
+// apart starting at Start. Records outside the playback time bracket
+// are skipped.
+type SyntheticSource struct {
+	Start time.Time
+	Every time.Duration
+	Count int
+
+	n         int
+	startTime time.Time
+	endTime   time.Time
+}
+
+// NewSyntheticSource returns a source generating count records every
+// apart starting at start
+func NewSyntheticSource(start time.Time, every time.Duration, count int) *SyntheticSource {
+	return &SyntheticSource{Start: start, Every: every, Count: count}
+}
+
+// Next implements an iterator for the generated records in the bracket
+func (ss *SyntheticSource) Next() (gopeat.TimeStamper, bool) {
+	for ss.n < ss.Count {
+		ss.n++
+		rec := Record{Tim: ss.at(ss.n), Val: int64(ss.n)}
+		if rec.Tim.After(ss.endTime) && !ss.endTime.IsZero() {
+			break
+		}
+		if inBracket(rec.Tim, ss.startTime, ss.endTime) {
+			return rec, true
+		}
+	}
+	return nil, false
+}
+
+// at returns the time of record n
+func (ss *SyntheticSource) at(n int) time.Time {
+	return ss.Start.Add(time.Duration(n-1) * ss.Every)
+}
+
+// Err always returns nil, generating can't fail
+func (ss *SyntheticSource) Err() error {
+	return nil
+}
+
+// TimeRange returns the times of the first and last records, see
+// gopeat.TimeRanger
+func (ss *SyntheticSource) TimeRange() (first, last time.Time, ok bool) {
+	if ss.Count < 1 {
+		return time.Time{}, time.Time{}, false
+	}
+	return ss.at(1), ss.at(ss.Count), true
+}
+
+// SetStartTime sets min timpstamp for data provided
+func (ss *SyntheticSource) SetStartTime(startTime time.Time) {
+	ss.startTime = startTime
+}
+
+// SetEndTime sets max timpstamp for data provided
+func (ss *SyntheticSource) SetEndTime(endTime time.Time) {
+	ss.endTime = endTime
+}
+
+// BlockingSource blocks in Next until Release, for testing how code
+// copes with a stalled source. Next then returns no value.
+type BlockingSource struct {
+	release    chan struct{}
+	once       sync.Once
+	nextCalled atomic.Bool
+}
+
+// NewBlockingSource returns a source that blocks until Release
+func NewBlockingSource() *BlockingSource {
+	return &BlockingSource{release: make(chan struct{})}
+}
+
+// Next blocks until Release is called, then reports no more data
+func (bs *BlockingSource) Next() (gopeat.TimeStamper, bool) {
+	bs.nextCalled.Store(true)
+	<-bs.release
+	return nil, false
+}
+
+// Release unblocks Next, safe to call more than once
+func (bs *BlockingSource) Release() {
+	bs.once.Do(func() { close(bs.release) })
+}
+
+// NextCalled reports if Next has been called
+func (bs *BlockingSource) NextCalled() bool {
+	return bs.nextCalled.Load()
+}
+
+// SetStartTime is a no-op, the source has no data
+func (bs *BlockingSource) SetStartTime(startTime time.Time) {
+}
+
+// SetEndTime is a no-op, the source has no data
+func (bs *BlockingSource) SetEndTime(endTime time.Time) {
+}
+
+// inBracket reports if tim is in the start to end bracket, a zero
+// start or end leaves that side open
+func inBracket(tim, start, end time.Time) bool {
+	if !start.IsZero() && tim.Before(start) {
+		return false
+	}
+	return end.IsZero() || !tim.After(end)
+}
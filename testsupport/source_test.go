@@ -0,0 +1,125 @@
+package testsupport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/michelpmcdonald/go-peat"
+)
+
+// Sources implement the gopeat interfaces
+var (
+	_ gopeat.TimeStampSource = (*SliceSource)(nil)
+	_ gopeat.TimeBracket     = (*SliceSource)(nil)
+	_ gopeat.ErrSource       = (*SliceSource)(nil)
+	_ gopeat.TimeRanger      = (*SliceSource)(nil)
+	_ gopeat.TimeStampSource = (*SyntheticSource)(nil)
+	_ gopeat.TimeBracket     = (*SyntheticSource)(nil)
+	_ gopeat.ErrSource       = (*SyntheticSource)(nil)
+	_ gopeat.TimeRanger      = (*SyntheticSource)(nil)
+	_ gopeat.TimeStampSource = (*BlockingSource)(nil)
+	_ gopeat.TimeBracket     = (*BlockingSource)(nil)
+)
+
+// drain returns the Vals of the records src provides
+func drain(src gopeat.TimeStampSource) []int64 {
+	var vals []int64
+	for ts, ok := src.Next(); ok; ts, ok = src.Next() {
+		vals = append(vals, ts.(Record).Val)
+	}
+	return vals
+}
+
+func equal(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestSliceSource confirms values are provided in order within the
+// bracket
+func TestSliceSource(t *testing.T) {
+	start := time.Date(2013, 9, 1, 17, 0, 0, 0, time.UTC)
+	var values []gopeat.TimeStamper
+	for i := 1; i <= 5; i++ {
+		values = append(values,
+			Record{Tim: start.Add(time.Duration(i) * time.Second), Val: int64(i)})
+	}
+	ss := NewSliceSource(values...)
+	first, last, ok := ss.TimeRange()
+	if !ok || !first.Equal(values[0].GetTimeStamp()) ||
+		!last.Equal(values[4].GetTimeStamp()) {
+		t.Errorf("TimeRange = %v, %v, %t; expected 1s to 5s", first, last, ok)
+	}
+	ss.SetStartTime(start.Add(2 * time.Second))
+	ss.SetEndTime(start.Add(4 * time.Second))
+
+	if vals := drain(ss); !equal(vals, []int64{2, 3, 4}) {
+		t.Errorf("got %v; expected [2 3 4]", vals)
+	}
+	if ss.Err() != nil {
+		t.Errorf("Err = %v; expected nil", ss.Err())
+	}
+}
+
+// TestSyntheticSource confirms records are generated at the interval
+// within the bracket and play back in full
+func TestSyntheticSource(t *testing.T) {
+	start := time.Now()
+	ss := NewSyntheticSource(start, 10*time.Millisecond, 5)
+	ss.SetStartTime(start.Add(10 * time.Millisecond))
+	ss.SetEndTime(start.Add(30 * time.Millisecond))
+	if vals := drain(ss); !equal(vals, []int64{2, 3, 4}) {
+		t.Errorf("got %v; expected [2 3 4]", vals)
+	}
+	if _, last, _ := ss.TimeRange(); !last.Equal(start.Add(40 * time.Millisecond)) {
+		t.Errorf("TimeRange last = %v; expected start + 40ms", last)
+	}
+
+	// Plays in a real playback
+	src := NewSyntheticSource(start.Add(time.Millisecond), time.Millisecond, 10)
+	var got []int64
+	pb, err := gopeat.New("test", start, start.Add(time.Second), src, 1,
+		func(ts gopeat.TimeStamper) error {
+			got = append(got, ts.(Record).Val)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := pb.Run(); res.Completion != gopeat.CompletedOK || len(got) != 10 {
+		t.Errorf("Completion %d, %d sends; expected CompletedOK, 10",
+			res.Completion, len(got))
+	}
+}
+
+// TestBlockingSource confirms Next blocks until Release
+func TestBlockingSource(t *testing.T) {
+	bs := NewBlockingSource()
+	done := make(chan bool)
+	go func() {
+		_, ok := bs.Next()
+		done <- ok
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Next returned before Release")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if !bs.NextCalled() {
+		t.Error("NextCalled false; expected true")
+	}
+
+	bs.Release()
+	bs.Release()
+	if ok := <-done; ok {
+		t.Error("Next ok true after Release; expected false")
+	}
+}
@@ -0,0 +1,37 @@
+package gopeat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ExportTrace writes the timings of the last playback run in the
+// folded stack format read by flamegraph tools. Each sent record is
+// two lines, the time slept before the send and the rest of the wall
+// time since the previous send, mostly client callback time, in
+// microseconds:
+//
+//	gopeat;ES;rec1;sleep 9873
+//	gopeat;ES;rec1;work 127
+//
+// With a TimingSampleRate only the sampled records are written, work
+// is the rest of the wall time since the previous sampled record.
+// Call after Wait returns.
+func (pb *PlayBack) ExportTrace(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	frame := strings.NewReplacer(";", "_", " ", "_", "\n", "_").Replace
+	root := "gopeat;" + frame(pb.Symbol)
+
+	prevWall := pb.WallStartTime
+	for _, rt := range pb.Timings() {
+		work := rt.ActualWall.Sub(prevWall) - rt.SleepDur
+		prevWall = rt.ActualWall
+		fmt.Fprintf(bw, "%s;rec%d;sleep %d\n",
+			root, rt.RecNum, max(rt.SleepDur.Microseconds(), 0))
+		fmt.Fprintf(bw, "%s;rec%d;work %d\n",
+			root, rt.RecNum, max(work.Microseconds(), 0))
+	}
+	return bw.Flush()
+}
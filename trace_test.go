@@ -0,0 +1,58 @@
+package gopeat
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestExportTrace confirms the exported trace parses as folded stacks
+// with a sleep and a work event for each record sent
+func TestExportTrace(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 5; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * 20 * time.Millisecond),
+			Val: int64(i)})
+	}
+	pb, _ := New("ES; mini", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+	pb.Play()
+	pb.Wait()
+
+	var buf bytes.Buffer
+	if err := pb.ExportTrace(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	events := map[string]int{}
+	var slept int64
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		stack, cnt, ok := strings.Cut(line, " ")
+		if !ok {
+			t.Fatalf("line %q has no count", line)
+		}
+		n, err := strconv.ParseInt(cnt, 10, 64)
+		if err != nil || n < 0 {
+			t.Fatalf("line %q count not a non negative int", line)
+		}
+		frames := strings.Split(stack, ";")
+		if len(frames) != 4 || frames[0] != "gopeat" || frames[1] != "ES__mini" {
+			t.Fatalf("line %q frames %q", line, frames)
+		}
+		events[frames[3]]++
+		if frames[3] == "sleep" {
+			slept += n
+		}
+	}
+	if events["sleep"] != 5 || events["work"] != 5 {
+		t.Errorf("events %v; expected 5 sleep and 5 work", events)
+	}
+
+	// Most of the 100ms run is spent sleeping
+	if slept < 80000 {
+		t.Errorf("slept %d(us); expected most of 100000(us)", slept)
+	}
+}
@@ -0,0 +1,136 @@
+package gopeat
+
+import "time"
+
+// clockMark pins sim time to wall time, from wall on sim time moves
+// at rate, 0 while paused
+type clockMark struct {
+	wall time.Time
+	sim  time.Time
+	rate float64
+}
+
+// startMarks pins StartTime to the run's wall start, held up by a
+// pause from the warmup
+func (pb *PlayBack) startMarks(wall time.Time, paused bool) {
+	pb.rateMu.Lock()
+	defer pb.rateMu.Unlock()
+	rate := pb.rate
+	if paused {
+		rate = 0
+	}
+	pb.marks = []clockMark{{wall: wall, sim: pb.StartTime, rate: rate}}
+}
+
+// markLocked pins the sim time at wall and moves on at rate from
+// there. No-op before the run starts. rateMu must be held.
+func (pb *PlayBack) markLocked(wall time.Time, rate float64) {
+	if len(pb.marks) == 0 {
+		return
+	}
+	pb.marks = append(pb.marks,
+		clockMark{wall: wall, sim: pb.simAtLocked(wall), rate: rate})
+}
+
+// markRateLocked moves sim time on at rate from now, a paused run
+// picks up the rate on resume. rateMu must be held.
+func (pb *PlayBack) markRateLocked(rate float64) {
+	if n := len(pb.marks); n > 0 && pb.marks[n-1].rate != 0 {
+		pb.markLocked(pb.clock().Now(), rate)
+	}
+}
+
+// setRateAt switches the rate from the sim time at on, for rates
+// set ahead of time like RateTriggers
+func (pb *PlayBack) setRateAt(at time.Time, rate float64) {
+	pb.rateMu.Lock()
+	defer pb.rateMu.Unlock()
+	if n := len(pb.marks); n > 0 && pb.marks[n-1].rate != 0 {
+		pb.marks = append(pb.marks,
+			clockMark{wall: pb.wallAtLocked(at), sim: at, rate: rate})
+	}
+	pb.rate = rate
+}
+
+// restoreBaseRate undoes the RateTriggers crossed, switching back to
+// the rate set by New or SetRate from now on
+func (pb *PlayBack) restoreBaseRate() {
+	pb.rateMu.Lock()
+	defer pb.rateMu.Unlock()
+	pb.markRateLocked(pb.baseRate)
+	pb.rate = pb.baseRate
+}
+
+// markPaused stops sim time at wall, markResumed restarts it
+func (pb *PlayBack) markPaused(wall time.Time) {
+	pb.rateMu.Lock()
+	pb.markLocked(wall, 0)
+	pb.rateMu.Unlock()
+}
+
+func (pb *PlayBack) markResumed(wall time.Time) {
+	pb.rateMu.Lock()
+	pb.markLocked(wall, pb.rate)
+	pb.rateMu.Unlock()
+}
+
+// WallTimeFor returns the wall time playback sends, or sent, a value
+// timestamped simTime. It follows the rate changes and pauses so far
+// and assumes the current rate from here on, a run paused now is taken
+// as resuming now. Calendar closed time and MaxInterRecordWait are not
+// accounted for, nor is a value already waited on at the old rate
+// when the rate changes. Zero before the run starts.
+func (pb *PlayBack) WallTimeFor(simTime time.Time) time.Time {
+	pb.rateMu.RLock()
+	defer pb.rateMu.RUnlock()
+	if len(pb.marks) == 0 {
+		return time.Time{}
+	}
+	return pb.wallAtLocked(simTime)
+}
+
+// wallAtLocked returns the wall time for simTime, rateMu must be held
+func (pb *PlayBack) wallAtLocked(simTime time.Time) time.Time {
+	// The mark simTime is reached after, the first mark if before all
+	i := len(pb.marks) - 1
+	for i > 0 && pb.marks[i].sim.After(simTime) {
+		i--
+	}
+	m := pb.marks[i]
+	rate := m.rate
+	if rate == 0 {
+		// Paused now, assume a resume now at the current rate
+		rate = pb.rate
+		if now := pb.clock().Now(); now.After(m.wall) {
+			m.wall = now
+		}
+	}
+	return m.wall.Add(time.Duration(float64(simTime.Sub(m.sim)) / rate))
+}
+
+// SimTimeFor returns the sim time playback is at, was at, or will be
+// at at the wall time wall, the inverse of WallTimeFor. Zero before
+// the run starts.
+func (pb *PlayBack) SimTimeFor(wall time.Time) time.Time {
+	pb.rateMu.RLock()
+	defer pb.rateMu.RUnlock()
+	if len(pb.marks) == 0 {
+		return time.Time{}
+	}
+	return pb.simAtLocked(wall)
+}
+
+// simAtLocked returns the sim time at wall, rateMu must be held
+func (pb *PlayBack) simAtLocked(wall time.Time) time.Time {
+	i := len(pb.marks) - 1
+	for i > 0 && pb.marks[i].wall.After(wall) {
+		i--
+	}
+	m := pb.marks[i]
+	rate := m.rate
+	if rate == 0 && wall.Before(m.wall) {
+		// Before the first mark of a run started paused
+		rate = pb.rate
+	}
+	return m.sim.Add(time.Duration(float64(wall.Sub(m.wall)) * rate))
+}
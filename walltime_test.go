@@ -0,0 +1,53 @@
+package gopeat
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWallTimeFor confirms sim and wall time convert back and forth
+// across a pause and a rate change, and match the actual sends
+func TestWallTimeFor(t *testing.T) {
+	var mts mockSliceBackedDs
+	simStartTime := time.Now()
+	for i := 1; i <= 8; i++ {
+		mts.TimeStampers = append(mts.TimeStampers, mockTsData{
+			Tim: simStartTime.Add(time.Duration(i) * 20 * time.Millisecond),
+			Val: int64(i)})
+	}
+	pb, _ := New("test", simStartTime, simStartTime.Add(time.Second), &mts, 1, nil)
+	if !pb.WallTimeFor(simStartTime).IsZero() {
+		t.Error("WallTimeFor before the run; expected zero")
+	}
+
+	pb.SendTs = func(ts TimeStamper) error {
+		switch ts.(mockTsData).Val {
+		case 2:
+			pb.Pause()
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				pb.SetRate(2)
+				pb.Resume()
+			}()
+		}
+		return nil
+	}
+	pb.Play()
+	pb.Wait()
+
+	for _, rt := range pb.Timings() {
+		wall := pb.WallTimeFor(rt.TsTime)
+		if d := rt.ActualWall.Sub(wall); d < -3*time.Millisecond || d > 3*time.Millisecond {
+			t.Errorf("send %d at %v of %v; expected within 3ms", rt.RecNum, d, wall)
+		}
+		if sim := pb.SimTimeFor(wall); sim.Sub(rt.TsTime).Abs() > time.Microsecond {
+			t.Errorf("round trip %v to %v; expected the same", rt.TsTime, sim)
+		}
+	}
+
+	// Sim time stands still over the pause
+	paused := pb.WallTimeFor(simStartTime.Add(40 * time.Millisecond)).Add(10 * time.Millisecond)
+	if sim := pb.SimTimeFor(paused.Add(20 * time.Millisecond)); !sim.Equal(pb.SimTimeFor(paused)) {
+		t.Errorf("sim time moved during the pause, %v to %v", pb.SimTimeFor(paused), sim)
+	}
+}